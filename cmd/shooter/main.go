@@ -5,15 +5,22 @@ import (
 
 	"stampede-shooter/internal/config"
 	"stampede-shooter/internal/orchestrator"
+	"stampede-shooter/internal/util"
 )
 
 func main() {
 	// Parse configuration
 	cfg := config.Parse()
+	if err := util.SetupLogging(cfg.LogFormat); err != nil {
+		log.Fatal(err)
+	}
 
 	// Validate required parameters
-	if cfg.ScriptPath == "" {
-		log.Fatal("--script parameter is required")
+	if cfg.ScriptPath == "" && cfg.ScriptDir == "" {
+		log.Fatal("--script or --script-dir parameter is required")
+	}
+	if cfg.ScriptPath != "" && cfg.ScriptDir != "" {
+		log.Fatal("--script and --script-dir are mutually exclusive")
 	}
 
 	// Create and run orchestrator
@@ -22,6 +29,11 @@ func main() {
 		log.Fatalf("Failed to create orchestrator: %v", err)
 	}
 
+	if cfg.Simulate {
+		o.Simulate()
+		return
+	}
+
 	if err := o.Run(); err != nil {
 		log.Fatalf("Test failed: %v", err)
 	}
@@ -4,6 +4,10 @@ go 1.21
 
 require (
 	github.com/HdrHistogram/hdrhistogram-go v1.1.2
+	github.com/santhosh-tekuri/jsonschema/v5 v5.3.1
 	github.com/spf13/pflag v1.0.6
+	golang.org/x/net v0.25.0
 	gopkg.in/yaml.v3 v3.0.1
 )
+
+require golang.org/x/text v0.15.0 // indirect
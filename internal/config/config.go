@@ -2,39 +2,245 @@ package config
 
 import (
 	"flag"
+	"log"
+	"os"
 	"time"
 )
 
 // Config holds all configuration for the load test
 type Config struct {
-	Users           int           `json:"users"`
-	RPS             int           `json:"rps"`
-	Duration        time.Duration `json:"duration"`
-	ScriptPath      string        `json:"script_path"`
-	LoginURL        string        `json:"login_url"`
-	LoginHeader     string        `json:"login_header"`
-	OutputFile      string        `json:"output_file"`
-	Verbose         bool          `json:"verbose"`
-	InsecureTLS     bool          `json:"insecure_tls"`
-	CredentialsFile string        `json:"credentials_file"`
+	Users              int           `json:"users"`
+	UserIDOffset       int           `json:"user_id_offset"`
+	RPS                int           `json:"rps"`
+	ActionsPerSec      int           `json:"actions_per_second"`
+	GlobalRPS          int           `json:"global_rps"`
+	ArrivalRate        int           `json:"arrival_rate"`
+	Duration           time.Duration `json:"duration"`
+	MaxRequests        int           `json:"max_requests"`
+	IdleTimeout        time.Duration `json:"idle_timeout"`
+	RampUp             time.Duration `json:"ramp_up"`
+	Warmup             time.Duration `json:"warmup"`
+	Stages             string        `json:"stages"`
+	WindowWarmup       time.Duration `json:"window_warmup"`
+	WindowCooldown     time.Duration `json:"window_cooldown"`
+	TimeScale          float64       `json:"time_scale"`
+	ScriptPath         string        `json:"script_path"`
+	ScriptDir          string        `json:"script_dir"`
+	TemplateEngine     string        `json:"template_engine"`
+	DataFile           string        `json:"data_file"`
+	FailOnCaptureMiss  bool          `json:"fail_on_capture_miss"`
+	StrictEnv          bool          `json:"strict_env"`
+	Seed               int64         `json:"seed"`
+	ActionMode         string        `json:"action_mode"`
+	WeightsFrom        string        `json:"weights_from"`
+	BaseURL            string        `json:"base_url"`
+	LoginURL           string        `json:"login_url"`
+	LoginHeader        string        `json:"login_header"`
+	LoginBody          string        `json:"login_body"`
+	LoginStagger       time.Duration `json:"login_stagger"`
+	LoginRPS           int           `json:"login_rps"`
+	TokenExpirySource  string        `json:"token_expiry_source"`
+	TokenRefreshMargin time.Duration `json:"token_refresh_margin"`
+	OAuthTokenURL      string        `json:"oauth_token_url"`
+	OAuthClientID      string        `json:"oauth_client_id"`
+	OAuthClientSecret  string        `json:"oauth_client_secret"`
+	OAuthScope         string        `json:"oauth_scope"`
+	AuthToken          string        `json:"auth_token"`
+	AuthRefreshURL     string        `json:"auth_refresh_url"`
+	AuthTokenField     string        `json:"auth_token_field"`
+	ServerTimeHeader   string        `json:"server_time_header"`
+	SizeStats          bool          `json:"size_stats"`
+	MetricsBuffer      int           `json:"metrics_buffer"`
+	MetricsBlocking    bool          `json:"metrics_blocking"`
+	OutputFile         string        `json:"output_file"`
+	CSVFile            string        `json:"csv_file"`
+	HeatmapFile        string        `json:"heatmap_file"`
+	SamplesFile        string        `json:"samples_file"`
+	MetricsAddr        string        `json:"metrics_addr"`
+	HeatmapInterval    time.Duration `json:"heatmap_interval"`
+	ThroughputBucket   time.Duration `json:"throughput_bucket"`
+	TimeSeries         time.Duration `json:"timeseries_interval"`
+	RPSWindow          time.Duration `json:"rps_window"`
+	FlamegraphFile     string        `json:"flamegraph_file"`
+	BadgeFile          string        `json:"badge_file"`
+	BadgeMetric        string        `json:"badge_metric"`
+	SLAP95             time.Duration `json:"sla_p95"`
+	SLAErrorRate       float64       `json:"sla_error_rate"`
+	HealthWeights      string        `json:"health_weights"`
+	FailureWebhook     string        `json:"on_failure_webhook"`
+	FailureTemplate    string        `json:"on_failure_webhook_template"`
+	ErrorDumpFile      string        `json:"error_dump_file"`
+	ErrorDumpMax       int           `json:"error_dump_max"`
+	ErrorDumpSecrets   bool          `json:"error_dump_secrets"`
+	Verbose            bool          `json:"verbose"`
+	LogFormat          string        `json:"log_format"`
+	Trace              bool          `json:"trace"`
+	TraceSecrets       bool          `json:"trace_secrets"`
+	Simulate           bool          `json:"simulate"`
+	DryRun             bool          `json:"dry_run"`
+	InsecureTLS        bool          `json:"insecure_tls"`
+	TLSSessionCache    int           `json:"tls_session_cache"`
+	DisableKeepAlive   bool          `json:"disable_keepalive"`
+	MaxIdleConns       int           `json:"max_idle_conns"`
+	MaxIdlePerHost     int           `json:"max_idle_conns_per_host"`
+	MaxConnsPerHost    int           `json:"max_conns_per_host"`
+	ConnIdleTimeout    time.Duration `json:"conn_idle_timeout"`
+	HTTP2              bool          `json:"http2"`
+	ForceHTTP2         bool          `json:"force_http2"`
+	HTTP10             bool          `json:"http1_0"`
+	CredentialsFile    string        `json:"credentials_file"`
+	CredentialsStream  bool          `json:"credentials_stream"`
+	UniqueCredentials  bool          `json:"unique_credentials"`
+	ExportCookies      string        `json:"export_cookies"`
+	Cookie             string        `json:"cookie"`
+	DumpCookies        bool          `json:"dump_cookies"`
+	Retries            int           `json:"retries"`
+	DialRetries        int           `json:"dial_retries"`
+	RetryBackoff       time.Duration `json:"retry_backoff"`
+	RetryBudget        float64       `json:"retry_budget"`
+	RetryJitter        bool          `json:"retry_jitter"`
+	SourceIPs          string        `json:"source_ips"`
+	UnixSocket         string        `json:"unix_socket"`
+	Proxy              string        `json:"proxy"`
+	ProxyList          string        `json:"proxy_list"`
+	ProxyDrop          bool          `json:"proxy_drop_unreachable"`
+	ConfigFile         string        `json:"-"`
+
+	set map[string]bool // Flags explicitly given on the command line
 }
 
-// Parse parses command line flags into config
+// Parse parses command line flags into config. With --config, values are
+// layered as defaults < config file < command line flags.
 func Parse() *Config {
 	cfg := &Config{}
+	registerFlags(flag.CommandLine, cfg)
+	flag.Parse()
 
-	flag.IntVar(&cfg.Users, "users", 10, "Number of concurrent users")
-	flag.IntVar(&cfg.RPS, "rps", 1, "Requests per second per user")
-	flag.DurationVar(&cfg.Duration, "duration", 30*time.Second, "Test duration")
-	flag.StringVar(&cfg.ScriptPath, "script", "", "Path to test script (required)")
-	flag.StringVar(&cfg.LoginURL, "login-url", "", "Optional login endpoint URL")
-	flag.StringVar(&cfg.LoginHeader, "login-hdr", "", "Authentication header (format: key:value)")
-	flag.StringVar(&cfg.OutputFile, "out", "", "Output file for JSON results")
-	flag.BoolVar(&cfg.Verbose, "verbose", false, "Show live progress updates")
-	flag.BoolVar(&cfg.InsecureTLS, "insecure-tls", false, "Skip TLS certificate verification")
-	flag.StringVar(&cfg.CredentialsFile, "credentials", "", "Path to credentials file (format: username,password)")
+	cfg.set = make(map[string]bool)
+	flag.Visit(func(f *flag.Flag) {
+		cfg.set[f.Name] = true
+	})
 
-	flag.Parse()
+	if cfg.ConfigFile != "" {
+		set := cfg.set
+		fileCfg, fs, err := loadFile(cfg.ConfigFile)
+		if err != nil {
+			log.Fatalf("Invalid --config: %v", err)
+		}
+		// Re-apply the command line so flags override the file
+		fs.Parse(os.Args[1:])
+		for name := range set {
+			fileCfg.set[name] = true
+		}
+		cfg = fileCfg
+	}
 
 	return cfg
 }
+
+// registerFlags defines all command line flags on fs, storing their defaults
+// in cfg
+func registerFlags(fs *flag.FlagSet, cfg *Config) {
+	fs.StringVar(&cfg.ConfigFile, "config", "", "YAML or JSON file of option values keyed like the JSON output (flags override it)")
+	fs.IntVar(&cfg.Users, "users", 10, "Number of concurrent users")
+	fs.IntVar(&cfg.UserIDOffset, "user-id-offset", 0, "Number user IDs from offset+1, to keep users of sharded runs on several machines apart")
+	fs.IntVar(&cfg.RPS, "rps", 1, "Requests per second per user")
+	fs.IntVar(&cfg.ActionsPerSec, "actions-per-second", 0, "Total actions per second across all users (overrides --rps; sizes --users automatically unless given)")
+	fs.IntVar(&cfg.GlobalRPS, "global-rps", 0, "Cap the combined request rate of all users at this many requests per second (0 = no cap)")
+	fs.IntVar(&cfg.ArrivalRate, "arrival-rate", 0, "Open model: start this many script iterations per second regardless of response times; --users caps concurrent iterations")
+	fs.DurationVar(&cfg.Duration, "duration", 30*time.Second, "Test duration")
+	fs.IntVar(&cfg.MaxRequests, "max-requests", 0, "Stop after this many requests in total, or at --duration if that comes first (0 = no cap)")
+	fs.DurationVar(&cfg.RampUp, "ramp-up", 0, "Start workers evenly spread over this period instead of all at once")
+	fs.DurationVar(&cfg.Warmup, "warmup", 0, "Send requests for this long before recording them; stats, duration and RPS cover only the rest of the test")
+	fs.StringVar(&cfg.Stages, "stages", "", "Stepped load profile: comma-separated users:duration stages run in order, e.g. 10:30s,20:30s,30:1m (sets --users and --duration)")
+	fs.DurationVar(&cfg.WindowWarmup, "window-warmup", 0, "Also report steady-state stats of requests started this long after the test start (excludes warm-up)")
+	fs.DurationVar(&cfg.WindowCooldown, "window-cooldown", 0, "Also report steady-state stats excluding requests started in this final part of the test (excludes ramp-down)")
+	fs.DurationVar(&cfg.IdleTimeout, "idle-timeout", 0, "End the test early if no request succeeds for this long (0 = never)")
+	fs.Float64Var(&cfg.TimeScale, "time-scale", 1.0, "Multiplier applied to all action delays (0.5 halves think time, 0 removes it)")
+	fs.StringVar(&cfg.ScriptPath, "script", "", "Path to test script (required)")
+	fs.StringVar(&cfg.ScriptDir, "script-dir", "", "Directory of YAML action files, loaded in lexical order (alternative to --script)")
+	fs.StringVar(&cfg.TemplateEngine, "template-engine", "simple", "Template engine for URLs, headers and bodies: simple ({{userId}} placeholders) or go (text/template)")
+	fs.StringVar(&cfg.DataFile, "data", "", "YAML, JSON or CSV file of named value lists for {{pick <name>}}")
+	fs.BoolVar(&cfg.StrictEnv, "strict-env", false, "Fail when the script references an unset environment variable with {{env \"NAME\"}} instead of expanding it to empty")
+	fs.BoolVar(&cfg.FailOnCaptureMiss, "fail-on-capture-miss", false, "Count requests whose captures do not match the response as failed instead of soft misses")
+	fs.Int64Var(&cfg.Seed, "seed", 0, "Seed for random template values, delays and picks, so runs can be reproduced (0 = seed from the clock)")
+	fs.StringVar(&cfg.ActionMode, "action-mode", "sequential", "How each iteration walks the script: sequential (every action in order) or weighted (one action picked by weight)")
+	fs.StringVar(&cfg.WeightsFrom, "weights-from", "", "Derive action weights from an access log or endpoint,count CSV of real traffic")
+	fs.StringVar(&cfg.BaseURL, "base-url", "", "Resolve relative action and login URLs against this URL, e.g. https://staging.example.com")
+	fs.StringVar(&cfg.LoginURL, "login-url", "", "Optional login endpoint URL")
+	fs.StringVar(&cfg.LoginHeader, "login-hdr", "", "Authentication header (format: key:value)")
+	fs.StringVar(&cfg.LoginBody, "login-body", "", "Login request body with {{username}}, {{password}} and {{userId}} placeholders; sent as JSON if it starts with { and as a form otherwise")
+	fs.DurationVar(&cfg.LoginStagger, "login-stagger", 0, "Delay between successive workers' login attempts (worker N waits (N-1) x stagger)")
+	fs.IntVar(&cfg.LoginRPS, "login-rps", 0, "Maximum login and re-login requests per second across all users (0 = unlimited)")
+	fs.StringVar(&cfg.TokenExpirySource, "token-expiry-source", "", "Session token expiry source for proactive re-login: jwt or header:<Name>")
+	fs.DurationVar(&cfg.TokenRefreshMargin, "token-refresh-margin", 30*time.Second, "Re-login this long before the session token expires")
+	fs.StringVar(&cfg.OAuthTokenURL, "oauth-token-url", "", "OAuth2 token endpoint; each user fetches a bearer token with the client-credentials grant and refreshes it before expiry")
+	fs.StringVar(&cfg.OAuthClientID, "oauth-client-id", "", "OAuth2 client ID for --oauth-token-url")
+	fs.StringVar(&cfg.OAuthClientSecret, "oauth-client-secret", "", "OAuth2 client secret for --oauth-token-url")
+	fs.StringVar(&cfg.OAuthScope, "oauth-scope", "", "Space-separated scopes to request from --oauth-token-url")
+	fs.StringVar(&cfg.AuthToken, "auth-token", "", "Bearer token sent as Authorization: Bearer <token> with every request")
+	fs.StringVar(&cfg.AuthRefreshURL, "auth-refresh-url", "", "Endpoint POSTed with the current token to get a new one after a 401 response")
+	fs.StringVar(&cfg.AuthTokenField, "auth-token-field", "access_token", "Dotted path of the new token in the --auth-refresh-url JSON response, e.g. data.token")
+	fs.StringVar(&cfg.ServerTimeHeader, "server-time-header", "", "Response header reporting server processing time, e.g. X-Response-Time or Server-Timing")
+	fs.BoolVar(&cfg.SizeStats, "size-stats", false, "Report response size percentiles per action")
+	fs.IntVar(&cfg.MetricsBuffer, "metrics-buffer", 10000, "Completed requests buffered for the metrics collector before they are dropped (or workers wait, with --metrics-blocking)")
+	fs.BoolVar(&cfg.MetricsBlocking, "metrics-blocking", false, "Make workers wait when the metrics buffer is full instead of dropping requests from the results")
+	fs.StringVar(&cfg.OutputFile, "out", "", "Output file for JSON results")
+	fs.StringVar(&cfg.MetricsAddr, "metrics-addr", "", "Serve live Prometheus metrics on /metrics at this address during the test, e.g. :9090")
+	fs.StringVar(&cfg.CSVFile, "csv", "", "Output file for per-action results as CSV")
+	fs.StringVar(&cfg.HeatmapFile, "heatmap", "", "Output file for per-interval latency heatmap JSON")
+	fs.StringVar(&cfg.SamplesFile, "samples", "", "Output file (JSON lines) streaming every request's start time, status and latency for offline analysis")
+	fs.DurationVar(&cfg.ThroughputBucket, "throughput-bucket", 0, "Show throughput over time in buckets of this size in the final report, e.g. 5s (0 = off)")
+	fs.DurationVar(&cfg.TimeSeries, "timeseries-interval", time.Second, "Interval of the RPS and latency time series in the JSON report; long runs are downsampled to at most 600 points (0 = off)")
+	fs.DurationVar(&cfg.RPSWindow, "rps-window", 5*time.Second, "Window of the recent request rate shown in live progress with --verbose (1s to 60s)")
+	fs.DurationVar(&cfg.HeatmapInterval, "heatmap-interval", time.Second, "Interval length for heatmap snapshots")
+	fs.StringVar(&cfg.FlamegraphFile, "flamegraph", "", "Output file for time per action and phase in folded stack format")
+	fs.StringVar(&cfg.BadgeFile, "badge", "", "Output file for a shields.io endpoint badge JSON")
+	fs.StringVar(&cfg.BadgeMetric, "badge-metric", "p95", "Metric shown on the badge: p95, error-rate or rps")
+	fs.DurationVar(&cfg.SLAP95, "sla-p95", 0, "p95 latency SLA across all actions (0 = none)")
+	fs.Float64Var(&cfg.SLAErrorRate, "sla-error-rate", 0, "Maximum error rate in percent, e.g. 1 for 1% (0 = none)")
+	fs.StringVar(&cfg.HealthWeights, "health-weights", "errors=0.5,latency=0.3,throughput=0.2", "Health score weights of the errors, latency and throughput components")
+	fs.StringVar(&cfg.FailureWebhook, "on-failure-webhook", "", "URL to POST a summary to when an SLA threshold fails")
+	fs.StringVar(&cfg.FailureTemplate, "on-failure-webhook-template", "", "text/template file for a custom --on-failure-webhook payload")
+	fs.StringVar(&cfg.ErrorDumpFile, "error-dump", "", "Output file (JSON lines) for failed requests with the exact request sent and response received")
+	fs.IntVar(&cfg.ErrorDumpMax, "error-dump-max", 100, "Maximum failed requests written to --error-dump (0 = unlimited)")
+	fs.BoolVar(&cfg.ErrorDumpSecrets, "error-dump-secrets", false, "Include secret headers and passwords in --error-dump instead of redacting them")
+	fs.BoolVar(&cfg.DryRun, "dry-run", false, "Validate the script and print each action's request for the first user without sending anything")
+	fs.BoolVar(&cfg.Simulate, "simulate", false, "Print the expected load profile for this configuration without sending requests")
+	fs.BoolVar(&cfg.Verbose, "verbose", false, "Show live progress updates")
+	fs.BoolVar(&cfg.Trace, "trace", false, "Print every request and response (headers and a truncated body) to stderr, for debugging with a few users")
+	fs.BoolVar(&cfg.TraceSecrets, "trace-secrets", false, "Don't redact Authorization, Cookie and other secret headers and the user's password from --trace output")
+	fs.StringVar(&cfg.LogFormat, "log-format", "text", "Log format: text, or json for one JSON object per line that also logs each failed request")
+	fs.BoolVar(&cfg.InsecureTLS, "insecure-tls", false, "Skip TLS certificate verification")
+	fs.IntVar(&cfg.TLSSessionCache, "tls-session-cache", 64, "TLS session cache size per worker for session resumption (0 disables)")
+	fs.BoolVar(&cfg.DisableKeepAlive, "disable-keepalive", false, "Send Connection: close and open a new connection per request, like an HTTP/1.0 client")
+	fs.IntVar(&cfg.MaxIdleConns, "max-idle-conns", 100, "Maximum idle keep-alive connections per user across all hosts (0 = unlimited)")
+	fs.IntVar(&cfg.MaxIdlePerHost, "max-idle-conns-per-host", 10, "Maximum idle keep-alive connections per user and host (0 = Go's default of 2)")
+	fs.IntVar(&cfg.MaxConnsPerHost, "max-conns-per-host", 0, "Maximum connections per user and host, including active ones; further requests wait (0 = unlimited)")
+	fs.DurationVar(&cfg.ConnIdleTimeout, "conn-idle-timeout", 30*time.Second, "Close keep-alive connections idle for this long (0 = never)")
+	fs.BoolVar(&cfg.HTTP2, "http2", false, "Negotiate HTTP/2 over TLS (ALPN) for https:// URLs; HTTP/1.1 is used otherwise")
+	fs.BoolVar(&cfg.ForceHTTP2, "force-http2", false, "Also send http:// URLs as HTTP/2 with prior knowledge (h2c); implies --http2")
+	fs.BoolVar(&cfg.HTTP10, "http1.0", false, "Send requests as HTTP/1.0 over a new connection each, to test legacy client behavior")
+	fs.StringVar(&cfg.CredentialsFile, "credentials", "", "Path to credentials file (username,password lines, CSV with a header row, or a JSON array of objects)")
+	fs.BoolVar(&cfg.UniqueCredentials, "unique-credentials", false, "Fail instead of sharing credentials between users when the file has fewer credentials than users")
+	fs.BoolVar(&cfg.CredentialsStream, "credentials-stream", false, "Read credentials from disk on demand instead of loading the file into memory (for files with millions of accounts)")
+	fs.StringVar(&cfg.ExportCookies, "export-cookies", "", "Write the first worker's cookies to this file (Netscape cookies.txt format) at test end")
+	fs.StringVar(&cfg.Cookie, "cookie", "", "Cookies to seed every worker's jar with for the target host, e.g. \"session=abc123; theme=dark\"")
+	fs.BoolVar(&cfg.DumpCookies, "dump-cookies", false, "Print the first worker's cookies to stderr after login (or at its start without --login-url)")
+	fs.IntVar(&cfg.Retries, "retries", 0, "Maximum retries per request on connection errors or 5xx responses")
+	fs.IntVar(&cfg.DialRetries, "dial-retries", 0, "Retry failed connection attempts this many times before failing the request (the request itself is never resent)")
+	fs.DurationVar(&cfg.RetryBackoff, "retry-backoff", 100*time.Millisecond, "Base backoff between retries (doubles per attempt)")
+	fs.BoolVar(&cfg.RetryJitter, "retry-jitter", false, "Randomize each retry backoff between 0 and the computed backoff (full jitter)")
+	fs.StringVar(&cfg.SourceIPs, "source-ips", "", "Comma-separated local IPs to bind worker connections to (round-robin by worker)")
+	fs.StringVar(&cfg.UnixSocket, "unix-socket", "", "Send all requests over this Unix domain socket; URLs still set the Host header")
+	fs.StringVar(&cfg.Proxy, "proxy", "", "Send all requests through this proxy, e.g. http://host:8080 or socks5://host:1080 (default: HTTP_PROXY/HTTPS_PROXY)")
+	fs.StringVar(&cfg.ProxyList, "proxy-list", "", "File of proxy URLs, one per line, assigned to workers round-robin")
+	fs.BoolVar(&cfg.ProxyDrop, "proxy-drop-unreachable", false, "Move workers off proxies that fail 3 requests in a row")
+	fs.Float64Var(&cfg.RetryBudget, "retry-budget", 0, "Maximum retries as a fraction of total requests, e.g. 0.1 (0 = unlimited)")
+}
+
+// IsSet reports whether the named flag was explicitly given on the command
+// line or in the config file
+func (c *Config) IsSet(name string) bool {
+	return c.set[name]
+}
@@ -0,0 +1,79 @@
+package config
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"reflect"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// LoadFile loads a YAML or JSON config file on top of the flag defaults.
+// Keys are the JSON names of Config fields, e.g. users or retry_backoff, and
+// durations are strings such as "30s". Unknown keys are an error.
+func LoadFile(path string) (*Config, error) {
+	cfg, _, err := loadFile(path)
+	return cfg, err
+}
+
+// loadFile loads a config file and returns it with a flag set bound to it,
+// so the command line can be parsed on top
+func loadFile(path string) (*Config, *flag.FlagSet, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read config file: %w", err)
+	}
+
+	cfg := &Config{set: make(map[string]bool)}
+	fs := flag.NewFlagSet(os.Args[0], flag.ExitOnError)
+	registerFlags(fs, cfg)
+
+	// Flag values point at their Config field, which maps file keys to flag names
+	flagNames := make(map[uintptr]string)
+	fs.VisitAll(func(f *flag.Flag) {
+		flagNames[reflect.ValueOf(f.Value).Pointer()] = f.Name
+	})
+
+	// YAML is a superset of JSON, so one parser reads both formats
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, nil, fmt.Errorf("failed to parse config file: %w", err)
+	}
+	if len(doc.Content) == 0 {
+		return cfg, fs, nil
+	}
+	root := doc.Content[0]
+	if root.Kind != yaml.MappingNode {
+		return nil, nil, fmt.Errorf("config file must map option names to values")
+	}
+
+	fields := configFields(cfg)
+	for i := 0; i+1 < len(root.Content); i += 2 {
+		key := root.Content[i]
+		field, ok := fields[key.Value]
+		if !ok {
+			return nil, nil, fmt.Errorf("unknown config key %q on line %d", key.Value, key.Line)
+		}
+		if err := root.Content[i+1].Decode(field.Addr().Interface()); err != nil {
+			return nil, nil, fmt.Errorf("invalid value for config key %q: %w", key.Value, err)
+		}
+		cfg.set[flagNames[field.Addr().Pointer()]] = true
+	}
+	return cfg, fs, nil
+}
+
+// configFields returns the settable fields of cfg by JSON name
+func configFields(cfg *Config) map[string]reflect.Value {
+	v := reflect.ValueOf(cfg).Elem()
+	fields := make(map[string]reflect.Value)
+	for i := 0; i < v.NumField(); i++ {
+		name := strings.Split(v.Type().Field(i).Tag.Get("json"), ",")[0]
+		if name == "" || name == "-" {
+			continue
+		}
+		fields[name] = v.Field(i)
+	}
+	return fields
+}
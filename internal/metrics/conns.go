@@ -0,0 +1,32 @@
+package metrics
+
+import "time"
+
+// GetNewConnPercentile returns the specified latency percentile of successful
+// requests sent on a newly established connection
+func (as *ActionStats) GetNewConnPercentile(percentile float64) time.Duration {
+	as.mu.RLock()
+	defer as.mu.RUnlock()
+
+	micros := valueAtQuantile(as.NewConnHistogram, percentile)
+	return time.Duration(micros) * time.Microsecond
+}
+
+// GetReusedConnPercentile returns the specified latency percentile of
+// successful requests sent on a reused connection
+func (as *ActionStats) GetReusedConnPercentile(percentile float64) time.Duration {
+	as.mu.RLock()
+	defer as.mu.RUnlock()
+
+	micros := valueAtQuantile(as.ReusedConnHistogram, percentile)
+	return time.Duration(micros) * time.Microsecond
+}
+
+// ConnSamples returns how many successful requests used a new and a reused
+// connection
+func (as *ActionStats) ConnSamples() (newConns, reused int64) {
+	as.mu.RLock()
+	defer as.mu.RUnlock()
+
+	return totalCount(as.NewConnHistogram), totalCount(as.ReusedConnHistogram)
+}
@@ -0,0 +1,105 @@
+package metrics
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Error categories of failed requests; unexpected statuses of 400 and above
+// are categorized as "status_<code>" instead
+const (
+	ErrorTimeout        = "timeout"         // The request ran out of time
+	ErrorCancelled      = "cancelled"       // Cut off by the end of the test
+	ErrorConnection     = "connection"      // Dial, reset, DNS or proxy failure
+	ErrorTLS            = "tls"             // TLS handshake or certificate failure
+	ErrorExpectMismatch = "expect_mismatch" // A non-error status other than expected
+	ErrorContentType    = "content_type"    // The response Content-Type was not the expected one
+	ErrorBodyMismatch   = "body_mismatch"   // The response body failed expect_body_contains or expect_body_regex
+	ErrorTemplate       = "template"        // The action's templates failed to expand
+	ErrorAuth           = "auth"            // No credentials, e.g. the OAuth token endpoint failed
+	ErrorCapture        = "capture"         // A capture missed with --fail-on-capture-miss
+	ErrorBodyTransform  = "body_transform"  // The response body could not be unwrapped
+	ErrorSchema         = "schema"          // The response violated expect_schema
+	ErrorConsistency    = "consistency"     // A consistency invariant failed
+	ErrorIdempotency    = "idempotency"     // A resend did not get the original response
+	ErrorOther          = "other"
+)
+
+// connectionErrors are substrings of transport errors caused by the connection
+var connectionErrors = []string{
+	"connection refused", "connection reset", "broken pipe", "no such host",
+	"EOF", "dial ", "proxyconnect", "network is unreachable",
+}
+
+// ErrorCount is the number of failed requests in an error category
+type ErrorCount struct {
+	Category string `json:"category"`
+	Count    int64  `json:"count"`
+}
+
+// errorCategory classifies a failed request by its error message and status
+func errorCategory(metric RequestMetric) string {
+	msg := metric.Error
+	statusCategory := func() string {
+		if metric.StatusCode >= 400 {
+			return fmt.Sprintf("status_%d", metric.StatusCode)
+		}
+		return ErrorExpectMismatch
+	}
+
+	switch {
+	case msg == "" || strings.HasPrefix(msg, "expected status"):
+		return statusCategory()
+	case strings.HasPrefix(msg, "expected content type"):
+		return ErrorContentType
+	case strings.HasPrefix(msg, "expected body"):
+		return ErrorBodyMismatch
+	case strings.HasPrefix(msg, "template error"):
+		return ErrorTemplate
+	case strings.HasPrefix(msg, "auth error"):
+		return ErrorAuth
+	case strings.HasPrefix(msg, "capture missed"):
+		return ErrorCapture
+	case strings.HasPrefix(msg, "body_transform"):
+		return ErrorBodyTransform
+	case strings.HasPrefix(msg, "schema violation"):
+		return ErrorSchema
+	case strings.HasPrefix(msg, "consistency violation"):
+		return ErrorConsistency
+	case strings.HasPrefix(msg, "idempotency violation"):
+		return ErrorIdempotency
+	case strings.HasPrefix(msg, "timeout after"), strings.Contains(msg, "Client.Timeout"),
+		strings.Contains(msg, "i/o timeout"), strings.Contains(msg, "handshake timeout"):
+		return ErrorTimeout
+	case strings.Contains(msg, "context deadline exceeded"), strings.Contains(msg, "context canceled"):
+		return ErrorCancelled
+	case strings.Contains(msg, "tls:"), strings.Contains(msg, "x509:"):
+		return ErrorTLS
+	}
+	for _, s := range connectionErrors {
+		if strings.Contains(msg, s) {
+			return ErrorConnection
+		}
+	}
+	return ErrorOther
+}
+
+// ErrorBreakdown returns the action's failed requests by error category,
+// most frequent first
+func (as *ActionStats) ErrorBreakdown() []ErrorCount {
+	as.mu.RLock()
+	defer as.mu.RUnlock()
+
+	counts := make([]ErrorCount, 0, len(as.ErrorCategories))
+	for category, count := range as.ErrorCategories {
+		counts = append(counts, ErrorCount{Category: category, Count: count})
+	}
+	sort.Slice(counts, func(i, j int) bool {
+		if counts[i].Count != counts[j].Count {
+			return counts[i].Count > counts[j].Count
+		}
+		return counts[i].Category < counts[j].Category
+	})
+	return counts
+}
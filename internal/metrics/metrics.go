@@ -1,7 +1,9 @@
 package metrics
 
 import (
+	"runtime"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/HdrHistogram/hdrhistogram-go"
@@ -15,8 +17,25 @@ type RequestMetric struct {
 	StartTime  time.Time
 	EndTime    time.Time
 	StatusCode int
+	Proto      string // Protocol of the response, e.g. HTTP/1.1 or HTTP/2.0
 	BytesRead  int64
+	Retries    int
 	Error      string
+
+	TLSHandshake bool // A TLS handshake was performed
+	TLSResumed   bool // The TLS handshake resumed a cached session
+	ConnClosed   bool // The response closed the connection (Connection: close or HTTP/1.0)
+	NewConn      bool // The request was sent on a newly established connection
+
+	ServerTime time.Duration // Server-reported processing time, 0 if not reported
+
+	Phases [NumPhases]time.Duration // Time spent in each traced phase
+	TTFB   time.Duration            // Request start to first response byte, 0 if not traced
+
+	CaptureMisses []string // Captures that did not match the response
+
+	Resent      bool // The request reused an earlier request's idempotency key
+	DedupFailed bool // The resent request's response differed from the original
 }
 
 // ActionStats holds aggregated statistics for a specific action
@@ -26,94 +45,599 @@ type ActionStats struct {
 	TotalErrors int64
 	Histogram   *hdrhistogram.Histogram
 	BytesTotal  int64
-	mu          sync.RWMutex
+	Retries     int64
+
+	// TLS handshakes split by full vs resumed sessions
+	TLSFullHandshakes int64
+	TLSResumed        int64
+
+	ConnClosed int64 // Responses that closed the connection
+
+	// The histograms below are created on their first value, so they are
+	// nil while nothing was recorded
+
+	// Latency of successful requests on new vs reused connections
+	NewConnHistogram    *hdrhistogram.Histogram
+	ReusedConnHistogram *hdrhistogram.Histogram
+
+	// Server-reported processing time of successful requests
+	ServerHistogram *hdrhistogram.Histogram
+
+	// Time to first byte of successful requests, next to Histogram's time to
+	// the fully read body
+	TTFBHistogram *hdrhistogram.Histogram
+
+	SizeHistogram *hdrhistogram.Histogram // Response body sizes in bytes of all responses, with EnableSizeStats
+
+	PhaseTotals PhaseTotals // Time per phase of successful requests
+
+	CaptureMisses   map[string]int64 // Responses each capture did not match, by capture name
+	ErrorCategories map[string]int64 // Failed requests by error category, e.g. timeout or status_500
+
+	// Idempotency resends, and those whose response differed from the original
+	Resends       int64
+	DedupFailures int64
+
+	shard *shard // The action's part of the collector-wide aggregates, nil for window stats
+
+	mu sync.RWMutex
+}
+
+// IntervalQuantiles are the percentiles captured for each interval snapshot
+var IntervalQuantiles = []float64{0, 10, 20, 30, 40, 50, 60, 70, 80, 90, 95, 99, 99.9, 100}
+
+// IntervalSnapshot holds the latency distribution of one time interval
+type IntervalSnapshot struct {
+	Offset      time.Duration   // Interval start relative to the test start
+	Count       int64           // Successful requests in the interval
+	Errors      int64           // Failed requests in the interval
+	Percentiles []time.Duration // Latency at each of IntervalQuantiles
 }
 
 // Collector aggregates metrics from multiple workers
 type Collector struct {
 	metrics   chan RequestMetric
-	actions   map[string]*ActionStats
+	actions   sync.Map   // Action name -> *ActionStats, read without a collector-wide lock
+	actionsMu sync.Mutex // Serializes creating action stats, so a lost race builds nothing
 	startTime time.Time
-	mu        sync.RWMutex
+	mu        sync.RWMutex // Guards snapshots and concurrency samples; per-metric aggregates are sharded by action
 	done      chan struct{}
+	blocking  bool // Record waits instead of dropping when the channel is full
+
+	// Interval snapshots, enabled with EnableIntervals
+	interval      time.Duration
+	intervalStart time.Time
+	intervalHist  *hdrhistogram.Histogram // Merges the actions' parts of an interval
+	intervals     []IntervalSnapshot
+
+	// Time series of throughput and latency, enabled with EnableTimeSeries
+	seriesBase     time.Duration // Interval the series was enabled with
+	seriesInterval time.Duration // Current interval, doubled by each downsampling
+	seriesStart    time.Time
+	seriesHist     *hdrhistogram.Histogram
+	series         []TimeSeriesPoint
+
+	// Completed requests per time bucket, enabled with EnableThroughputBuckets
+	bucketSize time.Duration
+
+	sizeStats bool // Record response sizes, enabled with EnableSizeStats
+
+	// In-flight request tracking, sampled every concurrencySampleInterval
+	inFlight           atomic.Int64
+	maxInFlight        atomic.Int64
+	concurrencySum     int64
+	concurrencySamples int64
+	stopSampling       chan struct{}
+	samplingDone       chan struct{}
+
+	tokenRefreshes atomic.Int64 // Proactive re-logins before token expiry
+	logins         atomic.Int64 // Login and re-login requests sent
+	dialRetries    atomic.Int64 // Connection attempts retried after a dial failure
+	lastSuccess    atomic.Int64 // UnixNano end time of the latest successful request
+	dropped        atomic.Int64 // Metrics dropped because the channel was full
+	cancelled      atomic.Int64 // In-flight requests cut off by stopping their worker
+	recorded       atomic.Int64 // Requests recorded, successful or not
+
+	// Warm-up whose requests are ignored, enabled with EnableWarmup
+	warmupUntil   time.Time
+	warmupIgnored atomic.Int64
+
+	// Steady-state window, enabled with EnableWindow
+	windowFrom    time.Time
+	windowTo      time.Time
+	windowActions sync.Map // Action name -> *ActionStats of requests started in the window
+
+	// Open-arrival queue time, from scheduled start to dispatch
+	queueHist       *hdrhistogram.Histogram
+	queueMu         sync.Mutex
+	droppedArrivals atomic.Int64
+
+	samples *SampleWriter // Raw per-request samples, enabled with EnableSamples
 }
 
-// NewCollector creates a new metrics collector
-func NewCollector() *Collector {
+// concurrencySampleInterval is how often in-flight requests are sampled
+const concurrencySampleInterval = 100 * time.Millisecond
+
+// DefaultBufferSize is the default number of metrics the collector's channel
+// holds before Record drops or blocks
+const DefaultBufferSize = 10000
+
+// NewCollector creates a new metrics collector whose channel buffers up to
+// bufferSize metrics
+func NewCollector(bufferSize int) *Collector {
 	return &Collector{
-		metrics:   make(chan RequestMetric, 10000),
-		actions:   make(map[string]*ActionStats),
-		startTime: time.Now(),
-		done:      make(chan struct{}),
+		metrics:      make(chan RequestMetric, bufferSize),
+		startTime:    time.Now(),
+		done:         make(chan struct{}),
+		stopSampling: make(chan struct{}),
+		samplingDone: make(chan struct{}),
+	}
+}
+
+// EnableIntervals makes the collector snapshot and reset an interval histogram
+// every interval. It must be called before Start.
+func (c *Collector) EnableIntervals(interval time.Duration) {
+	c.interval = interval
+	c.intervalStart = c.startTime
+	c.intervalHist = hdrhistogram.New(1, 60000000, 3)
+}
+
+// EnableSizeStats makes the collector record response body sizes for
+// GetSizePercentile. It must be called before Start.
+func (c *Collector) EnableSizeStats() {
+	c.sizeStats = true
+}
+
+// Preallocate creates empty stats for each named action, so actions that
+// never record a request still appear in reports. It must be called before
+// Start.
+func (c *Collector) Preallocate(names []string) {
+	for _, name := range names {
+		c.actionStats(name)
 	}
 }
 
+// GetIntervals returns the interval snapshots taken so far
+func (c *Collector) GetIntervals() []IntervalSnapshot {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	result := make([]IntervalSnapshot, len(c.intervals))
+	copy(result, c.intervals)
+	return result
+}
+
+// SetBlocking makes Record wait for room in a full channel instead of
+// dropping the metric. Workers slow down while the collector catches up,
+// trading some load for complete data. It must be called before Start.
+func (c *Collector) SetBlocking(blocking bool) {
+	c.blocking = blocking
+}
+
 // Record sends a metric to the collector
 func (c *Collector) Record(metric RequestMetric) {
+	if c.blocking {
+		c.metrics <- metric
+		return
+	}
+
 	select {
 	case c.metrics <- metric:
 	default:
 		// Drop metric if channel is full to avoid blocking workers
+		c.dropped.Add(1)
 	}
 }
 
+// RecordedCount returns the number of requests recorded so far, successful
+// or not. It is a single atomic load, cheap enough to poll.
+func (c *Collector) RecordedCount() int64 {
+	return c.recorded.Load()
+}
+
+// DroppedCount returns the number of metrics dropped because the collector
+// fell behind and its channel was full. Totals undercount by this much.
+func (c *Collector) DroppedCount() int64 {
+	return c.dropped.Load()
+}
+
 // Start begins collecting metrics in a goroutine
 func (c *Collector) Start() {
 	go c.collect()
+	go c.sampleConcurrency()
 }
 
 // Stop stops the collector and closes channels
 func (c *Collector) Stop() {
+	close(c.stopSampling)
+	<-c.samplingDone
 	close(c.metrics)
 	<-c.done
 }
 
-// GetStats returns current aggregated statistics
-func (c *Collector) GetStats() map[string]*ActionStats {
+// RequestStarted marks a request as in flight
+func (c *Collector) RequestStarted() {
+	n := c.inFlight.Add(1)
+	for {
+		max := c.maxInFlight.Load()
+		if n <= max || c.maxInFlight.CompareAndSwap(max, n) {
+			return
+		}
+	}
+}
+
+// RequestFinished marks an in-flight request as completed
+func (c *Collector) RequestFinished() {
+	c.inFlight.Add(-1)
+}
+
+// RecordTokenRefresh counts a proactive session token refresh
+func (c *Collector) RecordTokenRefresh() {
+	c.tokenRefreshes.Add(1)
+}
+
+// TokenRefreshes returns the number of proactive session token refreshes
+func (c *Collector) TokenRefreshes() int64 {
+	return c.tokenRefreshes.Load()
+}
+
+// RecordLogin counts a login or re-login request
+func (c *Collector) RecordLogin() {
+	c.logins.Add(1)
+}
+
+// Logins returns the number of login and re-login requests sent
+func (c *Collector) Logins() int64 {
+	return c.logins.Load()
+}
+
+// RecordDialRetry counts a connection attempt retried after a dial failure
+func (c *Collector) RecordDialRetry() {
+	c.dialRetries.Add(1)
+}
+
+// DialRetries returns the number of retried connection attempts
+func (c *Collector) DialRetries() int64 {
+	return c.dialRetries.Load()
+}
+
+// RecordCancelledRequest counts an in-flight request cut off because its
+// worker was stopped mid-test. Such requests are neither successes nor
+// errors, as their outcome says nothing about the target.
+func (c *Collector) RecordCancelledRequest() {
+	c.cancelled.Add(1)
+}
+
+// CancelledRequests returns the number of in-flight requests cut off by
+// stopping their worker
+func (c *Collector) CancelledRequests() int64 {
+	return c.cancelled.Load()
+}
+
+// LastSuccess returns when the latest successful request completed, or the
+// zero time if none has
+func (c *Collector) LastSuccess() time.Time {
+	if ns := c.lastSuccess.Load(); ns != 0 {
+		return time.Unix(0, ns)
+	}
+	return time.Time{}
+}
+
+// GetConcurrency returns the average sampled and maximum observed number of
+// simultaneously in-flight requests
+func (c *Collector) GetConcurrency() (avg float64, max int64) {
 	c.mu.RLock()
 	defer c.mu.RUnlock()
 
-	result := make(map[string]*ActionStats)
-	for name, stats := range c.actions {
-		result[name] = stats
+	if c.concurrencySamples > 0 {
+		avg = float64(c.concurrencySum) / float64(c.concurrencySamples)
+	}
+	return avg, c.maxInFlight.Load()
+}
+
+// sampleConcurrency periodically samples the number of in-flight requests
+func (c *Collector) sampleConcurrency() {
+	defer close(c.samplingDone)
+
+	ticker := time.NewTicker(concurrencySampleInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-c.stopSampling:
+			return
+		case <-ticker.C:
+			c.mu.Lock()
+			c.concurrencySum += c.inFlight.Load()
+			c.concurrencySamples++
+			c.mu.Unlock()
+		}
 	}
+}
+
+// GetStats returns current aggregated statistics
+func (c *Collector) GetStats() map[string]*ActionStats {
+	result := make(map[string]*ActionStats)
+	c.forEachAction(func(stats *ActionStats) {
+		result[stats.Name] = stats
+	})
 	return result
 }
 
-// collect processes incoming metrics
+// actionStats returns the stats of an action, creating them on first use
+// for actions that were not preallocated. Once created the lookup takes no
+// lock.
+func (c *Collector) actionStats(name string) *ActionStats {
+	if stats, ok := c.actions.Load(name); ok {
+		return stats.(*ActionStats)
+	}
+	return c.createActionStats(&c.actions, name, func(stats *ActionStats) {
+		stats.shard = &shard{}
+	})
+}
+
+// createActionStats stores new stats for an action in actions unless another
+// goroutine created them first. Creating under actionsMu means only the
+// stats that are kept get built.
+func (c *Collector) createActionStats(actions *sync.Map, name string, init func(*ActionStats)) *ActionStats {
+	c.actionsMu.Lock()
+	defer c.actionsMu.Unlock()
+
+	if stats, ok := actions.Load(name); ok {
+		return stats.(*ActionStats)
+	}
+	stats := newActionStats(name)
+	if init != nil {
+		init(stats)
+	}
+	actions.Store(name, stats)
+	return stats
+}
+
+// forEachAction calls fn with the stats of every action
+func (c *Collector) forEachAction(fn func(*ActionStats)) {
+	c.actions.Range(func(_, stats interface{}) bool {
+		fn(stats.(*ActionStats))
+		return true
+	})
+}
+
+// newActionStats creates empty stats for an action. Only the latency
+// histogram is allocated; the others are created by recordValue.
+func newActionStats(name string) *ActionStats {
+	return &ActionStats{
+		Name:      name,
+		Histogram: hdrhistogram.New(1, 60000000, 3), // 1µs to 60s, 3 significant digits
+	}
+}
+
+// recordValue records a value in *hist, creating the histogram with the
+// given highest trackable value on first use
+func recordValue(hist **hdrhistogram.Histogram, highest, value int64) {
+	if *hist == nil {
+		*hist = hdrhistogram.New(1, highest, 3)
+	}
+	(*hist).RecordValue(value)
+}
+
+// valueAtQuantile returns a percentile of a lazily created histogram, 0 if
+// nothing was recorded
+func valueAtQuantile(hist *hdrhistogram.Histogram, percentile float64) int64 {
+	if hist == nil {
+		return 0
+	}
+	return hist.ValueAtQuantile(percentile)
+}
+
+// totalCount returns the values in a lazily created histogram
+func totalCount(hist *hdrhistogram.Histogram) int64 {
+	if hist == nil {
+		return 0
+	}
+	return hist.TotalCount()
+}
+
+// maxCollectGoroutines caps the goroutines aggregating metrics. Metrics of
+// different actions aggregate in parallel; beyond a few goroutines workers
+// need the CPU more than the collector does.
+const maxCollectGoroutines = 4
+
+// collect processes incoming metrics on several goroutines and rotates the
+// interval snapshots and time series on their ticks
 func (c *Collector) collect() {
 	defer close(c.done)
 
-	for metric := range c.metrics {
-		c.mu.Lock()
+	n := runtime.GOMAXPROCS(0)
+	if n > maxCollectGoroutines {
+		n = maxCollectGoroutines
+	}
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for metric := range c.metrics {
+				c.record(metric)
+				if c.samples != nil {
+					c.samples.add(metric)
+				}
+			}
+		}()
+	}
+	drained := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(drained)
+	}()
+
+	var tick <-chan time.Time
+	if c.interval > 0 {
+		ticker := time.NewTicker(c.interval)
+		defer ticker.Stop()
+		tick = ticker.C
+	}
+
+	var seriesTick <-chan time.Time
+	if c.seriesBase > 0 {
+		ticker := time.NewTicker(c.seriesBase)
+		defer ticker.Stop()
+		seriesTick = ticker.C
+	}
 
-		// Get or create action stats
-		stats, exists := c.actions[metric.Name]
-		if !exists {
-			hist := hdrhistogram.New(1, 60000000, 3) // 1µs to 60s, 3 significant digits
-			stats = &ActionStats{
-				Name:      metric.Name,
-				Histogram: hist,
+	for {
+		select {
+		case <-drained:
+			if c.interval > 0 {
+				c.rotateInterval(time.Now())
 			}
-			c.actions[metric.Name] = stats
+			if c.seriesBase > 0 {
+				c.rotateSeries(time.Now(), true)
+			}
+			return
+		case now := <-tick:
+			c.rotateInterval(now)
+		case now := <-seriesTick:
+			c.rotateSeries(now, false)
+		}
+	}
+}
+
+// record aggregates a single metric into its action stats and the
+// collector-wide aggregates
+func (c *Collector) record(metric RequestMetric) {
+	latencyMicros := metric.EndTime.Sub(metric.StartTime).Microseconds()
+	success := metric.Error == "" && successStatus(metric.StatusCode)
+
+	// Warm-up successes still show the target is up to --idle-timeout
+	if success {
+		c.storeLastSuccess(metric.EndTime)
+	}
+	if c.inWarmup(metric.StartTime) {
+		c.warmupIgnored.Add(1)
+		return
+	}
+
+	c.recorded.Add(1)
+	stats := c.actionStats(metric.Name)
+	stats.add(c, metric, latencyMicros, success)
+	stats.shard.add(c, metric, latencyMicros, success)
+	if c.inWindow(metric.StartTime) {
+		c.windowActionStats(metric.Name).add(c, metric, latencyMicros, success)
+	}
+}
+
+// storeLastSuccess moves the latest success time forward to end. Collector
+// goroutines may record out of order, so an earlier end never moves it back.
+func (c *Collector) storeLastSuccess(end time.Time) {
+	ns := end.UnixNano()
+	for {
+		last := c.lastSuccess.Load()
+		if ns <= last || c.lastSuccess.CompareAndSwap(last, ns) {
+			return
 		}
+	}
+}
+
+// successStatus reports whether a response status counts as a success: 2xx
+// and 3xx, and 101 Switching Protocols of an opened WebSocket
+func successStatus(code int) bool {
+	return code == 101 || code >= 200 && code < 400
+}
 
-		// Update stats
-		stats.mu.Lock()
-		latencyMicros := metric.EndTime.Sub(metric.StartTime).Microseconds()
+// add aggregates a single metric into the action stats
+func (stats *ActionStats) add(c *Collector, metric RequestMetric, latencyMicros int64, success bool) {
+	stats.mu.Lock()
+	defer stats.mu.Unlock()
+
+	if success {
+		stats.TotalOK++
+		stats.Histogram.RecordValue(latencyMicros)
+		if metric.NewConn {
+			recordValue(&stats.NewConnHistogram, 60000000, latencyMicros)
+		} else {
+			recordValue(&stats.ReusedConnHistogram, 60000000, latencyMicros)
+		}
+		if metric.ServerTime > 0 {
+			recordValue(&stats.ServerHistogram, 60000000, metric.ServerTime.Microseconds())
+		}
+		if metric.TTFB > 0 {
+			recordValue(&stats.TTFBHistogram, 60000000, metric.TTFB.Microseconds())
+		}
+		stats.PhaseTotals.add(metric.Phases, metric.EndTime.Sub(metric.StartTime))
+	} else {
+		stats.TotalErrors++
+		if stats.ErrorCategories == nil {
+			stats.ErrorCategories = make(map[string]int64)
+		}
+		stats.ErrorCategories[errorCategory(metric)]++
+	}
 
-		if metric.Error == "" && metric.StatusCode >= 200 && metric.StatusCode < 400 {
-			stats.TotalOK++
-			stats.Histogram.RecordValue(latencyMicros)
+	stats.BytesTotal += metric.BytesRead
+	if c.sizeStats && metric.StatusCode > 0 {
+		size := metric.BytesRead
+		if size > maxTrackedSize {
+			size = maxTrackedSize
+		}
+		recordValue(&stats.SizeHistogram, maxTrackedSize, size)
+	}
+	stats.Retries += int64(metric.Retries)
+	if metric.ConnClosed {
+		stats.ConnClosed++
+	}
+	if metric.Resent {
+		stats.Resends++
+		if metric.DedupFailed {
+			stats.DedupFailures++
+		}
+	}
+	for _, name := range metric.CaptureMisses {
+		if stats.CaptureMisses == nil {
+			stats.CaptureMisses = make(map[string]int64)
+		}
+		stats.CaptureMisses[name]++
+	}
+	if metric.TLSHandshake {
+		if metric.TLSResumed {
+			stats.TLSResumed++
 		} else {
-			stats.TotalErrors++
+			stats.TLSFullHandshakes++
 		}
+	}
+}
+
+// rotateInterval snapshots the current interval histogram and resets it
+func (c *Collector) rotateInterval(now time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
 
-		stats.BytesTotal += metric.BytesRead
-		stats.mu.Unlock()
+	// Merge and reset the actions' parts of the interval
+	c.intervalHist.Reset()
+	var errors int64
+	c.forEachShard(func(s *shard) {
+		if s.intervalHist != nil {
+			c.intervalHist.Merge(s.intervalHist)
+			s.intervalHist.Reset()
+		}
+		errors += s.intervalErrors
+		s.intervalErrors = 0
+	})
 
-		c.mu.Unlock()
+	snapshot := IntervalSnapshot{
+		Offset:      c.intervalStart.Sub(c.startTime),
+		Count:       c.intervalHist.TotalCount(),
+		Errors:      errors,
+		Percentiles: make([]time.Duration, len(IntervalQuantiles)),
+	}
+	for i, q := range IntervalQuantiles {
+		value := c.intervalHist.ValueAtQuantile(q)
+		if q == 0 {
+			value = c.intervalHist.Min()
+		}
+		snapshot.Percentiles[i] = time.Duration(value) * time.Microsecond
 	}
+
+	c.intervals = append(c.intervals, snapshot)
+	c.intervalStart = now
 }
 
 // GetLatencyPercentile returns the specified percentile from the histogram
@@ -124,3 +648,143 @@ func (as *ActionStats) GetLatencyPercentile(percentile float64) time.Duration {
 	micros := as.Histogram.ValueAtQuantile(percentile)
 	return time.Duration(micros) * time.Microsecond
 }
+
+// GetLatencyMin returns the lowest latency of a successful request
+func (as *ActionStats) GetLatencyMin() time.Duration {
+	as.mu.RLock()
+	defer as.mu.RUnlock()
+
+	return time.Duration(as.Histogram.Min()) * time.Microsecond
+}
+
+// GetLatencyMax returns the highest latency of a successful request
+func (as *ActionStats) GetLatencyMax() time.Duration {
+	as.mu.RLock()
+	defer as.mu.RUnlock()
+
+	return time.Duration(as.Histogram.Max()) * time.Microsecond
+}
+
+// GetLatencyMean returns the mean latency of successful requests
+func (as *ActionStats) GetLatencyMean() time.Duration {
+	as.mu.RLock()
+	defer as.mu.RUnlock()
+
+	return time.Duration(as.Histogram.Mean() * float64(time.Microsecond))
+}
+
+// GetLatencyStdDev returns the standard deviation of the latency of
+// successful requests
+func (as *ActionStats) GetLatencyStdDev() time.Duration {
+	as.mu.RLock()
+	defer as.mu.RUnlock()
+
+	return time.Duration(as.Histogram.StdDev() * float64(time.Microsecond))
+}
+
+// GetServerTimePercentile returns the specified percentile of server-reported
+// processing time
+func (as *ActionStats) GetServerTimePercentile(percentile float64) time.Duration {
+	as.mu.RLock()
+	defer as.mu.RUnlock()
+
+	micros := valueAtQuantile(as.ServerHistogram, percentile)
+	return time.Duration(micros) * time.Microsecond
+}
+
+// ServerTimeSamples returns how many successful requests reported a server time
+func (as *ActionStats) ServerTimeSamples() int64 {
+	as.mu.RLock()
+	defer as.mu.RUnlock()
+
+	return totalCount(as.ServerHistogram)
+}
+
+// GetTTFBPercentile returns the specified percentile of time to first byte
+func (as *ActionStats) GetTTFBPercentile(percentile float64) time.Duration {
+	as.mu.RLock()
+	defer as.mu.RUnlock()
+
+	micros := valueAtQuantile(as.TTFBHistogram, percentile)
+	return time.Duration(micros) * time.Microsecond
+}
+
+// TTFBSamples returns how many successful requests recorded a time to first
+// byte
+func (as *ActionStats) TTFBSamples() int64 {
+	as.mu.RLock()
+	defer as.mu.RUnlock()
+
+	return totalCount(as.TTFBHistogram)
+}
+
+// GetOverallPercentile returns the specified latency percentile across all actions
+func (c *Collector) GetOverallPercentile(percentile float64) time.Duration {
+	micros := c.mergedHistogram().ValueAtQuantile(percentile)
+	return time.Duration(micros) * time.Microsecond
+}
+
+// GetOverallPercentiles returns several latency percentiles across all
+// actions, merging the histograms only once
+func (c *Collector) GetOverallPercentiles(percentiles ...float64) []time.Duration {
+	merged := c.mergedHistogram()
+	result := make([]time.Duration, len(percentiles))
+	for i, percentile := range percentiles {
+		result[i] = time.Duration(merged.ValueAtQuantile(percentile)) * time.Microsecond
+	}
+	return result
+}
+
+// GetOverallMean returns the mean latency of successful requests across all actions
+func (c *Collector) GetOverallMean() time.Duration {
+	return time.Duration(c.mergedHistogram().Mean() * float64(time.Microsecond))
+}
+
+// GetOverallMin returns the lowest latency of a successful request across
+// all actions
+func (c *Collector) GetOverallMin() time.Duration {
+	return time.Duration(c.mergedHistogram().Min()) * time.Microsecond
+}
+
+// GetOverallMax returns the highest latency of a successful request across
+// all actions
+func (c *Collector) GetOverallMax() time.Duration {
+	return time.Duration(c.mergedHistogram().Max()) * time.Microsecond
+}
+
+// GetOverallStdDev returns the standard deviation of the latency of
+// successful requests across all actions
+func (c *Collector) GetOverallStdDev() time.Duration {
+	return time.Duration(c.mergedHistogram().StdDev() * float64(time.Microsecond))
+}
+
+// GetPercentilesWhere returns several latency percentiles across the actions
+// whose names match, e.g. all actions of one scenario
+func (c *Collector) GetPercentilesWhere(match func(name string) bool, percentiles ...float64) []time.Duration {
+	merged := c.mergedHistogramWhere(match)
+	result := make([]time.Duration, len(percentiles))
+	for i, percentile := range percentiles {
+		result[i] = time.Duration(merged.ValueAtQuantile(percentile)) * time.Microsecond
+	}
+	return result
+}
+
+// mergedHistogram returns a histogram combining the latencies of all actions
+func (c *Collector) mergedHistogram() *hdrhistogram.Histogram {
+	return c.mergedHistogramWhere(func(string) bool { return true })
+}
+
+// mergedHistogramWhere returns a histogram combining the latencies of the
+// actions whose names match
+func (c *Collector) mergedHistogramWhere(match func(name string) bool) *hdrhistogram.Histogram {
+	merged := hdrhistogram.New(1, 60000000, 3)
+	c.forEachAction(func(stats *ActionStats) {
+		if !match(stats.Name) {
+			return
+		}
+		stats.mu.RLock()
+		merged.Merge(stats.Histogram)
+		stats.mu.RUnlock()
+	})
+	return merged
+}
@@ -0,0 +1,161 @@
+package metrics
+
+import (
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// benchMetric returns a successful metric of one of several actions with
+// every collector-wide aggregate to update
+func benchMetric(i int64, actions int) RequestMetric {
+	start := time.Now()
+	return RequestMetric{
+		Name:       fmt.Sprintf("action%d", i%int64(actions)),
+		StartTime:  start,
+		EndTime:    start.Add(time.Duration(1000+i%5000) * time.Microsecond),
+		StatusCode: 200,
+		Proto:      "HTTP/1.1",
+		BytesRead:  512,
+		Phases:     [NumPhases]time.Duration{0, 0, 0, 800 * time.Microsecond, 200 * time.Microsecond},
+		TTFB:       800 * time.Microsecond,
+	}
+}
+
+func TestPreallocate(t *testing.T) {
+	c := NewCollector(DefaultBufferSize)
+	c.SetBlocking(true)
+	c.Preallocate([]string{"Login", "Search", "Export"})
+	// Aggregates enabled after preallocating must still cover those actions
+	c.EnableThroughputBuckets(time.Second)
+	c.EnableTimeSeries(time.Second)
+	c.EnableIntervals(time.Second)
+	c.Start()
+
+	start := time.Now()
+	c.Record(RequestMetric{Name: "Login", StartTime: start, EndTime: start.Add(10 * time.Millisecond), StatusCode: 200})
+	c.Record(RequestMetric{Name: "Search", StartTime: start, EndTime: start.Add(time.Millisecond), Error: "connection refused"})
+	c.Stop()
+
+	stats := c.GetStats()
+	if len(stats) != 3 {
+		t.Fatalf("GetStats() has %d actions, want 3", len(stats))
+	}
+	for name, want := range map[string][2]int64{"Login": {1, 0}, "Search": {0, 1}, "Export": {0, 0}} {
+		stat, ok := stats[name]
+		if !ok {
+			t.Fatalf("no stats for %s", name)
+		}
+		if stat.TotalOK != want[0] || stat.TotalErrors != want[1] {
+			t.Errorf("%s: %d ok, %d errors, want %d ok, %d errors", name, stat.TotalOK, stat.TotalErrors, want[0], want[1])
+		}
+	}
+
+	// An action that never ran reports zeros rather than garbage
+	export := stats["Export"]
+	if export.Retries != 0 || export.BytesTotal != 0 || len(export.ErrorCategories) != 0 {
+		t.Errorf("Export: %d retries, %d bytes, errors %v, want none", export.Retries, export.BytesTotal, export.ErrorCategories)
+	}
+	for _, p := range []float64{50, 95, 99} {
+		if got := export.GetLatencyPercentile(p); got != 0 {
+			t.Errorf("Export: p%v latency %s, want 0", p, got)
+		}
+	}
+	if got := export.GetTTFBPercentile(99); got != 0 {
+		t.Errorf("Export: p99 TTFB %s, want 0", got)
+	}
+}
+
+func TestFeatureHistogramsLazy(t *testing.T) {
+	c := NewCollector(DefaultBufferSize)
+	c.Preallocate([]string{"Login"})
+	stats := c.actionStats("Login")
+	if stats.NewConnHistogram != nil || stats.ReusedConnHistogram != nil || stats.ServerHistogram != nil ||
+		stats.TTFBHistogram != nil || stats.SizeHistogram != nil {
+		t.Fatal("feature histograms allocated before any value was recorded")
+	}
+	if newConns, reused := stats.ConnSamples(); newConns != 0 || reused != 0 || stats.GetNewConnPercentile(50) != 0 ||
+		stats.ServerTimeSamples() != 0 || stats.TTFBSamples() != 0 || stats.SizeSamples() != 0 || stats.GetSizePercentile(50) != 0 {
+		t.Error("empty stats report samples")
+	}
+
+	// A success on a reused connection with no server time, without size stats
+	start := time.Now()
+	c.record(RequestMetric{Name: "Login", StartTime: start, EndTime: start.Add(10 * time.Millisecond),
+		StatusCode: 200, BytesRead: 512, TTFB: 5 * time.Millisecond})
+	if stats.ReusedConnHistogram == nil || stats.TTFBHistogram == nil {
+		t.Error("histograms of recorded values not created")
+	}
+	if stats.NewConnHistogram != nil || stats.ServerHistogram != nil || stats.SizeHistogram != nil {
+		t.Error("histograms without values allocated")
+	}
+	if got := c.GetTTFBCount(); got != 1 {
+		t.Errorf("GetTTFBCount() = %d, want 1", got)
+	}
+
+	c.EnableSizeStats()
+	c.record(RequestMetric{Name: "Login", StartTime: start, EndTime: start.Add(10 * time.Millisecond), StatusCode: 200, BytesRead: 512})
+	if got := stats.SizeSamples(); got != 1 {
+		t.Errorf("SizeSamples() = %d, want 1 once size stats are enabled", got)
+	}
+}
+
+func TestActionStatsCreatedOnce(t *testing.T) {
+	c := NewCollector(DefaultBufferSize)
+	results := make([]*ActionStats, 32)
+	var wg sync.WaitGroup
+	for i := range results {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			results[i] = c.actionStats("Login")
+		}(i)
+	}
+	wg.Wait()
+	for i, stats := range results {
+		if stats != results[0] || stats.shard == nil {
+			t.Fatalf("goroutine %d got different or incomplete stats", i)
+		}
+	}
+}
+
+// BenchmarkCollectorRecord aggregates metrics of 16 actions from parallel
+// goroutines, with throughput buckets and a time series enabled. Metrics of
+// different actions must not contend on a collector-wide lock.
+func BenchmarkCollectorRecord(b *testing.B) {
+	c := NewCollector(DefaultBufferSize)
+	c.EnableThroughputBuckets(time.Second)
+	c.EnableTimeSeries(time.Second)
+
+	var n atomic.Int64
+	b.ReportAllocs()
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			c.record(benchMetric(n.Add(1), 16))
+		}
+	})
+	b.ReportMetric(float64(b.N)/b.Elapsed().Seconds(), "metrics/s")
+}
+
+// BenchmarkCollectorPipeline sends metrics through Record and the collector
+// goroutine, the path workers use
+func BenchmarkCollectorPipeline(b *testing.B) {
+	c := NewCollector(DefaultBufferSize)
+	c.SetBlocking(true)
+	c.EnableThroughputBuckets(time.Second)
+	c.EnableTimeSeries(time.Second)
+	c.Start()
+
+	var n atomic.Int64
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			c.Record(benchMetric(n.Add(1), 16))
+		}
+	})
+	c.Stop()
+	b.ReportMetric(float64(b.N)/b.Elapsed().Seconds(), "metrics/s")
+}
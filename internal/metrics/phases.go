@@ -0,0 +1,124 @@
+package metrics
+
+import (
+	"time"
+
+	"github.com/HdrHistogram/hdrhistogram-go"
+)
+
+// Phase is a stage of a request measured with httptrace
+type Phase int
+
+const (
+	PhaseDNS     Phase = iota // DNS lookup
+	PhaseConnect              // TCP connect
+	PhaseTLS                  // TLS handshake
+	PhaseServer               // Request written to first response byte
+	PhaseBody                 // First response byte to body fully read
+	NumPhases
+)
+
+// PhaseNames are the report names of each phase
+var PhaseNames = [NumPhases]string{"dns", "connect", "tls", "server", "body"}
+
+// PhaseTotals holds the time spent in each phase summed over many requests,
+// along with the summed total request time
+type PhaseTotals struct {
+	Phases   [NumPhases]time.Duration
+	Total    time.Duration
+	Requests int64
+}
+
+// Other returns request time not attributed to any phase, such as waiting
+// for a pooled connection or writing the request
+func (t PhaseTotals) Other() time.Duration {
+	other := t.Total
+	for _, d := range t.Phases {
+		other -= d
+	}
+	if other < 0 {
+		return 0
+	}
+	return other
+}
+
+// add accumulates one request's phases
+func (t *PhaseTotals) add(phases [NumPhases]time.Duration, total time.Duration) {
+	for i, d := range phases {
+		t.Phases[i] += d
+	}
+	t.Total += total
+	t.Requests++
+}
+
+// GetPhaseTotals returns the time spent per phase across all actions
+func (c *Collector) GetPhaseTotals() PhaseTotals {
+	var totals PhaseTotals
+	c.forEachAction(func(stats *ActionStats) {
+		stats.mu.RLock()
+		for i, d := range stats.PhaseTotals.Phases {
+			totals.Phases[i] += d
+		}
+		totals.Total += stats.PhaseTotals.Total
+		totals.Requests += stats.PhaseTotals.Requests
+		stats.mu.RUnlock()
+	})
+	return totals
+}
+
+// GetPhasePercentile returns the specified percentile of a phase's duration
+// across all requests in which the phase occurred
+func (c *Collector) GetPhasePercentile(phase Phase, percentile float64) time.Duration {
+	merged := hdrhistogram.New(1, 60000000, 3)
+	c.forEachShard(func(s *shard) {
+		if s.phaseHists[phase] != nil {
+			merged.Merge(s.phaseHists[phase])
+		}
+	})
+	return time.Duration(merged.ValueAtQuantile(percentile)) * time.Microsecond
+}
+
+// GetPhaseCount returns the number of successful requests in which a phase
+// occurred, e.g. how many opened a new connection
+func (c *Collector) GetPhaseCount(phase Phase) int64 {
+	var count int64
+	c.forEachShard(func(s *shard) {
+		if s.phaseHists[phase] != nil {
+			count += s.phaseHists[phase].TotalCount()
+		}
+	})
+	return count
+}
+
+// GetTTFBPercentile returns the specified percentile of the time to first
+// byte of successful requests: from sending the request, connection setup
+// included, to the first response byte
+func (c *Collector) GetTTFBPercentile(percentile float64) time.Duration {
+	merged := hdrhistogram.New(1, 60000000, 3)
+	c.forEachAction(func(stats *ActionStats) {
+		stats.mu.RLock()
+		if stats.TTFBHistogram != nil {
+			merged.Merge(stats.TTFBHistogram)
+		}
+		stats.mu.RUnlock()
+	})
+	return time.Duration(merged.ValueAtQuantile(percentile)) * time.Microsecond
+}
+
+// GetTTFBCount returns the number of successful requests with a traced time
+// to first byte
+func (c *Collector) GetTTFBCount() int64 {
+	var count int64
+	c.forEachAction(func(stats *ActionStats) {
+		count += stats.TTFBSamples()
+	})
+	return count
+}
+
+// GetPhaseTotals returns the time spent per phase by this action
+func (as *ActionStats) GetPhaseTotals() PhaseTotals {
+	as.mu.RLock()
+	defer as.mu.RUnlock()
+
+	return as.PhaseTotals
+}
@@ -0,0 +1,99 @@
+package metrics
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+// prometheusQuantiles are the latency quantiles exported per action
+var prometheusQuantiles = []float64{0.5, 0.9, 0.95, 0.99}
+
+// prometheusSample is one action's values at scrape time
+type prometheusSample struct {
+	name      string
+	ok        int64
+	errors    int64
+	bytes     int64
+	retries   int64
+	quantiles []float64 // Seconds, matching prometheusQuantiles
+	sum       float64   // Seconds; the histogram mean times the count
+}
+
+// WritePrometheus writes the current per-action counters and latency
+// quantiles of successful requests in the Prometheus text exposition format
+func (c *Collector) WritePrometheus(w io.Writer) error {
+	var samples []prometheusSample
+	c.forEachAction(func(stats *ActionStats) {
+		samples = append(samples, stats.prometheusSample())
+	})
+	sort.Slice(samples, func(i, j int) bool { return samples[i].name < samples[j].name })
+
+	out := bufio.NewWriter(w)
+
+	fmt.Fprintln(out, "# HELP stampede_requests_total Requests completed, by action and result.")
+	fmt.Fprintln(out, "# TYPE stampede_requests_total counter")
+	for _, s := range samples {
+		fmt.Fprintf(out, "stampede_requests_total{action=%s,result=\"ok\"} %d\n", promLabel(s.name), s.ok)
+		fmt.Fprintf(out, "stampede_requests_total{action=%s,result=\"error\"} %d\n", promLabel(s.name), s.errors)
+	}
+
+	fmt.Fprintln(out, "# HELP stampede_response_bytes_total Response body bytes received, by action.")
+	fmt.Fprintln(out, "# TYPE stampede_response_bytes_total counter")
+	for _, s := range samples {
+		fmt.Fprintf(out, "stampede_response_bytes_total{action=%s} %d\n", promLabel(s.name), s.bytes)
+	}
+
+	fmt.Fprintln(out, "# HELP stampede_retries_total Retried request attempts, by action.")
+	fmt.Fprintln(out, "# TYPE stampede_retries_total counter")
+	for _, s := range samples {
+		fmt.Fprintf(out, "stampede_retries_total{action=%s} %d\n", promLabel(s.name), s.retries)
+	}
+
+	fmt.Fprintln(out, "# HELP stampede_request_duration_seconds Latency of successful requests, by action.")
+	fmt.Fprintln(out, "# TYPE stampede_request_duration_seconds summary")
+	for _, s := range samples {
+		for i, q := range prometheusQuantiles {
+			fmt.Fprintf(out, "stampede_request_duration_seconds{action=%s,quantile=\"%g\"} %g\n", promLabel(s.name), q, s.quantiles[i])
+		}
+		fmt.Fprintf(out, "stampede_request_duration_seconds_sum{action=%s} %g\n", promLabel(s.name), s.sum)
+		fmt.Fprintf(out, "stampede_request_duration_seconds_count{action=%s} %d\n", promLabel(s.name), s.ok)
+	}
+
+	fmt.Fprintln(out, "# HELP stampede_in_flight_requests Requests currently in flight.")
+	fmt.Fprintln(out, "# TYPE stampede_in_flight_requests gauge")
+	fmt.Fprintf(out, "stampede_in_flight_requests %d\n", c.inFlight.Load())
+
+	fmt.Fprintln(out, "# HELP stampede_dropped_metrics_total Completed requests not recorded because the metrics channel was full.")
+	fmt.Fprintln(out, "# TYPE stampede_dropped_metrics_total counter")
+	fmt.Fprintf(out, "stampede_dropped_metrics_total %d\n", c.dropped.Load())
+
+	return out.Flush()
+}
+
+// prometheusSample reads the action's exported values under one lock
+func (as *ActionStats) prometheusSample() prometheusSample {
+	as.mu.RLock()
+	defer as.mu.RUnlock()
+
+	s := prometheusSample{
+		name:      as.Name,
+		ok:        as.TotalOK,
+		errors:    as.TotalErrors,
+		bytes:     as.BytesTotal,
+		retries:   as.Retries,
+		quantiles: make([]float64, len(prometheusQuantiles)),
+		sum:       as.Histogram.Mean() * float64(as.Histogram.TotalCount()) / 1e6,
+	}
+	for i, q := range prometheusQuantiles {
+		s.quantiles[i] = float64(as.Histogram.ValueAtQuantile(q*100)) / 1e6
+	}
+	return s
+}
+
+// promLabel quotes a label value, escaping backslashes, quotes and newlines
+func promLabel(value string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(value) + `"`
+}
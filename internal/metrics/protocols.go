@@ -0,0 +1,13 @@
+package metrics
+
+// GetProtocols returns the number of responses per protocol, e.g. HTTP/1.1
+// or HTTP/2.0
+func (c *Collector) GetProtocols() map[string]int64 {
+	result := make(map[string]int64)
+	c.forEachShard(func(s *shard) {
+		for proto, count := range s.protocols {
+			result[proto] += count
+		}
+	})
+	return result
+}
@@ -0,0 +1,52 @@
+package metrics
+
+import (
+	"time"
+
+	"github.com/HdrHistogram/hdrhistogram-go"
+)
+
+// RecordQueueTime records how long a scheduled iteration waited for a free
+// worker in open-arrival mode
+func (c *Collector) RecordQueueTime(d time.Duration) {
+	c.queueMu.Lock()
+	defer c.queueMu.Unlock()
+
+	if c.queueHist == nil {
+		c.queueHist = hdrhistogram.New(1, 600000000, 3) // 1µs to 10min
+	}
+	c.queueHist.RecordValue(d.Microseconds())
+}
+
+// RecordDroppedArrival counts a scheduled iteration dropped because the
+// arrival queue was full
+func (c *Collector) RecordDroppedArrival() {
+	c.droppedArrivals.Add(1)
+}
+
+// DroppedArrivals returns the number of dropped scheduled iterations
+func (c *Collector) DroppedArrivals() int64 {
+	return c.droppedArrivals.Load()
+}
+
+// QueueTimeSamples returns the number of iterations with a recorded queue time
+func (c *Collector) QueueTimeSamples() int64 {
+	c.queueMu.Lock()
+	defer c.queueMu.Unlock()
+
+	if c.queueHist == nil {
+		return 0
+	}
+	return c.queueHist.TotalCount()
+}
+
+// GetQueueTimePercentile returns the specified percentile of queue time
+func (c *Collector) GetQueueTimePercentile(percentile float64) time.Duration {
+	c.queueMu.Lock()
+	defer c.queueMu.Unlock()
+
+	if c.queueHist == nil {
+		return 0
+	}
+	return time.Duration(c.queueHist.ValueAtQuantile(percentile)) * time.Microsecond
+}
@@ -0,0 +1,69 @@
+package metrics
+
+import "time"
+
+// MaxRecentWindow is the longest window GetRecentRPS can average over
+const MaxRecentWindow = 60 * time.Second
+
+// recentSlots is the number of one-second slots in the recent ring buffer
+const recentSlots = int(MaxRecentWindow / time.Second)
+
+// recentCounter is a ring buffer of successful requests per wall-clock
+// second. Each slot remembers the second it counts, so slots left over from
+// a minute ago read as empty.
+type recentCounter struct {
+	counts  [recentSlots]int64
+	seconds [recentSlots]int64 // Unix second each slot counts
+}
+
+// add counts a success completed at end
+func (r *recentCounter) add(end time.Time) {
+	sec := end.Unix()
+	slot := int(sec % int64(recentSlots))
+	if r.seconds[slot] != sec {
+		r.seconds[slot] = sec
+		r.counts[slot] = 0
+	}
+	r.counts[slot]++
+}
+
+// sum returns the successes counted in the seconds [from, to)
+func (r *recentCounter) sum(from, to int64) int64 {
+	var total int64
+	for sec := from; sec < to; sec++ {
+		slot := int(sec % int64(recentSlots))
+		if r.seconds[slot] == sec {
+			total += r.counts[slot]
+		}
+	}
+	return total
+}
+
+// GetRecentRPS returns the rate of successful requests over the last window,
+// rounded to whole seconds and capped at MaxRecentWindow. Only completed
+// seconds count, so the rate does not dip while the current second fills.
+// Early in the test the window shrinks to the seconds elapsed so far.
+func (c *Collector) GetRecentRPS(window time.Duration) float64 {
+	seconds := int64(window / time.Second)
+	if seconds < 1 {
+		seconds = 1
+	}
+	if seconds > int64(recentSlots) {
+		seconds = int64(recentSlots)
+	}
+
+	now := time.Now().Unix()
+	from := now - seconds
+	if start := c.startTime.Unix(); from < start {
+		from = start
+	}
+	if from >= now {
+		return 0
+	}
+
+	var total int64
+	c.forEachShard(func(s *shard) {
+		total += s.recent.sum(from, now)
+	})
+	return float64(total) / float64(now-from)
+}
@@ -0,0 +1,131 @@
+package metrics
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync/atomic"
+)
+
+// sampleQueueSize is how many samples may wait for the writer before new
+// ones are dropped
+const sampleQueueSize = 10000
+
+// Sample is the compact record of one request written with --samples
+type Sample struct {
+	StartMs   int64   `json:"start_ms"` // Unix time the request started, in milliseconds
+	Action    string  `json:"action"`
+	Method    string  `json:"method,omitempty"`
+	Status    int     `json:"status"`
+	LatencyMs float64 `json:"latency_ms"`
+	Bytes     int64   `json:"bytes"`
+	Proto     string  `json:"proto,omitempty"`
+	Retries   int     `json:"retries,omitempty"`
+	NewConn   bool    `json:"new_conn,omitempty"`
+	Error     string  `json:"error,omitempty"`
+}
+
+// SampleWriter streams every recorded request as a JSON line to a file for
+// offline analysis. Samples are queued to a dedicated goroutine and written
+// through a buffer, so a slow disk never holds up the collector; samples
+// that don't fit in the queue are dropped and counted.
+type SampleWriter struct {
+	file    *os.File
+	samples chan Sample
+	done    chan struct{}
+	err     error // First write error, read after done is closed
+
+	written atomic.Int64
+	dropped atomic.Int64
+}
+
+// NewSampleWriter creates filename and starts writing samples to it
+func NewSampleWriter(filename string) (*SampleWriter, error) {
+	file, err := os.Create(filename)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create samples file: %w", err)
+	}
+
+	w := &SampleWriter{
+		file:    file,
+		samples: make(chan Sample, sampleQueueSize),
+		done:    make(chan struct{}),
+	}
+	go w.run()
+	return w, nil
+}
+
+// add queues a metric's sample without blocking
+func (w *SampleWriter) add(metric RequestMetric) {
+	sample := Sample{
+		StartMs:   metric.StartTime.UnixMilli(),
+		Action:    metric.Name,
+		Method:    metric.Method,
+		Status:    metric.StatusCode,
+		LatencyMs: float64(metric.EndTime.Sub(metric.StartTime).Microseconds()) / 1000,
+		Bytes:     metric.BytesRead,
+		Proto:     metric.Proto,
+		Retries:   metric.Retries,
+		NewConn:   metric.NewConn,
+		Error:     metric.Error,
+	}
+
+	select {
+	case w.samples <- sample:
+	default:
+		w.dropped.Add(1)
+	}
+}
+
+// run writes queued samples until the queue is closed
+func (w *SampleWriter) run() {
+	defer close(w.done)
+
+	buf := bufio.NewWriterSize(w.file, 64*1024)
+	encoder := json.NewEncoder(buf)
+	for sample := range w.samples {
+		if w.err != nil {
+			continue // Keep draining so add never blocks
+		}
+		if w.err = encoder.Encode(sample); w.err == nil {
+			w.written.Add(1)
+		}
+	}
+
+	if err := buf.Flush(); w.err == nil {
+		w.err = err
+	}
+}
+
+// Close writes the queued samples, flushes them and closes the file. It
+// must be called after the collector has stopped.
+func (w *SampleWriter) Close() error {
+	close(w.samples)
+	<-w.done
+
+	if err := w.file.Close(); w.err == nil {
+		w.err = err
+	}
+	if w.err != nil {
+		return fmt.Errorf("failed to write samples: %w", w.err)
+	}
+	return nil
+}
+
+// Written returns the number of samples written
+func (w *SampleWriter) Written() int64 {
+	return w.written.Load()
+}
+
+// Dropped returns the number of samples dropped because the writer fell
+// behind
+func (w *SampleWriter) Dropped() int64 {
+	return w.dropped.Load()
+}
+
+// EnableSamples makes the collector stream every request it records to w.
+// It must be called before Start.
+func (c *Collector) EnableSamples(w *SampleWriter) {
+	c.samples = w
+}
@@ -0,0 +1,105 @@
+package metrics
+
+import (
+	"sync"
+	"time"
+
+	"github.com/HdrHistogram/hdrhistogram-go"
+)
+
+// shard is one action's part of the collector-wide aggregates. Metrics are
+// aggregated into the shard of their action under the shard's own lock, so
+// metrics of different actions never contend; readers merge the shards.
+type shard struct {
+	mu sync.Mutex
+
+	buckets   []ThroughputBucket // Throughput buckets, when enabled
+	protocols map[string]int64   // Responses per protocol
+	recent    recentCounter      // Successes per second of the last minute
+
+	// Open time series point and interval snapshot, created on the first
+	// success once enabled. The collector merges and resets them when it
+	// rotates.
+	seriesHist     *hdrhistogram.Histogram
+	seriesErrors   int64
+	intervalHist   *hdrhistogram.Histogram
+	intervalErrors int64
+
+	// Per-phase durations, created the first time the phase occurs
+	phaseHists [NumPhases]*hdrhistogram.Histogram
+}
+
+// add aggregates a completed request into the shard
+func (s *shard) add(c *Collector, metric RequestMetric, latencyMicros int64, success bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.countThroughput(c, metric.EndTime, success)
+	s.countProtocol(metric.Proto)
+	if !success {
+		s.seriesErrors++
+		s.intervalErrors++
+		return
+	}
+
+	s.recent.add(metric.EndTime)
+	if c.seriesBase > 0 {
+		if s.seriesHist == nil {
+			s.seriesHist = hdrhistogram.New(1, 60000000, 3)
+		}
+		s.seriesHist.RecordValue(latencyMicros)
+	}
+	if c.interval > 0 {
+		if s.intervalHist == nil {
+			s.intervalHist = hdrhistogram.New(1, 60000000, 3)
+		}
+		s.intervalHist.RecordValue(latencyMicros)
+	}
+	for i, d := range metric.Phases {
+		if d <= 0 {
+			continue
+		}
+		if s.phaseHists[i] == nil {
+			s.phaseHists[i] = hdrhistogram.New(1, 60000000, 3)
+		}
+		s.phaseHists[i].RecordValue(d.Microseconds())
+	}
+}
+
+// countThroughput adds a completed request to its bucket. The caller must
+// hold s.mu.
+func (s *shard) countThroughput(c *Collector, end time.Time, success bool) {
+	if c.bucketSize <= 0 || end.Before(c.startTime) {
+		return
+	}
+
+	index := int(end.Sub(c.startTime) / c.bucketSize)
+	for len(s.buckets) <= index {
+		s.buckets = append(s.buckets, ThroughputBucket{Offset: time.Duration(len(s.buckets)) * c.bucketSize})
+	}
+	if success {
+		s.buckets[index].OK++
+	} else {
+		s.buckets[index].Errors++
+	}
+}
+
+// countProtocol counts a response's protocol. The caller must hold s.mu.
+func (s *shard) countProtocol(proto string) {
+	if proto == "" {
+		return
+	}
+	if s.protocols == nil {
+		s.protocols = make(map[string]int64)
+	}
+	s.protocols[proto]++
+}
+
+// forEachShard calls fn with every action's shard locked
+func (c *Collector) forEachShard(fn func(*shard)) {
+	c.forEachAction(func(stats *ActionStats) {
+		stats.shard.mu.Lock()
+		defer stats.shard.mu.Unlock()
+		fn(stats.shard)
+	})
+}
@@ -0,0 +1,22 @@
+package metrics
+
+// maxTrackedSize caps the response sizes recorded in SizeHistogram; larger
+// bodies are recorded as this size
+const maxTrackedSize = 1 << 30
+
+// GetSizePercentile returns the specified percentile of response body sizes
+// in bytes
+func (as *ActionStats) GetSizePercentile(percentile float64) int64 {
+	as.mu.RLock()
+	defer as.mu.RUnlock()
+
+	return valueAtQuantile(as.SizeHistogram, percentile)
+}
+
+// SizeSamples returns how many response sizes were recorded
+func (as *ActionStats) SizeSamples() int64 {
+	as.mu.RLock()
+	defer as.mu.RUnlock()
+
+	return totalCount(as.SizeHistogram)
+}
@@ -0,0 +1,37 @@
+package metrics
+
+import "time"
+
+// ThroughputBucket counts the requests completed in one time bucket
+type ThroughputBucket struct {
+	Offset time.Duration // Bucket start relative to the test start
+	OK     int64         // Successful requests completed in the bucket
+	Errors int64         // Failed requests completed in the bucket
+}
+
+// EnableThroughputBuckets makes the collector count completed requests per
+// bucket of the given size. It must be called before Start.
+func (c *Collector) EnableThroughputBuckets(size time.Duration) {
+	c.bucketSize = size
+}
+
+// ThroughputBucketSize returns the throughput bucket size, 0 if disabled
+func (c *Collector) ThroughputBucketSize() time.Duration {
+	return c.bucketSize
+}
+
+// GetThroughput returns the request counts of every bucket from the test
+// start to the latest completed request, including empty buckets
+func (c *Collector) GetThroughput() []ThroughputBucket {
+	var result []ThroughputBucket
+	c.forEachShard(func(s *shard) {
+		for len(result) < len(s.buckets) {
+			result = append(result, ThroughputBucket{Offset: time.Duration(len(result)) * c.bucketSize})
+		}
+		for i, bucket := range s.buckets {
+			result[i].OK += bucket.OK
+			result[i].Errors += bucket.Errors
+		}
+	})
+	return result
+}
@@ -0,0 +1,134 @@
+package metrics
+
+import (
+	"time"
+
+	"github.com/HdrHistogram/hdrhistogram-go"
+)
+
+// MaxTimeSeriesPoints caps the time series. When it is exceeded, adjacent
+// points are merged and the interval doubles, so long runs keep their whole
+// timeline at a coarser resolution in bounded memory.
+const MaxTimeSeriesPoints = 600
+
+// TimeSeriesPoint summarizes the requests completed in one interval
+type TimeSeriesPoint struct {
+	Offset time.Duration // Interval start relative to the test start
+	Length time.Duration // Time the interval covered
+	OK     int64         // Successful requests completed in the interval
+	Errors int64         // Failed requests completed in the interval
+
+	// Latency percentiles of the interval's successful requests
+	P50, P95, P99 time.Duration
+}
+
+// RPS returns the successful requests per second of the interval
+func (p TimeSeriesPoint) RPS() float64 {
+	if p.Length <= 0 {
+		return 0
+	}
+	return float64(p.OK) / p.Length.Seconds()
+}
+
+// merge combines two consecutive points. Merged percentiles keep the higher
+// of the two, so a latency spike is not averaged away by downsampling.
+func (p TimeSeriesPoint) merge(next TimeSeriesPoint) TimeSeriesPoint {
+	maxDuration := func(a, b time.Duration) time.Duration {
+		if a > b {
+			return a
+		}
+		return b
+	}
+	return TimeSeriesPoint{
+		Offset: p.Offset,
+		Length: p.Length + next.Length,
+		OK:     p.OK + next.OK,
+		Errors: p.Errors + next.Errors,
+		P50:    maxDuration(p.P50, next.P50),
+		P95:    maxDuration(p.P95, next.P95),
+		P99:    maxDuration(p.P99, next.P99),
+	}
+}
+
+// EnableTimeSeries makes the collector roll the latency of successful
+// requests into a time series point every interval. It must be called before
+// Start.
+func (c *Collector) EnableTimeSeries(interval time.Duration) {
+	c.seriesBase = interval
+	c.seriesInterval = interval
+	c.seriesStart = c.startTime
+	c.seriesHist = hdrhistogram.New(1, 60000000, 3) // Merges the actions' parts of a point
+}
+
+// TimeSeriesInterval returns the current time series interval, 0 if
+// disabled. It grows as the series is downsampled.
+func (c *Collector) TimeSeriesInterval() time.Duration {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	return c.seriesInterval
+}
+
+// GetTimeSeries returns the time series points taken so far
+func (c *Collector) GetTimeSeries() []TimeSeriesPoint {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	result := make([]TimeSeriesPoint, len(c.series))
+	copy(result, c.series)
+	return result
+}
+
+// rotateSeries closes the current time series point if its interval has
+// elapsed, or unconditionally when final is set at the end of the test
+func (c *Collector) rotateSeries(now time.Time, final bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	// Ticks come every base interval; after downsampling a point spans several
+	if !final && now.Sub(c.seriesStart) < c.seriesInterval-c.seriesBase/2 {
+		return
+	}
+
+	// Merge and reset the actions' parts of the open point
+	c.seriesHist.Reset()
+	var errors int64
+	c.forEachShard(func(s *shard) {
+		if s.seriesHist != nil {
+			c.seriesHist.Merge(s.seriesHist)
+			s.seriesHist.Reset()
+		}
+		errors += s.seriesErrors
+		s.seriesErrors = 0
+	})
+
+	c.series = append(c.series, TimeSeriesPoint{
+		Offset: c.seriesStart.Sub(c.startTime),
+		Length: now.Sub(c.seriesStart),
+		OK:     c.seriesHist.TotalCount(),
+		Errors: errors,
+		P50:    time.Duration(c.seriesHist.ValueAtQuantile(50)) * time.Microsecond,
+		P95:    time.Duration(c.seriesHist.ValueAtQuantile(95)) * time.Microsecond,
+		P99:    time.Duration(c.seriesHist.ValueAtQuantile(99)) * time.Microsecond,
+	})
+	c.seriesStart = now
+
+	if len(c.series) > MaxTimeSeriesPoints {
+		c.downsampleSeries()
+	}
+}
+
+// downsampleSeries merges adjacent pairs of points and doubles the interval
+// of points still to come. The caller must hold c.mu.
+func (c *Collector) downsampleSeries() {
+	merged := c.series[:0]
+	for i := 0; i < len(c.series); i += 2 {
+		point := c.series[i]
+		if i+1 < len(c.series) {
+			point = point.merge(c.series[i+1])
+		}
+		merged = append(merged, point)
+	}
+	c.series = merged
+	c.seriesInterval *= 2
+}
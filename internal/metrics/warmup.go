@@ -0,0 +1,27 @@
+package metrics
+
+import "time"
+
+// EnableWarmup makes the collector ignore requests started before until, so
+// connection setup, JIT and cache warming don't skew the results. Ignored
+// requests are only counted. It must be called before Start.
+func (c *Collector) EnableWarmup(until time.Time) {
+	c.warmupUntil = until
+}
+
+// Warmup returns when the warm-up ends, and false if there is none
+func (c *Collector) Warmup() (until time.Time, ok bool) {
+	return c.warmupUntil, !c.warmupUntil.IsZero()
+}
+
+// WarmupCount returns the number of requests ignored because they started
+// during the warm-up
+func (c *Collector) WarmupCount() int64 {
+	return c.warmupIgnored.Load()
+}
+
+// inWarmup reports whether a request started at start is ignored as part of
+// the warm-up
+func (c *Collector) inWarmup(start time.Time) bool {
+	return start.Before(c.warmupUntil)
+}
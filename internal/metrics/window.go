@@ -0,0 +1,45 @@
+package metrics
+
+import "time"
+
+// EnableWindow makes the collector also aggregate requests started between
+// from and to into separate steady-state stats, so warm-up and ramp-down
+// traffic can be excluded without hiding it. It must be called before Start.
+func (c *Collector) EnableWindow(from, to time.Time) {
+	c.windowFrom = from
+	c.windowTo = to
+}
+
+// Window returns the steady-state window, and false if none is enabled
+func (c *Collector) Window() (from, to time.Time, ok bool) {
+	return c.windowFrom, c.windowTo, !c.windowTo.IsZero()
+}
+
+// GetWindowStats returns the statistics of requests started within the
+// steady-state window
+func (c *Collector) GetWindowStats() map[string]*ActionStats {
+	result := make(map[string]*ActionStats)
+	c.windowActions.Range(func(_, stats interface{}) bool {
+		result[stats.(*ActionStats).Name] = stats.(*ActionStats)
+		return true
+	})
+	return result
+}
+
+// inWindow reports whether a request started at start counts toward the
+// steady-state window
+func (c *Collector) inWindow(start time.Time) bool {
+	if c.windowTo.IsZero() {
+		return false
+	}
+	return !start.Before(c.windowFrom) && start.Before(c.windowTo)
+}
+
+// windowActionStats returns the steady-state stats of an action, creating
+// them on first use
+func (c *Collector) windowActionStats(name string) *ActionStats {
+	if stats, ok := c.windowActions.Load(name); ok {
+		return stats.(*ActionStats)
+	}
+	return c.createActionStats(&c.windowActions, name, nil)
+}
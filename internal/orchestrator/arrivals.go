@@ -0,0 +1,39 @@
+package orchestrator
+
+import (
+	"context"
+	"time"
+)
+
+// arrivalQueueSize bounds the scheduled iterations waiting for a free worker
+// in open-arrival mode; further arrivals are dropped and counted
+const arrivalQueueSize = 10000
+
+// scheduleArrivals emits one scheduled start time per iteration at the
+// configured arrival rate until ctx is done. Arrivals are timestamped with
+// when they were due, so any lag in dispatching them counts as queue time.
+func (o *Orchestrator) scheduleArrivals(ctx context.Context) {
+	interval := time.Second / time.Duration(o.cfg.ArrivalRate)
+	next := time.Now()
+
+	timer := time.NewTimer(0)
+	defer timer.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-timer.C:
+		}
+
+		// Emit every arrival that is due
+		for now := time.Now(); !next.After(now); next = next.Add(interval) {
+			select {
+			case o.arrivals <- next:
+			default:
+				o.collector.RecordDroppedArrival()
+			}
+		}
+		timer.Reset(time.Until(next))
+	}
+}
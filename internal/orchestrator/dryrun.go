@@ -0,0 +1,28 @@
+package orchestrator
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+
+	"stampede-shooter/internal/worker"
+)
+
+// dryRun prints the requests of one script iteration of the first user, in
+// its scenario if the script has several, without starting workers or
+// sending anything. Loading the orchestrator already validated the script,
+// data and credentials files; this also catches templates that fail to
+// expand and URLs that can't be sent.
+func (o *Orchestrator) dryRun() error {
+	userID := o.cfg.UserIDOffset + 1
+	s, shared := o.nextWorker()
+	log.Printf("Dry run: %d actions expanded for user %d, nothing is sent", len(s.Actions), userID)
+
+	w := worker.New(userID, o.cfg, s, o.collector, o.credentials, shared)
+	if failed := w.DryRun(context.Background(), os.Stdout, o.cfg.LoginURL); failed > 0 {
+		return fmt.Errorf("dry run: %d requests failed to build", failed)
+	}
+	log.Printf("Dry run OK")
+	return nil
+}
@@ -0,0 +1,54 @@
+package orchestrator
+
+import (
+	"context"
+	"log"
+	"sync/atomic"
+	"time"
+)
+
+// idleCheckInterval bounds how often the idle watch checks for progress
+const idleCheckInterval = time.Second
+
+// idleWatch reports whether the test was ended by --idle-timeout
+type idleWatch struct {
+	aborted atomic.Bool
+}
+
+// watchIdle cancels the test once no request has succeeded for
+// --idle-timeout, so a hard-down backend doesn't keep the test spinning
+// until its duration elapses
+func (o *Orchestrator) watchIdle(ctx context.Context, cancel context.CancelFunc) *idleWatch {
+	watch := &idleWatch{}
+	start := time.Now()
+
+	interval := o.cfg.IdleTimeout / 4
+	if interval > idleCheckInterval {
+		interval = idleCheckInterval
+	}
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case now := <-ticker.C:
+				last := o.collector.LastSuccess()
+				if last.Before(start) {
+					last = start
+				}
+				if idle := now.Sub(last); idle >= o.cfg.IdleTimeout {
+					log.Printf("No successful requests for %v, aborting", idle.Round(time.Second))
+					watch.aborted.Store(true)
+					cancel()
+					return
+				}
+			}
+		}
+	}()
+
+	return watch
+}
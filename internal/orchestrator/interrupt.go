@@ -0,0 +1,60 @@
+package orchestrator
+
+import (
+	"context"
+	"log"
+	"os"
+	"os/signal"
+	"sync/atomic"
+	"syscall"
+)
+
+// interruptExitCode is the conventional exit status after SIGINT
+const interruptExitCode = 130
+
+// interruptWatch reports whether the test was ended by SIGINT or SIGTERM
+type interruptWatch struct {
+	interrupted atomic.Bool
+	signals     chan os.Signal
+	done        chan struct{}
+}
+
+// watchInterrupt ends the test on the first SIGINT or SIGTERM, so workers
+// finish and the report is still printed and saved. A second signal exits
+// immediately. stop must be called once the run is over.
+func (o *Orchestrator) watchInterrupt(ctx context.Context, cancel context.CancelFunc) *interruptWatch {
+	watch := &interruptWatch{
+		signals: make(chan os.Signal, 2),
+		done:    make(chan struct{}),
+	}
+	signal.Notify(watch.signals, os.Interrupt, syscall.SIGTERM)
+
+	go func() {
+		received := 0
+		for {
+			select {
+			case <-watch.done:
+				return
+			case sig := <-watch.signals:
+				received++
+				if received > 1 {
+					log.Printf("Received %v again, exiting without a report", sig)
+					os.Exit(interruptExitCode)
+				}
+				if ctx.Err() == nil {
+					watch.interrupted.Store(true)
+					cancel()
+				}
+				log.Printf("Received %v, stopping workers and reporting results (repeat to exit immediately)", sig)
+			}
+		}
+	}()
+
+	return watch
+}
+
+// stop restores the default signal handling
+func (w *interruptWatch) stop() {
+	signal.Stop(w.signals)
+	close(w.done)
+}
@@ -0,0 +1,39 @@
+package orchestrator
+
+import (
+	"context"
+	"log"
+	"time"
+)
+
+// maxRequestsCheckInterval is how often the test is checked for having
+// completed --max-requests requests
+const maxRequestsCheckInterval = 10 * time.Millisecond
+
+// watchMaxRequests cancels the test once --max-requests requests have
+// completed. Workers stop sending once the cap is taken; this ends the test
+// when the last of those requests is done rather than at --duration.
+func (o *Orchestrator) watchMaxRequests(ctx context.Context, cancel context.CancelFunc) {
+	max := int64(o.cfg.MaxRequests)
+
+	go func() {
+		ticker := time.NewTicker(maxRequestsCheckInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				// Warm-up, dropped and cancelled requests took from the cap too
+				done := o.collector.RecordedCount() + o.collector.WarmupCount() +
+					o.collector.DroppedCount() + o.collector.CancelledRequests()
+				if done >= max {
+					log.Printf("Reached --max-requests %d, stopping", max)
+					cancel()
+					return
+				}
+			}
+		}
+	}()
+}
@@ -0,0 +1,45 @@
+package orchestrator
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"time"
+)
+
+// serveMetrics exposes the collector on /metrics in the Prometheus text
+// format until ctx is done. The listener is opened before returning so a
+// bad --metrics-addr fails the run up front.
+func (o *Orchestrator) serveMetrics(ctx context.Context) error {
+	listener, err := net.Listen("tcp", o.cfg.MetricsAddr)
+	if err != nil {
+		return fmt.Errorf("failed to listen on --metrics-addr: %w", err)
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/metrics", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		if err := o.collector.WritePrometheus(w); err != nil {
+			log.Printf("Failed to write metrics: %v", err)
+		}
+	})
+	server := &http.Server{Handler: mux, ReadHeaderTimeout: 5 * time.Second}
+
+	go func() {
+		if err := server.Serve(listener); err != nil && err != http.ErrServerClosed {
+			log.Printf("Metrics server error: %v", err)
+		}
+	}()
+
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		server.Shutdown(shutdownCtx)
+	}()
+
+	log.Printf("Serving Prometheus metrics on http://%s/metrics", listener.Addr())
+	return nil
+}
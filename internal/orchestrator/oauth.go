@@ -0,0 +1,55 @@
+package orchestrator
+
+import (
+	"crypto/tls"
+	"fmt"
+	"net/http"
+	"time"
+
+	"stampede-shooter/internal/config"
+	"stampede-shooter/internal/util"
+)
+
+// oauthTimeout bounds each token request, OAuth or refresh
+const oauthTimeout = 30 * time.Second
+
+// newOAuthProvider creates the OAuth2 client-credentials provider configured
+// by the --oauth-* flags
+func newOAuthProvider(cfg config.Config, credentials *util.CredentialsManager) (*util.OAuthProvider, error) {
+	if cfg.OAuthClientID == "" {
+		return nil, fmt.Errorf("--oauth-token-url requires --oauth-client-id")
+	}
+
+	return util.NewOAuthProvider(util.OAuthConfig{
+		TokenURL:      cfg.OAuthTokenURL,
+		ClientID:      cfg.OAuthClientID,
+		ClientSecret:  cfg.OAuthClientSecret,
+		Scope:         cfg.OAuthScope,
+		RefreshMargin: cfg.TokenRefreshMargin,
+	}, authClient(cfg), credentials), nil
+}
+
+// newBearerProvider creates the bearer token provider configured by
+// --auth-token and --auth-refresh-url
+func newBearerProvider(cfg config.Config, credentials *util.CredentialsManager) (*util.BearerProvider, error) {
+	if cfg.OAuthTokenURL != "" {
+		return nil, fmt.Errorf("--auth-token and --auth-refresh-url cannot be combined with --oauth-token-url")
+	}
+	if cfg.AuthRefreshURL != "" && cfg.AuthTokenField == "" {
+		return nil, fmt.Errorf("--auth-refresh-url requires --auth-token-field")
+	}
+	return util.NewBearerProvider(cfg.AuthToken, cfg.AuthRefreshURL, cfg.AuthTokenField, authClient(cfg), credentials), nil
+}
+
+// authClient returns the client token requests are sent with. It is
+// separate from the workers' clients so token requests don't count against
+// the load or share its connections.
+func authClient(cfg config.Config) *http.Client {
+	return &http.Client{
+		Timeout: oauthTimeout,
+		Transport: &http.Transport{
+			Proxy:           http.ProxyFromEnvironment,
+			TLSClientConfig: &tls.Config{InsecureSkipVerify: cfg.InsecureTLS},
+		},
+	}
+}
@@ -4,7 +4,10 @@ import (
 	"context"
 	"fmt"
 	"log"
-	"sync"
+	"net"
+	"net/url"
+	"strings"
+	"time"
 
 	"stampede-shooter/internal/config"
 	"stampede-shooter/internal/metrics"
@@ -16,64 +19,517 @@ import (
 
 // Orchestrator coordinates the load test execution
 type Orchestrator struct {
-	cfg         config.Config
-	script      *script.Script
-	collector   *metrics.Collector
-	reporter    *reporter.Reporter
-	credentials *util.CredentialsManager
+	cfg          config.Config
+	script       *script.Script
+	collector    *metrics.Collector
+	samples      *metrics.SampleWriter // Raw per-request samples, nil without --samples
+	reporter     *reporter.Reporter
+	credentials  *util.CredentialsManager
+	data         *script.Data // Lists for {{pick}}, nil without --data
+	shared       *worker.Shared
+	scenarios    *script.ScenarioAssigner // Assigns workers to scenarios, nil without scenarios
+	scenarioRuns []scenarioRun            // What each scenario's workers run with
+	stages       []reporter.Stage         // Stepped load profile, nil without --stages
+	arrivals     chan time.Time           // Open-arrival schedule, nil in closed mode
+	oauth        *util.OAuthProvider      // OAuth token provider, nil unless --oauth-token-url is set
+	bearer       *util.BearerProvider     // Bearer token provider, nil unless --auth-token or --auth-refresh-url is set
 }
 
+// maxAutoUsers caps the worker count derived from --actions-per-second
+const maxAutoUsers = 1000
+
+// maxTraceUsers is the user count above which --trace warns about its output
+const maxTraceUsers = 5
+
+// globalBurstDivisor sizes the --global-rps burst as 1/globalBurstDivisor of
+// a second's worth of requests
+const globalBurstDivisor = 100
+
 // New creates a new orchestrator
 func New(cfg config.Config) (*Orchestrator, error) {
+	// A stepped load profile sets the peak user count and the duration
+	var stages []reporter.Stage
+	if cfg.Stages != "" {
+		if cfg.IsSet("users") || cfg.IsSet("duration") {
+			return nil, fmt.Errorf("--stages sets the user count and duration; don't combine it with --users or --duration")
+		}
+		if cfg.RampUp > 0 {
+			return nil, fmt.Errorf("--stages and --ramp-up cannot be used together")
+		}
+		var err error
+		if stages, err = parseStages(cfg.Stages); err != nil {
+			return nil, fmt.Errorf("invalid --stages: %w", err)
+		}
+		cfg.Users, cfg.Duration = stagesTotal(stages)
+	}
+
+	// Size the worker pool for total-throughput pacing. Each worker only
+	// needs to sustain one action per second unless --users caps the pool.
+	if cfg.ActionsPerSec > 0 && !cfg.IsSet("users") && stages == nil {
+		cfg.Users = cfg.ActionsPerSec
+		if cfg.Users > maxAutoUsers {
+			cfg.Users = maxAutoUsers
+		}
+	}
+
 	// Load test script
-	script, err := script.LoadScript(cfg.ScriptPath)
+	var s *script.Script
+	var err error
+	if cfg.ScriptDir != "" {
+		s, err = script.LoadScriptDir(cfg.ScriptDir)
+	} else {
+		s, err = script.LoadScript(cfg.ScriptPath)
+	}
 	if err != nil {
 		return nil, fmt.Errorf("failed to load script: %w", err)
 	}
+	if len(s.MissingEnv) > 0 {
+		if cfg.StrictEnv {
+			return nil, fmt.Errorf("script references unset environment variables: %s", strings.Join(s.MissingEnv, ", "))
+		}
+		log.Printf("Warning: script references unset environment variables, expanded to empty: %s", strings.Join(s.MissingEnv, ", "))
+	}
+
+	var data *script.Data
+	if cfg.DataFile != "" {
+		if data, err = script.LoadData(cfg.DataFile); err != nil {
+			return nil, err
+		}
+	}
+	if err := s.SetData(data); err != nil {
+		return nil, err
+	}
+
+	switch cfg.TemplateEngine {
+	case script.EngineSimple:
+	case script.EngineGo:
+		if err := s.CompileTemplates(); err != nil {
+			return nil, fmt.Errorf("failed to compile templates: %w", err)
+		}
+	default:
+		return nil, fmt.Errorf("invalid --template-engine %q: expected %s or %s", cfg.TemplateEngine, script.EngineSimple, script.EngineGo)
+	}
+
+	if cfg.WeightsFrom != "" {
+		// Weights only take effect when actions are picked by weight
+		if cfg.ActionMode != script.ModeWeighted {
+			return nil, fmt.Errorf("--weights-from requires --action-mode %s", script.ModeWeighted)
+		}
+		report, err := s.DeriveWeights(cfg.WeightsFrom)
+		if err != nil {
+			return nil, fmt.Errorf("failed to derive weights: %w", err)
+		}
+		logDerivedWeights(cfg.WeightsFrom, report)
+	}
+
+	var picker *script.ActionPicker
+	switch cfg.ActionMode {
+	case script.ModeSequential:
+	case script.ModeWeighted:
+		if picker, err = script.NewActionPicker(s.Actions); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("invalid --action-mode %q: expected %s or %s", cfg.ActionMode, script.ModeSequential, script.ModeWeighted)
+	}
 
 	// Load credentials if provided
 	var credentials *util.CredentialsManager
 	if cfg.CredentialsFile != "" {
-		credentials, err = util.LoadCredentials(cfg.CredentialsFile)
+		if cfg.CredentialsStream {
+			credentials, err = util.LoadCredentialsStreaming(cfg.CredentialsFile)
+		} else {
+			credentials, err = util.LoadCredentials(cfg.CredentialsFile)
+		}
 		if err != nil {
 			return nil, fmt.Errorf("failed to load credentials: %w", err)
 		}
 
 		// Validate we have enough credentials
-		if err := credentials.Validate(cfg.Users); err != nil {
-			log.Printf("Warning: %v", err)
+		if err := credentials.Validate(cfg.UserIDOffset + cfg.Users); err != nil {
+			if cfg.UniqueCredentials {
+				credentials.Close()
+				return nil, fmt.Errorf("--unique-credentials: %w", err)
+			}
+			log.Printf("Warning: %v. Users will share credentials round-robin", err)
 		} else {
 			log.Printf("Loaded %d credentials for %d users", credentials.Count(), cfg.Users)
 		}
 	}
 
 	// Create metrics collector
-	collector := metrics.NewCollector()
+	if cfg.MetricsBuffer < 1 {
+		return nil, fmt.Errorf("--metrics-buffer must be at least 1")
+	}
+	collector := metrics.NewCollector(cfg.MetricsBuffer)
+	collector.SetBlocking(cfg.MetricsBlocking)
+	if cfg.SizeStats {
+		collector.EnableSizeStats()
+	}
+	collector.Preallocate(s.ActionNames())
+	if cfg.HeatmapFile != "" {
+		if cfg.HeatmapInterval <= 0 {
+			return nil, fmt.Errorf("--heatmap-interval must be positive")
+		}
+		collector.EnableIntervals(cfg.HeatmapInterval)
+	}
+	if cfg.ThroughputBucket < 0 {
+		return nil, fmt.Errorf("--throughput-bucket must not be negative")
+	}
+	if cfg.ThroughputBucket > 0 {
+		collector.EnableThroughputBuckets(cfg.ThroughputBucket)
+	}
+	if cfg.TimeSeries < 0 {
+		return nil, fmt.Errorf("--timeseries-interval must not be negative")
+	}
+	if cfg.TimeSeries > 0 {
+		collector.EnableTimeSeries(cfg.TimeSeries)
+	}
+	if cfg.MaxIdleConns < 0 || cfg.MaxIdlePerHost < 0 || cfg.MaxConnsPerHost < 0 || cfg.ConnIdleTimeout < 0 {
+		return nil, fmt.Errorf("connection pool limits and --conn-idle-timeout must not be negative")
+	}
+	if cfg.ForceHTTP2 && (cfg.ProxyList != "" || cfg.Proxy != "") {
+		return nil, fmt.Errorf("--force-http2 cannot be combined with --proxy or --proxy-list: h2c connections are dialed directly")
+	}
+	if cfg.HTTP10 && (cfg.HTTP2 || cfg.ForceHTTP2) {
+		return nil, fmt.Errorf("--http1.0 cannot be combined with --http2 or --force-http2")
+	}
+	if cfg.HTTP10 && (cfg.ProxyList != "" || cfg.Proxy != "") {
+		return nil, fmt.Errorf("--http1.0 cannot be combined with --proxy or --proxy-list: HTTP/1.0 connections are dialed directly")
+	}
+	if cfg.RPSWindow < time.Second || cfg.RPSWindow > metrics.MaxRecentWindow {
+		return nil, fmt.Errorf("--rps-window must be between 1s and %s", metrics.MaxRecentWindow)
+	}
+
+	if cfg.TimeScale < 0 {
+		return nil, fmt.Errorf("--time-scale must not be negative")
+	}
+
+	if cfg.BadgeFile != "" && !validBadgeMetric(cfg.BadgeMetric) {
+		return nil, fmt.Errorf("invalid --badge-metric %q: expected one of %s", cfg.BadgeMetric, strings.Join(reporter.BadgeMetrics, ", "))
+	}
+
+	healthWeights, err := reporter.ParseHealthWeights(cfg.HealthWeights)
+	if err != nil {
+		return nil, fmt.Errorf("invalid --health-weights: %w", err)
+	}
+
+	if cfg.FailureWebhook != "" && cfg.SLAP95 == 0 && cfg.SLAErrorRate == 0 {
+		log.Printf("Warning: --on-failure-webhook has no effect without --sla-p95 or --sla-error-rate")
+	}
+
+	// Validate token expiry tracking
+	if src := cfg.TokenExpirySource; src != "" && src != "jwt" && !strings.HasPrefix(src, "header:") {
+		return nil, fmt.Errorf("invalid --token-expiry-source %q: expected jwt or header:<Name>", src)
+	}
+	if cfg.TokenExpirySource != "" && cfg.LoginURL == "" {
+		log.Printf("Warning: --token-expiry-source has no effect without --login-url")
+	}
+	if cfg.LoginBody != "" && cfg.LoginURL == "" {
+		log.Printf("Warning: --login-body has no effect without --login-url")
+	}
+	if cfg.Trace && cfg.Users > maxTraceUsers {
+		log.Printf("Warning: --trace prints every request; with %d users the output will be huge and slow the test down", cfg.Users)
+	}
+	if cfg.TraceSecrets && !cfg.Trace {
+		log.Printf("Warning: --trace-secrets has no effect without --trace")
+	}
+	if s.HasWebSocket() && (cfg.Proxy != "" || cfg.ProxyList != "" || cfg.UnixSocket != "") {
+		log.Printf("Warning: WebSocket actions connect directly and ignore --proxy, --proxy-list and --unix-socket")
+	}
+	if cfg.UniqueCredentials && cfg.CredentialsFile == "" {
+		log.Printf("Warning: --unique-credentials has no effect without --credentials")
+	}
+
+	if cfg.UserIDOffset < 0 {
+		return nil, fmt.Errorf("--user-id-offset must not be negative")
+	}
+
+	if cfg.IdleTimeout < 0 {
+		return nil, fmt.Errorf("--idle-timeout must not be negative")
+	}
+
+	if cfg.MaxRequests < 0 {
+		return nil, fmt.Errorf("--max-requests must not be negative")
+	}
+
+	if cfg.DialRetries < 0 {
+		return nil, fmt.Errorf("--dial-retries must not be negative")
+	}
+
+	if cfg.RampUp < 0 {
+		return nil, fmt.Errorf("--ramp-up must not be negative")
+	}
+	if cfg.RampUp >= cfg.Duration && cfg.RampUp > 0 {
+		log.Printf("Warning: --ramp-up %v is not shorter than --duration %v; not all workers will start", cfg.RampUp, cfg.Duration)
+	}
+
+	if cfg.Warmup < 0 {
+		return nil, fmt.Errorf("--warmup must not be negative")
+	}
+	if cfg.Warmup > 0 && cfg.Warmup >= cfg.Duration {
+		return nil, fmt.Errorf("--warmup %v must be shorter than --duration %v", cfg.Warmup, cfg.Duration)
+	}
+
+	if cfg.WindowWarmup < 0 || cfg.WindowCooldown < 0 {
+		return nil, fmt.Errorf("--window-warmup and --window-cooldown must not be negative")
+	}
+	if excluded := cfg.WindowWarmup + cfg.WindowCooldown; excluded > 0 && excluded >= cfg.Duration {
+		return nil, fmt.Errorf("--window-warmup plus --window-cooldown (%v) must be shorter than --duration %v", excluded, cfg.Duration)
+	}
+
+	if cfg.GlobalRPS < 0 {
+		return nil, fmt.Errorf("--global-rps must not be negative")
+	}
+
+	if cfg.ArrivalRate < 0 {
+		return nil, fmt.Errorf("--arrival-rate must not be negative")
+	}
+	if cfg.ArrivalRate > 0 && cfg.ActionsPerSec > 0 {
+		return nil, fmt.Errorf("--arrival-rate and --actions-per-second cannot be used together")
+	}
+
+	if cfg.UnixSocket != "" && cfg.SourceIPs != "" {
+		return nil, fmt.Errorf("--unix-socket and --source-ips cannot be used together")
+	}
+
+	// Parse local source addresses
+	sourceIPs, err := parseSourceIPs(cfg.SourceIPs)
+	if err != nil {
+		return nil, err
+	}
+
+	// Create state shared across workers
+	shared := &worker.Shared{
+		RetryBudget: util.NewRetryBudget(cfg.RetryBudget),
+		SourceIPs:   sourceIPs,
+		Consistency: worker.NewConsistencyStore(),
+		Idempotency: worker.NewIdempotencyPool(),
+		Picker:      picker,
+	}
+	if cfg.MaxRequests > 0 {
+		shared.RequestLimit = util.NewRequestLimit(int64(cfg.MaxRequests))
+	}
+	if cfg.ActionsPerSec > 0 {
+		shared.RateLimiter = util.NewRateLimiter(cfg.ActionsPerSec)
+	}
+	var arrivals chan time.Time
+	if cfg.ArrivalRate > 0 {
+		arrivals = make(chan time.Time, arrivalQueueSize)
+		shared.Arrivals = arrivals
+	}
+	if cfg.Proxy != "" && cfg.ProxyList != "" {
+		return nil, fmt.Errorf("--proxy and --proxy-list are mutually exclusive")
+	}
+	if cfg.Proxy != "" {
+		shared.Proxy, err = util.ParseProxyURL(cfg.Proxy)
+		if err != nil {
+			return nil, fmt.Errorf("invalid --proxy: %w", err)
+		}
+	}
+	if cfg.BaseURL != "" {
+		shared.BaseURL, err = url.Parse(cfg.BaseURL)
+		if err != nil || !shared.BaseURL.IsAbs() || shared.BaseURL.Host == "" {
+			return nil, fmt.Errorf("invalid --base-url %q: expected an absolute URL such as https://staging.example.com", cfg.BaseURL)
+		}
+	}
+	if cfg.Cookie != "" {
+		shared.Cookies, err = worker.ParseCookies(cfg.Cookie)
+		if err != nil {
+			return nil, fmt.Errorf("invalid --cookie: %w", err)
+		}
+
+		// The cookies are for the host under test: the base URL's, or the
+		// login URL's when action URLs are absolute
+		shared.CookieURL = shared.BaseURL
+		if loginURL, err := url.Parse(cfg.LoginURL); shared.CookieURL == nil && err == nil && loginURL.IsAbs() && loginURL.Host != "" {
+			shared.CookieURL = loginURL
+		}
+		if shared.CookieURL == nil {
+			return nil, fmt.Errorf("--cookie needs --base-url or an absolute --login-url to know which host to set the cookies for")
+		}
+	}
+	if cfg.ProxyList != "" {
+		shared.Proxies, err = util.LoadProxyPool(cfg.ProxyList, cfg.ProxyDrop)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if cfg.LoginRPS > 0 {
+		shared.LoginLimit = util.NewRateLimiter(cfg.LoginRPS)
+	}
+	if cfg.GlobalRPS > 0 {
+		// A small burst keeps the combined rate close to the cap at any moment
+		shared.GlobalLimit = util.NewRateLimiterBurst(cfg.GlobalRPS, cfg.GlobalRPS/globalBurstDivisor)
+	}
+	var oauth *util.OAuthProvider
+	if cfg.OAuthTokenURL != "" {
+		if oauth, err = newOAuthProvider(cfg, credentials); err != nil {
+			return nil, err
+		}
+		shared.Credentials = oauth
+	}
+	var bearer *util.BearerProvider
+	if cfg.AuthToken != "" || cfg.AuthRefreshURL != "" {
+		if bearer, err = newBearerProvider(cfg, credentials); err != nil {
+			return nil, err
+		}
+		shared.Credentials = bearer
+	}
+	if cfg.ErrorDumpFile != "" && !cfg.Simulate && !cfg.DryRun {
+		shared.ErrorDump, err = worker.NewErrorDumper(cfg.ErrorDumpFile, cfg.ErrorDumpMax, cfg.ErrorDumpSecrets)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	var samples *metrics.SampleWriter
+	if cfg.SamplesFile != "" && !cfg.Simulate && !cfg.DryRun {
+		if samples, err = metrics.NewSampleWriter(cfg.SamplesFile); err != nil {
+			return nil, err
+		}
+		collector.EnableSamples(samples)
+	}
+
+	var scenarios *script.ScenarioAssigner
+	var scenarioRuns []scenarioRun
+	if len(s.Scenarios) > 0 {
+		scenarios = script.NewScenarioAssigner(s.Scenarios)
+		if scenarioRuns, err = newScenarioRuns(s, shared); err != nil {
+			return nil, err
+		}
+	}
 
 	// Create reporter
 	reporter := reporter.New(collector, cfg.Verbose)
+	if cfg.Retries > 0 {
+		reporter.SetRetryBudget(shared.RetryBudget)
+	}
+	if cfg.ActionsPerSec > 0 {
+		reporter.SetPacing(cfg.ActionsPerSec, cfg.Users)
+	}
+	if cfg.ServerTimeHeader != "" {
+		reporter.SetServerTimeHeader(cfg.ServerTimeHeader)
+	}
+	reporter.SetSizeStats(cfg.SizeStats)
+	reporter.SetSLA(cfg.SLAP95, cfg.SLAErrorRate)
+	if shared.Proxies != nil {
+		reporter.SetProxyPool(shared.Proxies)
+	}
+	reporter.SetHealthWeights(healthWeights)
+	reporter.SetRecentWindow(cfg.RPSWindow)
+	if scenarios != nil {
+		reporter.SetScenarios(scenarios)
+	}
+	if stages != nil {
+		reporter.SetStages(stages)
+	}
+	if picker != nil && scenarios == nil {
+		reporter.SetActionPicker(picker, s.Actions)
+	}
 
 	return &Orchestrator{
-		cfg:         cfg,
-		script:      script,
-		collector:   collector,
-		reporter:    reporter,
-		credentials: credentials,
+		cfg:          cfg,
+		script:       s,
+		collector:    collector,
+		samples:      samples,
+		reporter:     reporter,
+		credentials:  credentials,
+		data:         data,
+		shared:       shared,
+		scenarios:    scenarios,
+		scenarioRuns: scenarioRuns,
+		stages:       stages,
+		oauth:        oauth,
+		bearer:       bearer,
+		arrivals:     arrivals,
 	}, nil
 }
 
 // Run executes the load test
 func (o *Orchestrator) Run() error {
-	log.Printf("Starting load test with %d users for %v...", o.cfg.Users, o.cfg.Duration)
+	if o.cfg.DryRun {
+		return o.dryRun()
+	}
+
+	if o.cfg.MaxRequests > 0 {
+		log.Printf("Starting load test with %d users for %d requests or %v, whichever comes first...",
+			o.cfg.Users, o.cfg.MaxRequests, o.cfg.Duration)
+	} else {
+		log.Printf("Starting load test with %d users for %v...", o.cfg.Users, o.cfg.Duration)
+	}
 	log.Printf("Loaded script with %d actions", len(o.script.Actions))
+	if o.cfg.ScriptDir != "" {
+		for _, file := range o.script.Files {
+			log.Printf("  from %s", file)
+		}
+	}
+	if o.scenarios != nil {
+		o.logScenarios()
+	}
+
+	if o.cfg.ActionsPerSec > 0 {
+		log.Printf("Pacing %d actions/s across %d users (%.2f actions/s per user)",
+			o.cfg.ActionsPerSec, o.cfg.Users, float64(o.cfg.ActionsPerSec)/float64(o.cfg.Users))
+	}
+
+	if o.shared.Picker != nil {
+		log.Printf("Weighted mode: each iteration runs one action picked by weight")
+	}
+
+	if o.arrivals != nil {
+		log.Printf("Open arrival: starting %d iterations/s, at most %d concurrently", o.cfg.ArrivalRate, o.cfg.Users)
+	}
+
+	if o.shared.Proxies != nil {
+		log.Printf("Routing workers through %d proxies from %s", o.shared.Proxies.Count(), o.cfg.ProxyList)
+	}
+	if o.shared.Proxy != nil {
+		log.Printf("Routing requests through proxy %s", o.shared.Proxy.Redacted())
+	}
+
+	if o.cfg.MetricsBlocking {
+		log.Printf("Blocking metrics: workers wait when the %d-deep metrics buffer is full", o.cfg.MetricsBuffer)
+	}
+
+	if o.cfg.GlobalRPS > 0 {
+		log.Printf("Capping the combined request rate at %d/s across all users", o.cfg.GlobalRPS)
+	}
+
+	if o.cfg.LoginRPS > 0 {
+		log.Printf("Capping login requests at %d/s across all users", o.cfg.LoginRPS)
+	}
+
+	if len(o.shared.SourceIPs) > 0 {
+		log.Printf("Binding workers to %d source IPs", len(o.shared.SourceIPs))
+	}
+
+	if o.oauth != nil {
+		log.Printf("Fetching OAuth tokens per user from %s", o.cfg.OAuthTokenURL)
+	}
+
+	if o.bearer != nil && o.cfg.AuthRefreshURL != "" {
+		log.Printf("Sending a bearer token, refreshed from %s after 401 responses", o.cfg.AuthRefreshURL)
+	}
 
 	if o.credentials != nil {
 		log.Printf("Using credentials from: %s (%d available)", o.cfg.CredentialsFile, o.credentials.Count())
 	}
 
+	if o.cfg.Warmup > 0 {
+		o.collector.EnableWarmup(time.Now().Add(o.cfg.Warmup))
+		log.Printf("Warm-up: requests started in the first %v are not recorded", o.cfg.Warmup)
+	}
+
+	if o.cfg.WindowWarmup > 0 || o.cfg.WindowCooldown > 0 {
+		now := time.Now()
+		o.collector.EnableWindow(now.Add(o.cfg.WindowWarmup), now.Add(o.cfg.Duration-o.cfg.WindowCooldown))
+		log.Printf("Steady-state window: requests started between %v and %v into the test",
+			o.cfg.WindowWarmup, o.cfg.Duration-o.cfg.WindowCooldown)
+	}
+
 	// Start metrics collector
 	o.collector.Start()
-	defer o.collector.Stop()
 
 	// Start live reporter
 	o.reporter.StartLiveReporting()
@@ -82,32 +538,94 @@ func (o *Orchestrator) Run() error {
 	ctx, cancel := context.WithTimeout(context.Background(), o.cfg.Duration)
 	defer cancel()
 
+	interrupt := o.watchInterrupt(ctx, cancel)
+	defer interrupt.stop()
+
+	var idle *idleWatch
+	if o.cfg.IdleTimeout > 0 {
+		idle = o.watchIdle(ctx, cancel)
+	}
+
+	if o.cfg.MaxRequests > 0 {
+		o.watchMaxRequests(ctx, cancel)
+	}
+
+	if o.cfg.MetricsAddr != "" {
+		if err := o.serveMetrics(ctx); err != nil {
+			return err
+		}
+	}
+
 	// Start workers
-	log.Printf("Starting %d workers...", o.cfg.Users)
+	if o.stages != nil {
+		log.Printf("Stepping through %d stages, up to %d workers...", len(o.stages), o.cfg.Users)
+	} else {
+		log.Printf("Starting %d workers...", o.cfg.Users)
+	}
+	if o.cfg.UserIDOffset > 0 {
+		log.Printf("User IDs %d to %d", o.cfg.UserIDOffset+1, o.cfg.UserIDOffset+o.cfg.Users)
+	}
 
-	var wg sync.WaitGroup
-	for i := 0; i < o.cfg.Users; i++ {
-		wg.Add(1)
-		go func(userID int) {
-			defer wg.Done()
+	if o.arrivals != nil {
+		go o.scheduleArrivals(ctx)
+	}
 
-			// Create worker with credentials
-			w := worker.New(userID, o.cfg, o.script, o.collector, o.credentials)
+	spawnInterval := o.rampInterval()
+	if spawnInterval > 0 {
+		log.Printf("Ramping up over %v (a new worker every %v)", o.cfg.RampUp, spawnInterval)
+	}
 
-			// Run worker
-			if err := w.Run(ctx, o.cfg.LoginURL); err != nil {
-				log.Printf("Worker %d error: %v", userID, err)
-			}
-		}(i + 1) // User IDs start from 1
+	pool := o.newWorkerPool(ctx)
+	if o.stages != nil {
+		o.runStages(ctx, pool)
+	} else {
+		o.rampUp(ctx, pool, spawnInterval)
 	}
 
 	// Wait for test duration or context cancellation
 	<-ctx.Done()
 
-	log.Println("Test completed, waiting for workers to finish...")
+	if interrupt.interrupted.Load() {
+		log.Println("Test interrupted, waiting for workers to finish...")
+	} else {
+		log.Println("Test completed, waiting for workers to finish...")
+	}
 
 	// Wait for all workers to finish
-	wg.Wait()
+	pool.wait()
+
+	if o.credentials != nil {
+		if err := o.credentials.Err(); err != nil {
+			log.Printf("Warning: some requests used empty credentials: %v", err)
+		}
+		o.credentials.Close()
+	}
+	o.data.Close()
+
+	if o.oauth != nil {
+		log.Printf("OAuth tokens fetched: %d", o.oauth.Fetches())
+	}
+	if o.bearer != nil && o.cfg.AuthRefreshURL != "" {
+		log.Printf("Bearer token refreshes: %d", o.bearer.Refreshes())
+	}
+
+	// Drain remaining metrics before reporting
+	o.collector.Stop()
+
+	if o.shared.ErrorDump != nil {
+		o.shared.ErrorDump.Close()
+		log.Printf("Failed requests dumped to: %s", o.cfg.ErrorDumpFile)
+	}
+
+	if o.samples != nil {
+		if err := o.samples.Close(); err != nil {
+			return err
+		}
+		log.Printf("%d request samples saved to: %s", o.samples.Written(), o.cfg.SamplesFile)
+		if dropped := o.samples.Dropped(); dropped > 0 {
+			log.Printf("Warning: %d samples dropped because writing them fell behind", dropped)
+		}
+	}
 
 	// Generate final report
 	o.reporter.PrintFinalReport()
@@ -120,5 +638,91 @@ func (o *Orchestrator) Run() error {
 		log.Printf("Results saved to: %s", o.cfg.OutputFile)
 	}
 
+	if o.cfg.CSVFile != "" {
+		if err := o.reporter.SaveCSV(o.cfg.CSVFile); err != nil {
+			return fmt.Errorf("failed to save CSV: %w", err)
+		}
+		log.Printf("CSV results saved to: %s", o.cfg.CSVFile)
+	}
+
+	if o.cfg.HeatmapFile != "" {
+		if err := o.reporter.SaveHeatmap(o.cfg.HeatmapFile, o.cfg.HeatmapInterval); err != nil {
+			return fmt.Errorf("failed to save heatmap: %w", err)
+		}
+		log.Printf("Heatmap saved to: %s", o.cfg.HeatmapFile)
+	}
+
+	// Alert on failed thresholds; best effort, never changes the outcome
+	if o.cfg.FailureWebhook != "" {
+		if sent, err := o.reporter.SendFailureWebhook(o.cfg.FailureWebhook, o.cfg.FailureTemplate); err != nil {
+			log.Printf("Warning: failure webhook: %v", err)
+		} else if sent {
+			log.Printf("SLA failed, summary posted to webhook")
+		}
+	}
+
+	if o.cfg.FlamegraphFile != "" {
+		if err := o.reporter.SaveFlamegraph(o.cfg.FlamegraphFile); err != nil {
+			return fmt.Errorf("failed to save flamegraph: %w", err)
+		}
+		log.Printf("Flamegraph data saved to: %s", o.cfg.FlamegraphFile)
+	}
+
+	if o.cfg.BadgeFile != "" {
+		if err := o.reporter.SaveBadge(o.cfg.BadgeFile, o.cfg.BadgeMetric); err != nil {
+			return fmt.Errorf("failed to save badge: %w", err)
+		}
+		log.Printf("Badge saved to: %s", o.cfg.BadgeFile)
+	}
+
+	if idle != nil && idle.aborted.Load() {
+		return fmt.Errorf("aborted after no successful requests for %v", o.cfg.IdleTimeout)
+	}
+	if interrupt.interrupted.Load() {
+		return fmt.Errorf("interrupted before the test duration elapsed")
+	}
+
 	return nil
 }
+
+// logDerivedWeights prints the weights derived from a traffic log so they
+// can be sanity-checked
+func logDerivedWeights(filename string, report *script.WeightReport) {
+	log.Printf("Derived action weights from %s (%d of %d requests matched):",
+		filename, report.Total-report.Unmatched, report.Total)
+	for _, w := range report.Weights {
+		log.Printf("  %-24s %8d requests  %5.1f%%", w.Action, w.Requests, w.Weight)
+	}
+	if report.Unmatched > 0 {
+		log.Printf("Warning: %d requests (%.1f%%) in %s matched no action",
+			report.Unmatched, 100*float64(report.Unmatched)/float64(report.Total), filename)
+	}
+}
+
+// validBadgeMetric reports whether metric is one of reporter.BadgeMetrics
+func validBadgeMetric(metric string) bool {
+	for _, m := range reporter.BadgeMetrics {
+		if m == metric {
+			return true
+		}
+	}
+	return false
+}
+
+// parseSourceIPs parses a comma-separated list of local IP addresses
+func parseSourceIPs(list string) ([]net.IP, error) {
+	var ips []net.IP
+	for _, field := range strings.Split(list, ",") {
+		field = strings.TrimSpace(field)
+		if field == "" {
+			continue
+		}
+
+		ip := net.ParseIP(field)
+		if ip == nil {
+			return nil, fmt.Errorf("invalid source IP %q", field)
+		}
+		ips = append(ips, ip)
+	}
+	return ips, nil
+}
@@ -0,0 +1,113 @@
+package orchestrator
+
+import (
+	"context"
+	"errors"
+	"log"
+	"log/slog"
+	"sync"
+	"time"
+
+	"stampede-shooter/internal/script"
+	"stampede-shooter/internal/worker"
+)
+
+// workerPool starts and stops workers on demand and waits for them to
+// finish. Users are numbered by their slot in the pool from
+// --user-id-offset+1; a stopped slot is reused by the next worker started,
+// with the same user ID and scenario, so IDs stay within the peak user count.
+type workerPool struct {
+	o       *Orchestrator
+	ctx     context.Context
+	wg      sync.WaitGroup
+	slots   []poolSlot // Every slot started so far, by user index
+	running int        // slots[:running] have a running worker
+}
+
+// poolSlot is what the worker of one user slot runs with
+type poolSlot struct {
+	script *script.Script
+	shared *worker.Shared
+	cancel context.CancelCauseFunc // Stops the slot's current worker
+}
+
+// newWorkerPool creates a pool whose workers run until ctx is done
+func (o *Orchestrator) newWorkerPool(ctx context.Context) *workerPool {
+	return &workerPool{o: o, ctx: ctx}
+}
+
+// size returns the number of running workers
+func (p *workerPool) size() int {
+	return p.running
+}
+
+// scale starts or stops workers until n are running. Stopping takes the
+// most recently started workers first.
+func (p *workerPool) scale(n int) {
+	for p.running < n {
+		p.spawn()
+	}
+	for p.running > n {
+		p.running--
+		p.slots[p.running].cancel(worker.ErrStopped)
+	}
+}
+
+// spawn starts a worker in the next free slot
+func (p *workerPool) spawn() {
+	if p.running == len(p.slots) {
+		// Assign scenarios in start order so the split is deterministic
+		s, shared := p.o.nextWorker()
+		p.slots = append(p.slots, poolSlot{script: s, shared: shared})
+	}
+	slot := &p.slots[p.running]
+	p.running++
+	userID := p.o.cfg.UserIDOffset + p.running
+
+	ctx, cancel := context.WithCancelCause(p.ctx)
+	slot.cancel = cancel
+	s, shared := slot.script, slot.shared
+
+	p.wg.Add(1)
+	go func() {
+		defer p.wg.Done()
+		defer cancel(nil)
+
+		// Create worker with credentials
+		w := worker.New(userID, p.o.cfg, s, p.o.collector, p.o.credentials, shared)
+
+		// Run worker
+		if err := w.Run(ctx, p.o.cfg.LoginURL); err != nil && !errors.Is(context.Cause(ctx), worker.ErrStopped) {
+			slog.Error("worker failed", "worker_id", userID, "error", err)
+		}
+
+		// Export a representative worker's session cookies
+		if userID == p.o.cfg.UserIDOffset+1 && p.o.cfg.ExportCookies != "" {
+			if err := w.ExportCookies(p.o.cfg.ExportCookies); err != nil {
+				log.Printf("Failed to export cookies: %v", err)
+			} else {
+				log.Printf("Cookies of worker %d exported to: %s", userID, p.o.cfg.ExportCookies)
+			}
+		}
+	}()
+}
+
+// rampUp starts --users workers, staggered by spawnInterval
+func (o *Orchestrator) rampUp(ctx context.Context, pool *workerPool, spawnInterval time.Duration) {
+	for i := 0; i < o.cfg.Users; i++ {
+		// Stagger worker starts across the ramp-up; stop launching once the test ends
+		if i > 0 && spawnInterval > 0 {
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(spawnInterval):
+			}
+		}
+		pool.spawn()
+	}
+}
+
+// wait blocks until every started worker has finished
+func (p *workerPool) wait() {
+	p.wg.Wait()
+}
@@ -0,0 +1,53 @@
+package orchestrator
+
+import (
+	"log"
+
+	"stampede-shooter/internal/script"
+	"stampede-shooter/internal/worker"
+)
+
+// scenarioRun is what the workers of one scenario run with
+type scenarioRun struct {
+	script *script.Script
+	shared *worker.Shared
+}
+
+// newScenarioRuns prepares each scenario of s to run on its own, with its
+// own action picker in weighted mode
+func newScenarioRuns(s *script.Script, shared *worker.Shared) ([]scenarioRun, error) {
+	var runs []scenarioRun
+	for i := range s.Scenarios {
+		run := scenarioRun{script: s.ForScenario(i), shared: shared}
+		if shared.Picker != nil {
+			picker, err := script.NewActionPicker(run.script.Actions)
+			if err != nil {
+				return nil, err
+			}
+			scenarioShared := *shared
+			scenarioShared.Picker = picker
+			run.shared = &scenarioShared
+		}
+		runs = append(runs, run)
+	}
+	return runs, nil
+}
+
+// nextWorker assigns the next worker to a scenario and returns the script
+// and shared state it runs with. Without scenarios every worker runs the
+// whole script.
+func (o *Orchestrator) nextWorker() (*script.Script, *worker.Shared) {
+	if o.scenarios == nil {
+		return o.script, o.shared
+	}
+	run := o.scenarioRuns[o.scenarios.Next()]
+	return run.script, run.shared
+}
+
+// logScenarios lists the scenarios and the share of users each gets
+func (o *Orchestrator) logScenarios() {
+	for i, scenario := range o.scenarios.Scenarios() {
+		log.Printf("  scenario %s: %d actions, %.0f%% of users",
+			scenario.Name, len(scenario.Actions), o.scenarios.Share(i)*100)
+	}
+}
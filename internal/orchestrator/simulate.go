@@ -0,0 +1,219 @@
+package orchestrator
+
+import (
+	"fmt"
+	"math"
+	"time"
+
+	"stampede-shooter/internal/script"
+)
+
+// simulateRows is the number of rows in the simulated load table
+const simulateRows = 10
+
+// simulateStep is the resolution used to integrate the load profile
+const simulateStep = 100 * time.Millisecond
+
+// unboundedLoad describes the offered load when nothing paces the users
+const unboundedLoad = "unbounded (no pacing configured)"
+
+// Simulate prints the theoretical offered load over time for the current
+// configuration without sending any requests. Responses are assumed to be
+// instant, so the result is an upper bound on what the run will offer.
+func (o *Orchestrator) Simulate() {
+	fmt.Println("Simulated load profile (assumes instant responses):")
+	perUser, perIteration := o.userProfile()
+	// Shared pacing or the arrival rate caps the total offered load
+	limit := 0.0
+	if o.cfg.ActionsPerSec > 0 {
+		limit = float64(o.cfg.ActionsPerSec)
+		fmt.Printf("  Total capped at %d actions/s by --actions-per-second\n", o.cfg.ActionsPerSec)
+	}
+	if o.cfg.ArrivalRate > 0 {
+		limit = float64(o.cfg.ArrivalRate) * perIteration
+		fmt.Printf("  Open arrival: %d iterations/s offer %.0f req/s; anything above the workers' capacity queues\n",
+			o.cfg.ArrivalRate, limit)
+	}
+
+	if o.cfg.GlobalRPS > 0 && (limit == 0 || float64(o.cfg.GlobalRPS) < limit) {
+		limit = float64(o.cfg.GlobalRPS)
+		fmt.Printf("  Total capped at %d req/s by --global-rps\n", o.cfg.GlobalRPS)
+	}
+
+	fmt.Printf("\n%10s %8s %10s\n", "Time", "Users", "Req/s")
+	rowEvery := o.cfg.Duration / simulateRows
+	for i := 0; i < simulateRows; i++ {
+		t := rowEvery * time.Duration(i)
+		users := o.activeUsers(t)
+		fmt.Printf("%10s %8d %10s\n", t.Round(time.Second), users, formatLoad(offeredLoad(users, perUser, limit)))
+	}
+
+	// Integrate the offered load to find the peak and total
+	var total, peak float64
+	var peakAt, cappedAt time.Duration
+	for t := time.Duration(0); t < o.cfg.Duration; t += simulateStep {
+		load := offeredLoad(o.activeUsers(t), perUser, limit)
+		if math.IsInf(load, 1) {
+			fmt.Printf("\nPeak %s from t=%s: add delays, --rps, --actions-per-second or --global-rps to bound it\n",
+				unboundedLoad, t.Round(time.Second))
+			return
+		}
+		total += load * simulateStep.Seconds()
+		if load > peak {
+			peak, peakAt = load, t
+		}
+		if o.cfg.MaxRequests > 0 && cappedAt == 0 && total >= float64(o.cfg.MaxRequests) {
+			cappedAt = t + simulateStep
+		}
+	}
+
+	fmt.Printf("\nPeak %.1f req/s at t=%s, total ~%.0f requests over %s\n",
+		peak, peakAt.Round(time.Second), math.Round(total), o.cfg.Duration)
+	if cappedAt > 0 {
+		fmt.Printf("--max-requests %d ends the test at ~t=%s\n", o.cfg.MaxRequests, cappedAt.Round(time.Second))
+	}
+}
+
+// userProfile prints and returns the requests per second one user offers
+// and the requests of one of its iterations. With scenarios both are the
+// means over the scenarios, weighted by their share of users.
+func (o *Orchestrator) userProfile() (float64, float64) {
+	if o.scenarios == nil {
+		requests, iteration := o.iterationProfile(o.script, o.shared.Picker)
+		perUser := iterationRate(requests, iteration)
+		fmt.Printf("  Per user: %d requests per iteration, iteration %s, %.2f req/s\n",
+			requests, iteration.Round(time.Millisecond), perUser)
+		return perUser, float64(requests)
+	}
+
+	var perUser, perIteration float64
+	unbounded := false
+	for i, run := range o.scenarioRuns {
+		requests, iteration := o.iterationProfile(run.script, run.shared.Picker)
+		rate := iterationRate(requests, iteration)
+		share := o.scenarios.Share(i)
+		fmt.Printf("  Scenario %s (%.0f%% of users): %d requests per iteration, iteration %s, %.2f req/s\n",
+			o.scenarios.Scenarios()[i].Name, share*100, requests, iteration.Round(time.Millisecond), rate)
+
+		unbounded = unbounded || (rate == 0 && share > 0)
+		perUser += share * rate
+		perIteration += share * float64(requests)
+	}
+	if unbounded {
+		// A scenario without delays or rate limit makes the load unbounded
+		perUser = 0
+	}
+	fmt.Printf("  Per user on average: %.1f requests per iteration, %.2f req/s\n", perIteration, perUser)
+	return perUser, perIteration
+}
+
+// iterationRate returns the requests per second of a user repeating an
+// iteration, 0 if nothing bounds it
+func iterationRate(requests int, iteration time.Duration) float64 {
+	if iteration <= 0 {
+		return 0
+	}
+	return float64(requests) / iteration.Seconds()
+}
+
+// iterationProfile returns the requests per script iteration and the time
+// one iteration of s takes for a single user, combining per-user rate limiting
+// and mean action delays. Parallel groups count all their requests but take
+// only their longest delay. In weighted mode an iteration is one request
+// taking the weighted mean delay.
+func (o *Orchestrator) iterationProfile(s *script.Script, picker *script.ActionPicker) (int, time.Duration) {
+	perRequest := time.Duration(0)
+	if o.cfg.ActionsPerSec == 0 && o.cfg.ArrivalRate == 0 && o.cfg.RPS > 0 {
+		perRequest = time.Second / time.Duration(o.cfg.RPS)
+	}
+
+	if picker != nil {
+		var delay time.Duration
+		for i := range s.Actions {
+			delay += time.Duration(picker.Share(i) * float64(s.Actions[i].MeanDelay(o.cfg.TimeScale)))
+		}
+		if perRequest > delay {
+			delay = perRequest
+		}
+		return 1, delay
+	}
+
+	requests := 0
+	var iteration time.Duration
+	for _, step := range s.Steps() {
+		var delay time.Duration
+		for i := range step {
+			if d := step[i].MeanDelay(o.cfg.TimeScale); d > delay {
+				delay = d
+			}
+		}
+
+		limited := perRequest * time.Duration(len(step))
+		if limited > delay {
+			delay = limited
+		}
+
+		requests += len(step)
+		iteration += delay
+	}
+
+	return requests, iteration
+}
+
+// activeUsers returns how many workers have started by time t, accounting
+// for the ramp-up, login stagger and stages
+func (o *Orchestrator) activeUsers(t time.Duration) int {
+	if o.stages != nil {
+		return o.stageAt(t).Users
+	}
+
+	step := o.rampInterval()
+	if o.cfg.LoginURL != "" && o.cfg.LoginStagger > 0 {
+		step += o.cfg.LoginStagger
+	}
+	if step <= 0 {
+		return o.cfg.Users
+	}
+
+	started := int(t/step) + 1
+	if started > o.cfg.Users {
+		return o.cfg.Users
+	}
+	return started
+}
+
+// rampInterval returns the delay between worker starts during the ramp-up,
+// or 0 to start all workers at once
+func (o *Orchestrator) rampInterval() time.Duration {
+	if o.cfg.RampUp <= 0 || o.cfg.Users <= 0 {
+		return 0
+	}
+	return o.cfg.RampUp / time.Duration(o.cfg.Users)
+}
+
+// offeredLoad returns the requests per second users workers can send, capped
+// at limit requests per second if limit is positive. It is +Inf when neither
+// bounds the load.
+func offeredLoad(users int, perUser, limit float64) float64 {
+	if users == 0 {
+		return 0
+	}
+	if perUser == 0 {
+		// No delays or rate limit: only the limit bounds the load
+		perUser = math.Inf(1)
+	}
+
+	load := float64(users) * perUser
+	if limit > 0 && load > limit {
+		load = limit
+	}
+	return load
+}
+
+// formatLoad formats an offered load for the simulated load table
+func formatLoad(load float64) string {
+	if math.IsInf(load, 1) {
+		return unboundedLoad
+	}
+	return fmt.Sprintf("%.1f", load)
+}
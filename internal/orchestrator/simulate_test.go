@@ -0,0 +1,99 @@
+package orchestrator
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"stampede-shooter/internal/config"
+	"stampede-shooter/internal/script"
+	"stampede-shooter/internal/worker"
+)
+
+// simulate runs Simulate for a script and configuration and returns its output
+func simulate(t *testing.T, cfg config.Config, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "script.yml")
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	s, err := script.LoadScript(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	o := &Orchestrator{cfg: cfg, script: s, shared: &worker.Shared{}}
+
+	reader, writer, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	stdout := os.Stdout
+	os.Stdout = writer
+	defer func() { os.Stdout = stdout }()
+
+	output := make(chan string)
+	go func() {
+		data, _ := io.ReadAll(reader)
+		output <- string(data)
+	}()
+	o.Simulate()
+	writer.Close()
+	return <-output
+}
+
+func TestSimulateUnboundedLoad(t *testing.T) {
+	const noDelays = `
+- name: Home
+  method: GET
+  url: http://localhost/
+`
+	tests := []struct {
+		name      string
+		cfg       config.Config
+		unbounded bool
+		want      string
+	}{
+		{"no pacing", config.Config{}, true, "Peak unbounded (no pacing configured) from t=0s"},
+		{"per-user rate", config.Config{RPS: 2}, false, "Peak 8.0 req/s at t=0s, total ~80 requests over 10s"},
+		{"global rate", config.Config{GlobalRPS: 5}, false, "Peak 5.0 req/s at t=0s, total ~50 requests over 10s"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.cfg.Users = 4
+			tt.cfg.Duration = 10 * time.Second
+			output := simulate(t, tt.cfg, noDelays)
+
+			if !strings.Contains(output, tt.want) {
+				t.Errorf("output lacks %q:\n%s", tt.want, output)
+			}
+			if strings.Contains(output, "Inf") || strings.Contains(output, "NaN") {
+				t.Errorf("output shows a non-finite load:\n%s", output)
+			}
+			if got := strings.Contains(output, "4 "+unboundedLoad); got != tt.unbounded {
+				t.Errorf("table shows an unbounded load: %v, want %v:\n%s", got, tt.unbounded, output)
+			}
+		})
+	}
+}
+
+func TestOfferedLoad(t *testing.T) {
+	tests := []struct {
+		users          int
+		perUser, limit float64
+		want           string
+	}{
+		{4, 2, 0, "8.0"},
+		{4, 2, 5, "5.0"},
+		{4, 0, 5, "5.0"},
+		{4, 0, 0, unboundedLoad},
+		{0, 0, 0, "0.0"},
+	}
+	for _, tt := range tests {
+		if got := formatLoad(offeredLoad(tt.users, tt.perUser, tt.limit)); got != tt.want {
+			t.Errorf("offeredLoad(%d, %v, %v) = %s, want %s", tt.users, tt.perUser, tt.limit, got, tt.want)
+		}
+	}
+}
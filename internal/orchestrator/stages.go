@@ -0,0 +1,94 @@
+package orchestrator
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"stampede-shooter/internal/reporter"
+)
+
+// parseStages parses a --stages load profile of comma-separated
+// users:duration steps, e.g. "10:30s,20:30s", timing each stage's start
+// from the test start
+func parseStages(spec string) ([]reporter.Stage, error) {
+	var stages []reporter.Stage
+	var start time.Duration
+	for _, field := range strings.Split(spec, ",") {
+		field = strings.TrimSpace(field)
+		if field == "" {
+			continue
+		}
+
+		usersText, durationText, ok := strings.Cut(field, ":")
+		if !ok {
+			return nil, fmt.Errorf("invalid stage %q: expected users:duration", field)
+		}
+		users, err := strconv.Atoi(strings.TrimSpace(usersText))
+		if err != nil || users < 1 {
+			return nil, fmt.Errorf("invalid stage %q: users must be a positive number", field)
+		}
+		duration, err := time.ParseDuration(strings.TrimSpace(durationText))
+		if err != nil || duration <= 0 {
+			return nil, fmt.Errorf("invalid stage %q: duration must be positive, e.g. 30s", field)
+		}
+
+		stages = append(stages, reporter.Stage{Users: users, Start: start, Duration: duration})
+		start += duration
+	}
+	if len(stages) == 0 {
+		return nil, fmt.Errorf("no stages given")
+	}
+	return stages, nil
+}
+
+// stagesTotal returns the peak user count and total duration of stages
+func stagesTotal(stages []reporter.Stage) (int, time.Duration) {
+	users := 0
+	var duration time.Duration
+	for _, stage := range stages {
+		if stage.Users > users {
+			users = stage.Users
+		}
+		duration += stage.Duration
+	}
+	return users, duration
+}
+
+// runStages steps the pool through the stages, starting the workers a stage
+// adds and stopping those it drops. Stages are timed from the test start, so
+// slow worker starts don't push later stages back.
+func (o *Orchestrator) runStages(ctx context.Context, pool *workerPool) {
+	start := time.Now()
+	for i, stage := range o.stages {
+		if i > 0 {
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(time.Until(start.Add(stage.Start))):
+			}
+		}
+
+		change := ""
+		if running := pool.size(); i > 0 && stage.Users > running {
+			change = fmt.Sprintf(" (starting %d)", stage.Users-running)
+		} else if stage.Users < running {
+			change = fmt.Sprintf(" (stopping %d)", running-stage.Users)
+		}
+		log.Printf("Stage %d/%d: %d users for %v%s", i+1, len(o.stages), stage.Users, stage.Duration, change)
+		pool.scale(stage.Users)
+	}
+}
+
+// stageAt returns the stage active t into the test
+func (o *Orchestrator) stageAt(t time.Duration) reporter.Stage {
+	for i := len(o.stages) - 1; i > 0; i-- {
+		if t >= o.stages[i].Start {
+			return o.stages[i]
+		}
+	}
+	return o.stages[0]
+}
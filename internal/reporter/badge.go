@@ -0,0 +1,101 @@
+package reporter
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+)
+
+// BadgeMetrics are the metrics a badge can display
+var BadgeMetrics = []string{"p95", "error-rate", "rps"}
+
+// badge is a shields.io endpoint badge
+type badge struct {
+	SchemaVersion int    `json:"schemaVersion"`
+	Label         string `json:"label"`
+	Message       string `json:"message"`
+	Color         string `json:"color"`
+}
+
+// SetSLA sets the p95 latency and error rate (percent) targets used to judge
+// the run; zero disables a target
+func (r *Reporter) SetSLA(p95 time.Duration, errorRate float64) {
+	r.slaP95 = p95
+	r.slaErrorRate = errorRate
+}
+
+// SaveBadge writes a shields.io endpoint JSON for the given metric. The color
+// is green within the SLA (or throughput target for rps), yellow when close
+// to it, red beyond it and lightgrey when no target is set.
+func (r *Reporter) SaveBadge(filename, metric string) error {
+	var b badge
+	switch metric {
+	case "p95":
+		p95 := r.collector.GetOverallPercentile(95.0)
+		b = badge{Label: "p95", Message: formatDuration(p95), Color: "lightgrey"}
+		if r.slaP95 > 0 {
+			b.Color = thresholdColor(float64(p95), float64(r.slaP95), 1.5)
+		}
+	case "error-rate":
+		errorRate := r.errorRate()
+		b = badge{Label: "errors", Message: fmt.Sprintf("%.1f%%", errorRate), Color: "lightgrey"}
+		if r.slaErrorRate > 0 {
+			b.Color = thresholdColor(errorRate, r.slaErrorRate, 2)
+		}
+	case "rps":
+		rps := r.achievedRPS()
+		b = badge{Label: "rps", Message: fmt.Sprintf("%.1f", rps), Color: "lightgrey"}
+		if r.targetActionsPerSec > 0 && rps > 0 {
+			// Invert so that falling short of the target is "over" the threshold
+			b.Color = thresholdColor(float64(r.targetActionsPerSec)/rps, 1/0.95, 1.2)
+		}
+	default:
+		return fmt.Errorf("unknown badge metric %q", metric)
+	}
+	b.SchemaVersion = 1
+
+	data, err := json.Marshal(b)
+	if err != nil {
+		return fmt.Errorf("failed to encode badge: %w", err)
+	}
+	if err := os.WriteFile(filename, append(data, '\n'), 0644); err != nil {
+		return fmt.Errorf("failed to write badge file: %w", err)
+	}
+
+	return nil
+}
+
+// thresholdColor grades value against limit: green up to the limit, yellow
+// up to limit*tolerance and red beyond
+func thresholdColor(value, limit, tolerance float64) string {
+	switch {
+	case value <= limit:
+		return "green"
+	case value <= limit*tolerance:
+		return "yellow"
+	default:
+		return "red"
+	}
+}
+
+// errorRate returns the percentage of failed requests across all actions
+func (r *Reporter) errorRate() float64 {
+	total, errors := int64(0), int64(0)
+	for _, stat := range r.collector.GetStats() {
+		total += stat.TotalOK + stat.TotalErrors
+		errors += stat.TotalErrors
+	}
+	return sharePercent(errors, total)
+}
+
+// achievedRPS returns the requests per second across all actions, failed
+// ones included, since the end of the warm-up. It is the achieved rate the
+// final report compares with --actions-per-second.
+func (r *Reporter) achievedRPS() float64 {
+	total := int64(0)
+	for _, stat := range r.collector.GetStats() {
+		total += stat.TotalOK + stat.TotalErrors
+	}
+	return float64(total) / r.measuredElapsed().Seconds()
+}
@@ -0,0 +1,42 @@
+package reporter
+
+import (
+	"fmt"
+
+	"stampede-shooter/internal/metrics"
+)
+
+// printConnReuse compares the latency of requests sent on newly established
+// connections with those on reused ones. The gap between the medians is the
+// cost of a new connection, including any cold start of a freshly scaled
+// backend instance.
+func (r *Reporter) printConnReuse(stats map[string]*metrics.ActionStats, actionNames []string) {
+	fmt.Println("\nNew vs reused connections:")
+	fmt.Printf("%-15s %8s %8s %8s %8s %8s %8s %10s\n",
+		"Action", "New", "new p50", "new p95", "Reused", "re p50", "re p95", "new cost")
+
+	for _, name := range actionNames {
+		stat := stats[name]
+		newConns, reused := stat.ConnSamples()
+
+		newP50, newP95, reP50, reP95, cost := "-", "-", "-", "-", "-"
+		if newConns > 0 {
+			newP50 = formatDuration(stat.GetNewConnPercentile(50.0))
+			newP95 = formatDuration(stat.GetNewConnPercentile(95.0))
+		}
+		if reused > 0 {
+			reP50 = formatDuration(stat.GetReusedConnPercentile(50.0))
+			reP95 = formatDuration(stat.GetReusedConnPercentile(95.0))
+		}
+		if newConns > 0 && reused > 0 {
+			extra := stat.GetNewConnPercentile(50.0) - stat.GetReusedConnPercentile(50.0)
+			if extra < 0 {
+				extra = 0
+			}
+			cost = formatDuration(extra)
+		}
+
+		fmt.Printf("%-15s %8d %8s %8s %8d %8s %8s %10s\n",
+			truncateString(name, 15), newConns, newP50, newP95, reused, reP50, reP95, cost)
+	}
+}
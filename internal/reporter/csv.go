@@ -0,0 +1,82 @@
+package reporter
+
+import (
+	"encoding/csv"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// csvHeader lists the columns written by SaveCSV
+var csvHeader = []string{"action", "ok", "err", "p50_ms", "p90_ms", "p95_ms", "p99_ms", "rps", "bytes"}
+
+// SaveCSV writes one row per action and a final TOTAL row matching the
+// console summary, for spreadsheet analysis. Weighted runs add a
+// weight_share column with each action's configured share of the mix
+func (r *Reporter) SaveCSV(filename string) error {
+	stats := r.collector.GetStats()
+	elapsed := r.measuredElapsed().Seconds()
+
+	var actionNames []string
+	for name := range stats {
+		actionNames = append(actionNames, name)
+	}
+	sort.Strings(actionNames)
+
+	file, err := os.Create(filename)
+	if err != nil {
+		return fmt.Errorf("failed to create CSV file: %w", err)
+	}
+	defer file.Close()
+
+	w := csv.NewWriter(file)
+	write := func(row []string, weightShare float64) {
+		if r.weightShares != nil {
+			row = append(row, strconv.FormatFloat(weightShare, 'f', 4, 64))
+		}
+		w.Write(row)
+	}
+	header := csvHeader
+	if r.weightShares != nil {
+		header = append(header[:len(header):len(header)], "weight_share")
+	}
+	w.Write(header)
+
+	var totalOK, totalErr, totalBytes int64
+	var totalWeight float64
+	for _, name := range actionNames {
+		stat := stats[name]
+		write(csvRow(name, stat.TotalOK, stat.TotalErrors, stat.GetLatencyPercentile,
+			float64(stat.TotalOK)/elapsed, stat.BytesTotal), r.weightShares[name])
+
+		totalOK += stat.TotalOK
+		totalErr += stat.TotalErrors
+		totalBytes += stat.BytesTotal
+		totalWeight += r.weightShares[name]
+	}
+	write(csvRow("TOTAL", totalOK, totalErr, r.collector.GetOverallPercentile,
+		float64(totalOK)/elapsed, totalBytes), totalWeight)
+
+	w.Flush()
+	if err := w.Error(); err != nil {
+		return fmt.Errorf("failed to write CSV: %w", err)
+	}
+	return nil
+}
+
+// csvRow formats one CSV row; percentile returns a latency percentile
+func csvRow(name string, ok, errs int64, percentile func(float64) time.Duration, rps float64, bytes int64) []string {
+	ms := func(p float64) string {
+		return strconv.FormatFloat(float64(percentile(p).Microseconds())/1000, 'f', 3, 64)
+	}
+	return []string{
+		name,
+		strconv.FormatInt(ok, 10),
+		strconv.FormatInt(errs, 10),
+		ms(50.0), ms(90.0), ms(95.0), ms(99.0),
+		strconv.FormatFloat(rps, 'f', 2, 64),
+		strconv.FormatInt(bytes, 10),
+	}
+}
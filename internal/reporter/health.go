@@ -0,0 +1,127 @@
+package reporter
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// healthComponents are the health score components in display order
+var healthComponents = []string{"errors", "latency", "throughput"}
+
+// HealthComponent is one scored dimension of the health score
+type HealthComponent struct {
+	Name   string  `json:"name"`
+	Score  float64 `json:"score"`  // 0-100
+	Weight float64 `json:"weight"` // Normalized weight among scored components
+}
+
+// HealthScore combines error rate, latency and throughput into one 0-100 number
+type HealthScore struct {
+	Score      float64           `json:"score"`
+	Components []HealthComponent `json:"components"`
+}
+
+// ParseHealthWeights parses "errors=0.5,latency=0.3,throughput=0.2". Omitted
+// components get weight 0.
+func ParseHealthWeights(spec string) (map[string]float64, error) {
+	weights := make(map[string]float64)
+	for _, part := range strings.Split(spec, ",") {
+		name, value, ok := strings.Cut(strings.TrimSpace(part), "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid health weight %q: expected name=weight", part)
+		}
+
+		known := false
+		for _, c := range healthComponents {
+			known = known || c == name
+		}
+		if !known {
+			return nil, fmt.Errorf("unknown health component %q: expected %s", name, strings.Join(healthComponents, ", "))
+		}
+
+		weight, err := strconv.ParseFloat(value, 64)
+		if err != nil || weight < 0 {
+			return nil, fmt.Errorf("invalid weight for %s: %q", name, value)
+		}
+		weights[name] = weight
+	}
+	return weights, nil
+}
+
+// SetHealthWeights sets the weight of each health score component
+func (r *Reporter) SetHealthWeights(weights map[string]float64) {
+	r.healthWeights = weights
+}
+
+// HealthScore computes the run's health score. Each component scores 0-100:
+//
+//   - errors: 100 at or below --sla-error-rate, falling linearly to 0 at
+//     twice the SLA; without an SLA it is the success rate.
+//   - latency: 100 at or below --sla-p95, otherwise sla/p95 x 100.
+//   - throughput: achieved/target x 100 (capped at 100) against
+//     --actions-per-second.
+//
+// Latency and throughput are only scored when their target is set. The score
+// is the weighted mean of the scored components.
+func (r *Reporter) HealthScore() HealthScore {
+	var components []HealthComponent
+
+	errorRate := r.errorRate()
+	errorScore := 100 - errorRate
+	if r.slaErrorRate > 0 {
+		errorScore = 100 * clamp01(1-(errorRate-r.slaErrorRate)/r.slaErrorRate)
+	}
+	components = append(components, HealthComponent{Name: "errors", Score: errorScore})
+
+	if r.slaP95 > 0 {
+		latencyScore := 100.0
+		if p95 := r.collector.GetOverallPercentile(95.0); p95 > r.slaP95 {
+			latencyScore = 100 * float64(r.slaP95) / float64(p95)
+		}
+		components = append(components, HealthComponent{Name: "latency", Score: latencyScore})
+	}
+
+	if r.targetActionsPerSec > 0 {
+		throughputScore := 100 * clamp01(r.achievedRPS()/float64(r.targetActionsPerSec))
+		components = append(components, HealthComponent{Name: "throughput", Score: throughputScore})
+	}
+
+	// Normalize weights over the components that could be scored
+	totalWeight := 0.0
+	for _, c := range components {
+		totalWeight += r.healthWeights[c.Name]
+	}
+
+	var health HealthScore
+	for _, c := range components {
+		if totalWeight > 0 {
+			c.Weight = r.healthWeights[c.Name] / totalWeight
+		}
+		health.Score += c.Score * c.Weight
+		health.Components = append(health.Components, c)
+	}
+	return health
+}
+
+// printHealth prints the health score with its component breakdown
+func (r *Reporter) printHealth() {
+	health := r.HealthScore()
+
+	parts := make([]string, len(health.Components))
+	for i, c := range health.Components {
+		parts[i] = fmt.Sprintf("%s %.0f x%.2f", c.Name, c.Score, c.Weight)
+	}
+	fmt.Printf("Health score: %.0f/100 (%s)\n", health.Score, strings.Join(parts, ", "))
+}
+
+// clamp01 limits v to the range [0, 1]
+func clamp01(v float64) float64 {
+	if v < 0 {
+		return 0
+	}
+	if v > 1 {
+		return 1
+	}
+	return v
+}
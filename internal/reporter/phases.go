@@ -0,0 +1,157 @@
+package reporter
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"stampede-shooter/internal/metrics"
+)
+
+// phaseBarWidth is the width of a 100% bar in the phase breakdown
+const phaseBarWidth = 40
+
+// printPhases shows what fraction of total request time is spent in each
+// traced phase, averaged over all successful requests, followed by each
+// phase's percentiles over the requests it occurred in
+func (r *Reporter) printPhases() {
+	totals := r.collector.GetPhaseTotals()
+	if totals.Requests == 0 || totals.Total <= 0 {
+		return
+	}
+
+	fmt.Println("\nTime per phase (share of total request time):")
+	for i, d := range totals.Phases {
+		printPhase(metrics.PhaseNames[i], d, totals)
+	}
+	printPhase("other", totals.Other(), totals)
+
+	fmt.Printf("\n%-8s %10s %8s %8s %8s\n", "Phase", "Requests", "p50", "p95", "p99")
+	for i := range totals.Phases {
+		phase := metrics.Phase(i)
+		count := r.collector.GetPhaseCount(phase)
+		if count == 0 {
+			fmt.Printf("%-8s %10d %8s %8s %8s\n", metrics.PhaseNames[i], 0, "-", "-", "-")
+			continue
+		}
+		fmt.Printf("%-8s %10d %8s %8s %8s\n", metrics.PhaseNames[i], count,
+			formatDuration(r.collector.GetPhasePercentile(phase, 50.0)),
+			formatDuration(r.collector.GetPhasePercentile(phase, 95.0)),
+			formatDuration(r.collector.GetPhasePercentile(phase, 99.0)))
+	}
+	if count := r.collector.GetTTFBCount(); count > 0 {
+		fmt.Printf("%-8s %10d %8s %8s %8s\n", "ttfb", count,
+			formatDuration(r.collector.GetTTFBPercentile(50.0)),
+			formatDuration(r.collector.GetTTFBPercentile(95.0)),
+			formatDuration(r.collector.GetTTFBPercentile(99.0)))
+	}
+}
+
+// printTTFB compares each action's time to first byte with its full
+// duration. The gap is the time spent reading the body, which grows with
+// large or streamed responses while TTFB tracks how fast the server answers.
+func (r *Reporter) printTTFB(stats map[string]*metrics.ActionStats, actionNames []string) {
+	fmt.Println("\nTime to first byte vs full duration:")
+	fmt.Printf("%-15s %8s %8s %8s %8s %8s %8s\n",
+		"Action", "Samples", "ttfb p50", "ttfb p95", "ttfb p99", "full p50", "full p95")
+
+	for _, name := range actionNames {
+		stat := stats[name]
+		samples := stat.TTFBSamples()
+		if samples == 0 {
+			fmt.Printf("%-15s %8d %8s %8s %8s %8s %8s\n", truncateString(name, 15), 0, "-", "-", "-", "-", "-")
+			continue
+		}
+		fmt.Printf("%-15s %8d %8s %8s %8s %8s %8s\n",
+			truncateString(name, 15),
+			samples,
+			formatDuration(stat.GetTTFBPercentile(50.0)),
+			formatDuration(stat.GetTTFBPercentile(95.0)),
+			formatDuration(stat.GetTTFBPercentile(99.0)),
+			formatDuration(stat.GetLatencyPercentile(50.0)),
+			formatDuration(stat.GetLatencyPercentile(95.0)))
+	}
+}
+
+// phasesReport returns the phases section of the JSON report: each phase's
+// share of request time and its percentiles over the requests it occurred in
+func (r *Reporter) phasesReport() map[string]interface{} {
+	totals := r.collector.GetPhaseTotals()
+	ms := func(d time.Duration) float64 { return float64(d.Microseconds()) / 1000 }
+
+	report := make(map[string]interface{})
+	for i, d := range totals.Phases {
+		phase := metrics.Phase(i)
+		report[metrics.PhaseNames[i]] = map[string]interface{}{
+			"share":    float64(d) / float64(totals.Total),
+			"avg_ms":   ms(d / time.Duration(totals.Requests)),
+			"requests": r.collector.GetPhaseCount(phase),
+			"p50_ms":   ms(r.collector.GetPhasePercentile(phase, 50.0)),
+			"p95_ms":   ms(r.collector.GetPhasePercentile(phase, 95.0)),
+			"p99_ms":   ms(r.collector.GetPhasePercentile(phase, 99.0)),
+		}
+	}
+	report["other"] = map[string]interface{}{
+		"share":  float64(totals.Other()) / float64(totals.Total),
+		"avg_ms": ms(totals.Other() / time.Duration(totals.Requests)),
+	}
+	report["ttfb"] = map[string]interface{}{
+		"requests": r.collector.GetTTFBCount(),
+		"p50_ms":   ms(r.collector.GetTTFBPercentile(50.0)),
+		"p95_ms":   ms(r.collector.GetTTFBPercentile(95.0)),
+		"p99_ms":   ms(r.collector.GetTTFBPercentile(99.0)),
+	}
+	return report
+}
+
+// printPhase prints one proportional bar of the phase breakdown
+func printPhase(name string, d time.Duration, totals metrics.PhaseTotals) {
+	share := float64(d) / float64(totals.Total)
+	avg := d / time.Duration(totals.Requests)
+
+	line := fmt.Sprintf("  %-8s %6.1f%%  avg %8s  %s",
+		name, share*100, formatDuration(avg), strings.Repeat("█", int(share*phaseBarWidth+0.5)))
+	fmt.Println(strings.TrimRight(line, " "))
+}
+
+// SaveFlamegraph writes the time spent per action and phase in the folded
+// stack format ("stampede;action;phase microseconds") read by flamegraph
+// tools such as flamegraph.pl and speedscope
+func (r *Reporter) SaveFlamegraph(filename string) error {
+	stats := r.collector.GetStats()
+
+	var actionNames []string
+	for name := range stats {
+		actionNames = append(actionNames, name)
+	}
+	sort.Strings(actionNames)
+
+	file, err := os.Create(filename)
+	if err != nil {
+		return fmt.Errorf("failed to create flamegraph file: %w", err)
+	}
+	defer file.Close()
+
+	w := bufio.NewWriter(file)
+	for _, name := range actionNames {
+		totals := stats[name].GetPhaseTotals()
+		frame := strings.ReplaceAll(name, ";", "_")
+
+		for i, d := range totals.Phases {
+			if d > 0 {
+				fmt.Fprintf(w, "stampede;%s;%s %d\n", frame, metrics.PhaseNames[i], d.Microseconds())
+			}
+		}
+		if other := totals.Other(); other > 0 {
+			fmt.Fprintf(w, "stampede;%s;other %d\n", frame, other.Microseconds())
+		}
+	}
+
+	if err := w.Flush(); err != nil {
+		return fmt.Errorf("failed to write flamegraph: %w", err)
+	}
+	return nil
+}
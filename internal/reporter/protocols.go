@@ -0,0 +1,35 @@
+package reporter
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// printProtocols prints the protocols responses arrived over, most used
+// first, so a run shows whether HTTP/2 was actually negotiated
+func (r *Reporter) printProtocols() {
+	protocols := r.collector.GetProtocols()
+	if len(protocols) == 0 {
+		return
+	}
+
+	var total int64
+	names := make([]string, 0, len(protocols))
+	for proto, count := range protocols {
+		names = append(names, proto)
+		total += count
+	}
+	sort.Slice(names, func(i, j int) bool {
+		if protocols[names[i]] != protocols[names[j]] {
+			return protocols[names[i]] > protocols[names[j]]
+		}
+		return names[i] < names[j]
+	})
+
+	parts := make([]string, len(names))
+	for i, proto := range names {
+		parts[i] = fmt.Sprintf("%s %.1f%%", proto, float64(protocols[proto])/float64(total)*100)
+	}
+	fmt.Printf("Protocols: %s\n", strings.Join(parts, ", "))
+}
@@ -9,24 +9,92 @@ import (
 	"time"
 
 	"stampede-shooter/internal/metrics"
+	"stampede-shooter/internal/script"
+	"stampede-shooter/internal/util"
 )
 
 // Reporter handles progress reporting and final results
 type Reporter struct {
-	collector *metrics.Collector
-	startTime time.Time
-	verbose   bool
+	collector   *metrics.Collector
+	startTime   time.Time
+	verbose     bool
+	retryBudget *util.RetryBudget
+
+	// Total-throughput pacing target, if --actions-per-second is used
+	targetActionsPerSec int
+	users               int
+
+	serverTimeHeader string // Header server processing time is read from, if any
+	sizeStats        bool   // Report response size percentiles
+
+	// SLA targets, zero when not set
+	slaP95       time.Duration
+	slaErrorRate float64
+
+	healthWeights map[string]float64 // Health score component weights
+
+	recentWindow time.Duration // Window of the recent rate in live progress
+
+	proxies *util.ProxyPool // Proxy pool whose usage is reported, if any
+
+	scenarios *script.ScenarioAssigner // Scenarios results are grouped by, if any
+	stages    []Stage                  // Stepped load profile, if any
+
+	weightShares map[string]float64 // Configured share of each action in weighted mode, nil otherwise
 }
 
 // New creates a new reporter
 func New(collector *metrics.Collector, verbose bool) *Reporter {
 	return &Reporter{
-		collector: collector,
-		startTime: time.Now(),
-		verbose:   verbose,
+		collector:    collector,
+		startTime:    time.Now(),
+		verbose:      verbose,
+		recentWindow: 5 * time.Second,
+	}
+}
+
+// SetRetryBudget enables retry reporting against the given budget
+func (r *Reporter) SetRetryBudget(budget *util.RetryBudget) {
+	r.retryBudget = budget
+}
+
+// SetPacing enables reporting of achieved vs target total throughput
+func (r *Reporter) SetPacing(actionsPerSec, users int) {
+	r.targetActionsPerSec = actionsPerSec
+	r.users = users
+}
+
+// SetServerTimeHeader enables reporting of server-reported processing time
+// read from the named response header
+func (r *Reporter) SetServerTimeHeader(name string) {
+	r.serverTimeHeader = name
+}
+
+// SetSizeStats enables reporting of response size percentiles
+func (r *Reporter) SetSizeStats(enabled bool) {
+	r.sizeStats = enabled
+}
+
+// SetActionPicker enables the Weight column: each action's share of the
+// weighted mix as configured, next to the Share actually executed
+func (r *Reporter) SetActionPicker(picker *script.ActionPicker, actions []script.Action) {
+	r.weightShares = make(map[string]float64, len(actions))
+	for i, action := range actions {
+		r.weightShares[action.Name] += picker.Share(i)
 	}
 }
 
+// SetProxyPool enables reporting of per-proxy request counts
+func (r *Reporter) SetProxyPool(pool *util.ProxyPool) {
+	r.proxies = pool
+}
+
+// SetRecentWindow sets the window the live progress averages the current
+// request rate over
+func (r *Reporter) SetRecentWindow(window time.Duration) {
+	r.recentWindow = window
+}
+
 // StartLiveReporting begins showing live progress updates
 func (r *Reporter) StartLiveReporting() {
 	if !r.verbose {
@@ -43,6 +111,16 @@ func (r *Reporter) StartLiveReporting() {
 	}()
 }
 
+// measuredElapsed returns the time the recorded stats cover: since the
+// start, or since the end of the --warmup
+func (r *Reporter) measuredElapsed() time.Duration {
+	start := r.startTime
+	if until, ok := r.collector.Warmup(); ok {
+		start = until
+	}
+	return time.Since(start)
+}
+
 // showProgress displays current test progress
 func (r *Reporter) showProgress() {
 	stats := r.collector.GetStats()
@@ -57,8 +135,8 @@ func (r *Reporter) showProgress() {
 	}
 
 	elapsed := time.Since(r.startTime).Seconds()
-	if elapsed > 0 {
-		currentRPS = float64(totalOK) / elapsed
+	if measured := r.measuredElapsed().Seconds(); measured > 0 {
+		currentRPS = float64(totalOK) / measured
 	}
 
 	successRate := float64(100)
@@ -66,8 +144,20 @@ func (r *Reporter) showProgress() {
 		successRate = float64(totalOK) / float64(totalOK+totalErr) * 100
 	}
 
-	fmt.Printf("\rElapsed: %.0fs | Requests: %d | Errors: %d | Success: %.1f%% | RPS: %.1f",
-		elapsed, totalOK, totalErr, successRate, currentRPS)
+	// Percentiles are cumulative since the start; the histograms cannot be
+	// windowed
+	latency := "-"
+	if totalOK > 0 {
+		p := r.collector.GetOverallPercentiles(50, 95, 99)
+		latency = fmt.Sprintf("%s/%s/%s", formatDuration(p[0]), formatDuration(p[1]), formatDuration(p[2]))
+	}
+
+	// The lifetime average lags during ramp-up and degradation, so lead with
+	// the rate over the recent window
+	recentRPS := r.collector.GetRecentRPS(r.recentWindow)
+
+	fmt.Printf("\rElapsed: %.0fs | Requests: %d | Errors: %d | Success: %.1f%% | RPS: %.1f (last %s), %.1f avg | p50/p95/p99 (all-time): %s   ",
+		elapsed, totalOK, totalErr, successRate, recentRPS, r.recentWindow.Round(time.Second), currentRPS, latency)
 }
 
 // PrintFinalReport displays the final test results
@@ -88,19 +178,39 @@ func (r *Reporter) PrintFinalReport() {
 	}
 	sort.Strings(actionNames)
 
-	// Print header
-	fmt.Printf("%-15s %8s %8s %8s %8s %8s %8s %8s\n",
-		"Action", "OK", "ERR", "p50", "p90", "p95", "p99", "RPS")
-	fmt.Println(strings.Repeat("─", 88))
+	// Count all requests up front to show each action's share of the traffic
+	allRequests := int64(0)
+	for _, stat := range stats {
+		allRequests += stat.TotalOK + stat.TotalErrors
+	}
+
+	// Print header; weighted runs compare the executed share to the weights
+	width := 97
+	fmt.Printf("%-15s %8s %8s %8s %8s %8s %8s %8s %8s",
+		"Action", "OK", "ERR", "p50", "p90", "p95", "p99", "RPS", "Share")
+	if r.weightShares != nil {
+		fmt.Printf(" %8s", "Weight")
+		width += 9
+	}
+	fmt.Println()
+	fmt.Println(strings.Repeat("─", width))
 
 	totalOK := int64(0)
 	totalErr := int64(0)
 	totalBytes := int64(0)
-	elapsed := time.Since(r.startTime).Seconds()
+	totalRetries := int64(0)
+	totalFullTLS := int64(0)
+	totalResumedTLS := int64(0)
+	totalConnClosed := int64(0)
+	elapsed := r.measuredElapsed().Seconds()
+	var neverExecuted []string
 
 	// Print stats for each action
 	for _, name := range actionNames {
 		stat := stats[name]
+		if stat.TotalOK+stat.TotalErrors == 0 {
+			neverExecuted = append(neverExecuted, name)
+		}
 
 		p50 := stat.GetLatencyPercentile(50.0)
 		p90 := stat.GetLatencyPercentile(90.0)
@@ -109,7 +219,9 @@ func (r *Reporter) PrintFinalReport() {
 
 		actionRPS := float64(stat.TotalOK) / elapsed
 
-		fmt.Printf("%-15s %8d %8d %8s %8s %8s %8s %8.1f\n",
+		share := sharePercent(stat.TotalOK+stat.TotalErrors, allRequests)
+
+		fmt.Printf("%-15s %8d %8d %8s %8s %8s %8s %8.1f %7.1f%%",
 			truncateString(name, 15),
 			stat.TotalOK,
 			stat.TotalErrors,
@@ -117,15 +229,33 @@ func (r *Reporter) PrintFinalReport() {
 			formatDuration(p90),
 			formatDuration(p95),
 			formatDuration(p99),
-			actionRPS)
+			actionRPS,
+			share)
+		if r.weightShares != nil {
+			fmt.Printf(" %7.1f%%", r.weightShares[name]*100)
+		}
+		fmt.Println()
 
 		totalOK += stat.TotalOK
 		totalErr += stat.TotalErrors
 		totalBytes += stat.BytesTotal
+		totalRetries += stat.Retries
+		totalFullTLS += stat.TLSFullHandshakes
+		totalResumedTLS += stat.TLSResumed
+		totalConnClosed += stat.ConnClosed
 	}
 
 	// Print totals
-	fmt.Println(strings.Repeat("─", 88))
+	fmt.Println(strings.Repeat("─", width))
+
+	// An absent action must not look like one that passed
+	if len(neverExecuted) > 0 {
+		fmt.Printf("Never executed: %s (no requests recorded)\n", strings.Join(neverExecuted, ", "))
+	}
+
+	if r.scenarios != nil {
+		r.printScenarios(stats, elapsed)
+	}
 
 	totalRequests := totalOK + totalErr
 	successRate := float64(100)
@@ -134,37 +264,312 @@ func (r *Reporter) PrintFinalReport() {
 	}
 
 	avgRPS := float64(totalOK) / elapsed
-	avgLatency := time.Duration(0)
-
-	// Calculate overall average latency
-	if len(stats) > 0 {
-		totalLatency := int64(0)
-		totalCount := int64(0)
-
-		for _, stat := range stats {
-			if stat.TotalOK > 0 {
-				// Use p50 as approximation for average
-				p50Micros := stat.Histogram.ValueAtQuantile(50.0)
-				totalLatency += p50Micros * stat.TotalOK
-				totalCount += stat.TotalOK
-			}
-		}
 
-		if totalCount > 0 {
-			avgLatency = time.Duration(totalLatency/totalCount) * time.Microsecond
-		}
+	// Mean and median latency of successful requests across all actions
+	meanLatency := r.collector.GetOverallMean()
+	medianLatency := r.collector.GetOverallPercentile(50.0)
+
+	fmt.Printf("\nTotals: %d requests, %.1f%% success, %.0fs, %.1f rps, mean %s, median %s\n",
+		totalRequests, successRate, elapsed, avgRPS, formatDuration(meanLatency), formatDuration(medianLatency))
+	if until, ok := r.collector.Warmup(); ok {
+		fmt.Printf("Warm-up: first %s not recorded (%d requests ignored)\n",
+			until.Sub(r.startTime).Round(time.Second), r.collector.WarmupCount())
 	}
+	if totalOK > 0 {
+		fmt.Printf("Latency: min %s, max %s, stddev %s\n",
+			formatDuration(r.collector.GetOverallMin()), formatDuration(r.collector.GetOverallMax()),
+			formatDuration(r.collector.GetOverallStdDev()))
+		r.printSpread(stats, actionNames)
+	}
+	r.printHealth()
+	r.printSLA()
 
-	fmt.Printf("\nTotals: %d requests, %.1f%% success, %.0fs, %.1f rps, avg %s\n",
-		totalRequests, successRate, elapsed, avgRPS, formatDuration(avgLatency))
+	if _, _, ok := r.collector.Window(); ok {
+		r.printWindow(actionNames)
+	}
+
+	if dropped := r.collector.DroppedCount(); dropped > 0 {
+		fmt.Printf("Warning: %d requests were not recorded because the metrics channel was full; totals undercount by %.1f%%\n",
+			dropped, sharePercent(dropped, totalRequests+dropped))
+		fmt.Println("  Raise --metrics-buffer, or use --metrics-blocking to record every request at the cost of slowing workers")
+	}
 
 	if totalBytes > 0 {
 		mbTransferred := float64(totalBytes) / (1024 * 1024)
 		fmt.Printf("Data transferred: %.2f MB (%.2f MB/s)\n",
 			mbTransferred, mbTransferred/elapsed)
 	}
+	r.printProtocols()
+
+	if r.targetActionsPerSec > 0 {
+		achieved := r.achievedRPS()
+		fmt.Printf("Pacing: target %d actions/s, achieved %.1f actions/s (%.2f per user across %d users)\n",
+			r.targetActionsPerSec, achieved, achieved/float64(r.users), r.users)
+	}
+
+	if handshakes := totalFullTLS + totalResumedTLS; handshakes > 0 {
+		fmt.Printf("TLS handshakes: %d full, %d resumed (%.1f%% resumption)\n",
+			totalFullTLS, totalResumedTLS, float64(totalResumedTLS)/float64(handshakes)*100)
+	}
+
+	if totalConnClosed > 0 {
+		r.printConnClosed(stats, actionNames, totalConnClosed, totalRequests)
+	}
+
+	r.printErrorBreakdown(stats, actionNames)
+	r.printCaptureMisses(stats, actionNames)
+	r.printIdempotency(stats, actionNames)
+
+	if logins := r.collector.Logins(); logins > 0 {
+		fmt.Printf("Login requests: %d (%.1f/s)\n", logins, float64(logins)/elapsed)
+	}
+
+	if refreshes := r.collector.TokenRefreshes(); refreshes > 0 {
+		fmt.Printf("Proactive token refreshes: %d\n", refreshes)
+	}
+
+	if retries := r.collector.DialRetries(); retries > 0 {
+		fmt.Printf("Dial retries: %d connection attempts retried\n", retries)
+	}
+
+	if cancelled := r.collector.CancelledRequests(); cancelled > 0 {
+		fmt.Printf("Cancelled: %d in-flight requests of workers stopped by a stage, not counted\n", cancelled)
+	}
+
+	avgConcurrency, maxConcurrency := r.collector.GetConcurrency()
+	fmt.Printf("Concurrency: avg %.1f, max %d in-flight requests\n", avgConcurrency, maxConcurrency)
+
+	if r.retryBudget != nil {
+		r.printRetries(totalRetries)
+	}
+
+	if r.proxies != nil {
+		r.printProxies()
+	}
+
+	if r.collector.QueueTimeSamples() > 0 {
+		r.printQueueTime()
+	}
+
+	if r.collector.ThroughputBucketSize() > 0 {
+		r.printThroughput()
+	}
+
+	if len(r.stages) > 0 {
+		r.printStages()
+	}
+
+	r.printPhases()
+	if r.collector.GetTTFBCount() > 0 {
+		r.printTTFB(stats, actionNames)
+	}
+	r.printConnReuse(stats, actionNames)
+
+	if r.sizeStats {
+		r.printSizes(stats, actionNames)
+	}
+	if r.serverTimeHeader != "" {
+		r.printServerTime(stats, actionNames)
+	}
+}
+
+// printSpread shows the latency range, mean and standard deviation of each
+// action, which percentiles alone don't convey
+func (r *Reporter) printSpread(stats map[string]*metrics.ActionStats, actionNames []string) {
+	fmt.Printf("\n%-15s %8s %8s %8s %8s\n", "Action", "min", "mean", "stddev", "max")
+	for _, name := range actionNames {
+		stat := stats[name]
+		if stat.TotalOK == 0 {
+			fmt.Printf("%-15s %8s %8s %8s %8s\n", truncateString(name, 15), "-", "-", "-", "-")
+			continue
+		}
+		fmt.Printf("%-15s %8s %8s %8s %8s\n",
+			truncateString(name, 15),
+			formatDuration(stat.GetLatencyMin()),
+			formatDuration(stat.GetLatencyMean()),
+			formatDuration(stat.GetLatencyStdDev()),
+			formatDuration(stat.GetLatencyMax()))
+	}
+	fmt.Println()
+}
+
+// printServerTime compares client-observed latency with server-reported
+// processing time; the difference is network and queueing overhead
+func (r *Reporter) printServerTime(stats map[string]*metrics.ActionStats, actionNames []string) {
+	fmt.Printf("\nServer time (from %s):\n", r.serverTimeHeader)
+	fmt.Printf("%-15s %8s %8s %8s %8s %10s\n",
+		"Action", "Samples", "srv p50", "srv p95", "cli p50", "overhead")
+
+	for _, name := range actionNames {
+		stat := stats[name]
+		samples := stat.ServerTimeSamples()
+		if samples == 0 {
+			fmt.Printf("%-15s %8d %8s %8s %8s %10s\n", truncateString(name, 15), 0, "-", "-", "-", "-")
+			continue
+		}
+
+		serverP50 := stat.GetServerTimePercentile(50.0)
+		clientP50 := stat.GetLatencyPercentile(50.0)
+		overhead := clientP50 - serverP50
+		if overhead < 0 {
+			overhead = 0
+		}
+
+		fmt.Printf("%-15s %8d %8s %8s %8s %10s\n",
+			truncateString(name, 15),
+			samples,
+			formatDuration(serverP50),
+			formatDuration(stat.GetServerTimePercentile(95.0)),
+			formatDuration(clientP50),
+			formatDuration(overhead))
+	}
+}
+
+// printConnClosed warns about responses that closed the connection, which
+// forces a new connection (and TLS handshake) for the next request
+func (r *Reporter) printConnClosed(stats map[string]*metrics.ActionStats, actionNames []string, total, requests int64) {
+	fmt.Printf("Warning: %d of %d responses closed the connection (Connection: close or HTTP/1.0), preventing connection reuse\n",
+		total, requests)
+	for _, name := range actionNames {
+		if closed := stats[name].ConnClosed; closed > 0 {
+			fmt.Printf("  %-15s %d\n", truncateString(name, 15), closed)
+		}
+	}
+}
+
+// printErrorBreakdown lists the most frequent error categories of each
+// action with failed requests
+func (r *Reporter) printErrorBreakdown(stats map[string]*metrics.ActionStats, actionNames []string) {
+	var lines []string
+	for _, name := range actionNames {
+		breakdown := stats[name].ErrorBreakdown()
+		if len(breakdown) == 0 {
+			continue
+		}
+		if len(breakdown) > topErrorCategories {
+			breakdown = breakdown[:topErrorCategories]
+		}
+
+		reasons := make([]string, len(breakdown))
+		for i, e := range breakdown {
+			reasons[i] = fmt.Sprintf("%s %d", e.Category, e.Count)
+		}
+		lines = append(lines, fmt.Sprintf("  %-15s %s", truncateString(name, 15), strings.Join(reasons, ", ")))
+	}
+
+	if len(lines) == 0 {
+		return
+	}
+	fmt.Println("Top errors:")
+	for _, line := range lines {
+		fmt.Println(line)
+	}
+}
+
+// printCaptureMisses lists captures that did not match some responses. A
+// missed capture keeps its previous value, so later actions may have used a
+// stale or empty variable.
+func (r *Reporter) printCaptureMisses(stats map[string]*metrics.ActionStats, actionNames []string) {
+	var lines []string
+	for _, name := range actionNames {
+		stat := stats[name]
+		captures := make([]string, 0, len(stat.CaptureMisses))
+		for capture := range stat.CaptureMisses {
+			captures = append(captures, capture)
+		}
+		sort.Strings(captures)
+
+		for _, capture := range captures {
+			lines = append(lines, fmt.Sprintf("  %-15s %-20s %d of %d responses",
+				truncateString(name, 15), capture, stat.CaptureMisses[capture], stat.TotalOK+stat.TotalErrors))
+		}
+	}
+
+	if len(lines) == 0 {
+		return
+	}
+	fmt.Println("Capture misses:")
+	for _, line := range lines {
+		fmt.Println(line)
+	}
+}
+
+// printIdempotency reports how the backend handled resent idempotency keys:
+// a resend is deduplicated when it gets the original response back
+func (r *Reporter) printIdempotency(stats map[string]*metrics.ActionStats, actionNames []string) {
+	header := false
+	for _, name := range actionNames {
+		stat := stats[name]
+		if stat.Resends == 0 {
+			continue
+		}
+		if !header {
+			fmt.Println("Idempotency resends:")
+			header = true
+		}
+		fmt.Printf("  %-15s %d resent, %d deduplicated, %d mismatched\n",
+			truncateString(name, 15), stat.Resends, stat.Resends-stat.DedupFailures, stat.DedupFailures)
+	}
+}
+
+// printProxies displays how many requests went through each proxy
+func (r *Reporter) printProxies() {
+	fmt.Println("Proxies:")
+	for _, proxy := range r.proxies.Stats() {
+		status := ""
+		if proxy.Down {
+			status = " (unreachable)"
+		}
+		fmt.Printf("  %-40s %8d requests%s\n", proxy.URL, proxy.Requests, status)
+	}
 }
 
+// printQueueTime displays how long open-arrival iterations waited for a free
+// worker. Growing queue time means the offered load exceeds what the
+// workers (and the service behind them) can handle.
+func (r *Reporter) printQueueTime() {
+	fmt.Printf("Queue time (scheduled to dispatch): p50 %s, p90 %s, p99 %s, max %s over %d iterations\n",
+		formatDuration(r.collector.GetQueueTimePercentile(50.0)),
+		formatDuration(r.collector.GetQueueTimePercentile(90.0)),
+		formatDuration(r.collector.GetQueueTimePercentile(99.0)),
+		formatDuration(r.collector.GetQueueTimePercentile(100.0)),
+		r.collector.QueueTimeSamples())
+
+	if dropped := r.collector.DroppedArrivals(); dropped > 0 {
+		fmt.Printf("Warning: %d scheduled iterations dropped because the arrival queue was full\n", dropped)
+	}
+}
+
+// printRetries displays retry totals and retry budget consumption
+func (r *Reporter) printRetries(totalRetries int64) {
+	fmt.Printf("Retries: %d\n", totalRetries)
+
+	if !r.retryBudget.Enabled() {
+		return
+	}
+
+	used := r.retryBudget.Used()
+	allowed := r.retryBudget.Allowed()
+	consumed := float64(0)
+	if allowed > 0 {
+		consumed = float64(used) / float64(allowed) * 100
+	}
+
+	fmt.Printf("Retry budget: %d of %d retries used (%.1f%%)\n", used, allowed, consumed)
+	if r.retryBudget.Exhausted() {
+		fmt.Println("Retry budget exhausted: retries were disabled for the rest of the test")
+	}
+}
+
+// ReportSchemaVersion is the version of the JSON report written by
+// SaveReport. Bump it whenever a field is renamed, removed or changes
+// meaning, or a new field is added.
+const ReportSchemaVersion = 17
+
+// topErrorCategories is how many error categories per action the final
+// report shows
+const topErrorCategories = 3
+
 // SaveReport saves the results to a JSON file
 func (r *Reporter) SaveReport(filename string) error {
 	if filename == "" {
@@ -172,29 +577,99 @@ func (r *Reporter) SaveReport(filename string) error {
 	}
 
 	stats := r.collector.GetStats()
-	elapsed := time.Since(r.startTime).Seconds()
+	elapsed := r.measuredElapsed().Seconds()
 
 	// Build report structure
 	report := map[string]interface{}{
-		"timestamp":    r.startTime.Format(time.RFC3339),
-		"duration_sec": elapsed,
-		"actions":      make(map[string]interface{}),
+		"schema_version": ReportSchemaVersion,
+		"timestamp":      r.startTime.Format(time.RFC3339),
+		"duration_sec":   elapsed,
+		"actions":        make(map[string]interface{}),
+	}
+	if until, ok := r.collector.Warmup(); ok {
+		report["warmup"] = map[string]interface{}{
+			"duration_sec":     until.Sub(r.startTime).Seconds(),
+			"ignored_requests": r.collector.WarmupCount(),
+		}
 	}
 
 	totalOK := int64(0)
 	totalErr := int64(0)
 	totalBytes := int64(0)
+	totalRetries := int64(0)
+
+	allRequests := int64(0)
+	for _, stat := range stats {
+		allRequests += stat.TotalOK + stat.TotalErrors
+	}
 
 	for name, stat := range stats {
 		actionReport := map[string]interface{}{
-			"total_ok":     stat.TotalOK,
-			"total_errors": stat.TotalErrors,
-			"bytes_total":  stat.BytesTotal,
-			"p50_ms":       stat.GetLatencyPercentile(50.0).Milliseconds(),
-			"p90_ms":       stat.GetLatencyPercentile(90.0).Milliseconds(),
-			"p95_ms":       stat.GetLatencyPercentile(95.0).Milliseconds(),
-			"p99_ms":       stat.GetLatencyPercentile(99.0).Milliseconds(),
-			"rps":          float64(stat.TotalOK) / elapsed,
+			"total_ok":            stat.TotalOK,
+			"total_errors":        stat.TotalErrors,
+			"bytes_total":         stat.BytesTotal,
+			"retries":             stat.Retries,
+			"tls_full_handshakes": stat.TLSFullHandshakes,
+			"tls_resumed":         stat.TLSResumed,
+			"connection_close":    stat.ConnClosed,
+			"p50_ms":              stat.GetLatencyPercentile(50.0).Milliseconds(),
+			"p90_ms":              stat.GetLatencyPercentile(90.0).Milliseconds(),
+			"p95_ms":              stat.GetLatencyPercentile(95.0).Milliseconds(),
+			"p99_ms":              stat.GetLatencyPercentile(99.0).Milliseconds(),
+			"min_ms":              float64(stat.GetLatencyMin().Microseconds()) / 1000,
+			"mean_ms":             float64(stat.GetLatencyMean().Microseconds()) / 1000,
+			"stddev_ms":           float64(stat.GetLatencyStdDev().Microseconds()) / 1000,
+			"max_ms":              float64(stat.GetLatencyMax().Microseconds()) / 1000,
+			"rps":                 float64(stat.TotalOK) / elapsed,
+		}
+
+		newConns, reused := stat.ConnSamples()
+		actionReport["new_conn_requests"] = newConns
+		actionReport["new_conn_p50_ms"] = stat.GetNewConnPercentile(50.0).Milliseconds()
+		actionReport["new_conn_p95_ms"] = stat.GetNewConnPercentile(95.0).Milliseconds()
+		actionReport["reused_conn_requests"] = reused
+		actionReport["reused_conn_p50_ms"] = stat.GetReusedConnPercentile(50.0).Milliseconds()
+		actionReport["reused_conn_p95_ms"] = stat.GetReusedConnPercentile(95.0).Milliseconds()
+
+		if stat.TotalOK+stat.TotalErrors == 0 {
+			actionReport["never_executed"] = true
+		}
+
+		if r.weightShares != nil {
+			actionReport["weight_share"] = r.weightShares[name]
+		}
+
+		if stat.Resends > 0 {
+			actionReport["resends"] = stat.Resends
+			actionReport["dedup_failures"] = stat.DedupFailures
+		}
+
+		if breakdown := stat.ErrorBreakdown(); len(breakdown) > 0 {
+			actionReport["errors_by_type"] = breakdown
+		}
+
+		if len(stat.CaptureMisses) > 0 {
+			actionReport["capture_misses"] = stat.CaptureMisses
+		}
+
+		if r.sizeStats {
+			actionReport["size_p50_bytes"] = stat.GetSizePercentile(50.0)
+			actionReport["size_p95_bytes"] = stat.GetSizePercentile(95.0)
+			actionReport["size_max_bytes"] = stat.GetSizePercentile(100.0)
+		}
+
+		if samples := stat.TTFBSamples(); samples > 0 {
+			actionReport["ttfb_samples"] = samples
+			actionReport["ttfb_p50_ms"] = stat.GetTTFBPercentile(50.0).Milliseconds()
+			actionReport["ttfb_p95_ms"] = stat.GetTTFBPercentile(95.0).Milliseconds()
+			actionReport["ttfb_p99_ms"] = stat.GetTTFBPercentile(99.0).Milliseconds()
+		}
+
+		if r.serverTimeHeader != "" {
+			actionReport["server_time_samples"] = stat.ServerTimeSamples()
+			actionReport["server_p50_ms"] = stat.GetServerTimePercentile(50.0).Milliseconds()
+			actionReport["server_p95_ms"] = stat.GetServerTimePercentile(95.0).Milliseconds()
+			actionReport["server_p99_ms"] = stat.GetServerTimePercentile(99.0).Milliseconds()
 		}
 
 		report["actions"].(map[string]interface{})[name] = actionReport
@@ -202,6 +677,7 @@ func (r *Reporter) SaveReport(filename string) error {
 		totalOK += stat.TotalOK
 		totalErr += stat.TotalErrors
 		totalBytes += stat.BytesTotal
+		totalRetries += stat.Retries
 	}
 
 	// Add summary
@@ -211,13 +687,80 @@ func (r *Reporter) SaveReport(filename string) error {
 		successRate = float64(totalOK) / float64(totalRequests) * 100
 	}
 
+	avgConcurrency, maxConcurrency := r.collector.GetConcurrency()
+
 	report["summary"] = map[string]interface{}{
-		"total_requests": totalRequests,
-		"total_ok":       totalOK,
-		"total_errors":   totalErr,
-		"success_rate":   successRate,
-		"avg_rps":        float64(totalOK) / elapsed,
-		"bytes_total":    totalBytes,
+		"total_requests":  totalRequests,
+		"total_ok":        totalOK,
+		"total_errors":    totalErr,
+		"success_rate":    successRate,
+		"avg_rps":         float64(totalOK) / elapsed,
+		"mean_ms":         float64(r.collector.GetOverallMean().Microseconds()) / 1000,
+		"median_ms":       float64(r.collector.GetOverallPercentile(50.0).Microseconds()) / 1000,
+		"min_ms":          float64(r.collector.GetOverallMin().Microseconds()) / 1000,
+		"max_ms":          float64(r.collector.GetOverallMax().Microseconds()) / 1000,
+		"stddev_ms":       float64(r.collector.GetOverallStdDev().Microseconds()) / 1000,
+		"bytes_total":     totalBytes,
+		"total_retries":   totalRetries,
+		"avg_concurrency": avgConcurrency,
+		"max_concurrency": maxConcurrency,
+		"token_refreshes": r.collector.TokenRefreshes(),
+		"login_requests":  r.collector.Logins(),
+		"dial_retries":    r.collector.DialRetries(),
+		"dropped_metrics": r.collector.DroppedCount(),
+		"cancelled":       r.collector.CancelledRequests(),
+		"protocols":       r.collector.GetProtocols(),
+	}
+
+	if r.scenarios != nil {
+		report["scenarios"] = r.scenariosReport(stats, elapsed)
+	}
+
+	if _, _, ok := r.collector.Window(); ok {
+		report["steady_state"] = r.windowReport()
+	}
+
+	report["health"] = r.HealthScore()
+	if sla := r.CheckSLA(); len(sla) > 0 {
+		report["sla"] = sla
+	}
+
+	if r.collector.ThroughputBucketSize() > 0 {
+		report["throughput"] = r.throughputReport()
+	}
+
+	if totals := r.collector.GetPhaseTotals(); totals.Requests > 0 && totals.Total > 0 {
+		report["phases"] = r.phasesReport()
+	}
+
+	if r.collector.TimeSeriesInterval() > 0 {
+		report["timeseries"] = r.timeSeriesReport()
+	}
+	if len(r.stages) > 0 {
+		report["stages"] = r.stagesReport()
+	}
+
+	if samples := r.collector.QueueTimeSamples(); samples > 0 {
+		report["queue_time"] = map[string]interface{}{
+			"iterations": samples,
+			"dropped":    r.collector.DroppedArrivals(),
+			"p50_ms":     r.collector.GetQueueTimePercentile(50.0).Milliseconds(),
+			"p90_ms":     r.collector.GetQueueTimePercentile(90.0).Milliseconds(),
+			"p99_ms":     r.collector.GetQueueTimePercentile(99.0).Milliseconds(),
+			"max_ms":     r.collector.GetQueueTimePercentile(100.0).Milliseconds(),
+		}
+	}
+
+	if r.proxies != nil {
+		report["proxies"] = r.proxies.Stats()
+	}
+
+	if r.retryBudget != nil && r.retryBudget.Enabled() {
+		report["retry_budget"] = map[string]interface{}{
+			"used":      r.retryBudget.Used(),
+			"allowed":   r.retryBudget.Allowed(),
+			"exhausted": r.retryBudget.Exhausted(),
+		}
 	}
 
 	// Write to file
@@ -238,6 +781,58 @@ func (r *Reporter) SaveReport(filename string) error {
 	return nil
 }
 
+// SaveHeatmap saves the per-interval latency distributions to a JSON file.
+// Each interval lists latencies (ms) at metrics.IntervalQuantiles, ready to
+// be rendered as a heatmap.
+func (r *Reporter) SaveHeatmap(filename string, interval time.Duration) error {
+	intervals := r.collector.GetIntervals()
+
+	rows := make([]map[string]interface{}, 0, len(intervals))
+	for _, snapshot := range intervals {
+		latencies := make([]float64, len(snapshot.Percentiles))
+		for i, d := range snapshot.Percentiles {
+			latencies[i] = float64(d.Microseconds()) / 1000
+		}
+
+		rows = append(rows, map[string]interface{}{
+			"offset_sec": snapshot.Offset.Seconds(),
+			"count":      snapshot.Count,
+			"errors":     snapshot.Errors,
+			"latency_ms": latencies,
+		})
+	}
+
+	heatmap := map[string]interface{}{
+		"timestamp":   r.startTime.Format(time.RFC3339),
+		"interval_ms": interval.Milliseconds(),
+		"quantiles":   metrics.IntervalQuantiles,
+		"intervals":   rows,
+	}
+
+	file, err := os.Create(filename)
+	if err != nil {
+		return fmt.Errorf("failed to create heatmap file: %w", err)
+	}
+	defer file.Close()
+
+	encoder := json.NewEncoder(file)
+	encoder.SetIndent("", "  ")
+
+	if err := encoder.Encode(heatmap); err != nil {
+		return fmt.Errorf("failed to write heatmap JSON: %w", err)
+	}
+
+	return nil
+}
+
+// sharePercent returns count as a percentage of total
+func sharePercent(count, total int64) float64 {
+	if total == 0 {
+		return 0
+	}
+	return float64(count) / float64(total) * 100
+}
+
 // formatDuration formats a duration for display
 func formatDuration(d time.Duration) string {
 	if d < time.Microsecond {
@@ -0,0 +1,330 @@
+package reporter
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"flag"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"stampede-shooter/internal/metrics"
+	"stampede-shooter/internal/script"
+)
+
+var update = flag.Bool("update", false, "rewrite the golden files in testdata")
+
+// fixedCollector returns a collector holding a fixed set of requests:
+// successes and a failure of two actions, plus an action that never ran
+func fixedCollector() *metrics.Collector {
+	collector := metrics.NewCollector(metrics.DefaultBufferSize)
+	collector.SetBlocking(true)
+	collector.EnableSizeStats()
+	collector.Preallocate([]string{"Login", "Search", "Export"})
+	collector.Start()
+
+	start := time.Now()
+	request := func(name string, latency time.Duration, status int, newConn bool) {
+		collector.Record(metrics.RequestMetric{
+			Name:       name,
+			Method:     "GET",
+			StartTime:  start,
+			EndTime:    start.Add(latency),
+			StatusCode: status,
+			Proto:      "HTTP/1.1",
+			BytesRead:  1024,
+			NewConn:    newConn,
+			ServerTime: latency / 2,
+			Phases:     [metrics.NumPhases]time.Duration{0, 0, 0, latency / 2, latency / 4},
+			TTFB:       latency * 3 / 4,
+		})
+	}
+	request("Login", 10*time.Millisecond, 200, true)
+	request("Login", 20*time.Millisecond, 200, false)
+	request("Login", 30*time.Millisecond, 200, false)
+	request("Search", 40*time.Millisecond, 200, true)
+	request("Search", 80*time.Millisecond, 200, false)
+	collector.Record(metrics.RequestMetric{
+		Name:       "Search",
+		Method:     "GET",
+		StartTime:  start,
+		EndTime:    start.Add(5 * time.Millisecond),
+		StatusCode: 500,
+		Proto:      "HTTP/1.1",
+		Error:      "expected status 200, got 500",
+	})
+
+	collector.Stop()
+	return collector
+}
+
+// normalizeReport replaces the fields that depend on the wall clock, so the
+// rest of the report can be compared byte for byte
+func normalizeReport(t *testing.T, data []byte) []byte {
+	t.Helper()
+	var report map[string]interface{}
+	if err := json.Unmarshal(data, &report); err != nil {
+		t.Fatalf("report is not valid JSON: %v", err)
+	}
+
+	report["timestamp"] = "<timestamp>"
+	report["duration_sec"] = "<elapsed>"
+	summary := report["summary"].(map[string]interface{})
+	summary["avg_rps"] = "<elapsed>"
+	summary["avg_concurrency"] = "<sampled>"
+	for _, action := range report["actions"].(map[string]interface{}) {
+		action.(map[string]interface{})["rps"] = "<elapsed>"
+	}
+
+	normalized, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return append(normalized, '\n')
+}
+
+// TestSaveReportGolden pins the structure of the JSON report. A failure
+// means the schema changed: bump ReportSchemaVersion, update the README and
+// rewrite the golden file with go test ./internal/reporter -update.
+func TestSaveReportGolden(t *testing.T) {
+	r := New(fixedCollector(), false)
+	r.SetServerTimeHeader("Server-Timing")
+	r.SetSizeStats(true)
+
+	path := filepath.Join(t.TempDir(), "report.json")
+	if err := r.SaveReport(path); err != nil {
+		t.Fatalf("SaveReport() error: %v", err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := normalizeReport(t, data)
+
+	golden := filepath.Join("testdata", "report.golden.json")
+	if *update {
+		if err := os.WriteFile(golden, got, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	want, err := os.ReadFile(golden)
+	if err != nil {
+		t.Fatalf("reading golden file: %v (run with -update to create it)", err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Errorf("JSON report differs from %s (schema version %d):\n%s", golden, ReportSchemaVersion, got)
+	}
+}
+
+// captureStdout returns what fn prints to standard output
+func captureStdout(t *testing.T, fn func()) string {
+	t.Helper()
+	reader, writer, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	stdout := os.Stdout
+	os.Stdout = writer
+	defer func() { os.Stdout = stdout }()
+
+	output := make(chan string)
+	go func() {
+		data, _ := io.ReadAll(reader)
+		output <- string(data)
+	}()
+	fn()
+	writer.Close()
+	return <-output
+}
+
+func TestActionFailingToSendIsReported(t *testing.T) {
+	collector := metrics.NewCollector(metrics.DefaultBufferSize)
+	collector.SetBlocking(true)
+	collector.Preallocate([]string{"Login", "Upload", "Logout"})
+	collector.Start()
+
+	start := time.Now()
+	collector.Record(metrics.RequestMetric{Name: "Login", StartTime: start, EndTime: start.Add(5 * time.Millisecond), StatusCode: 200})
+	// Upload never gets a response: every attempt fails before one arrives
+	for i := 0; i < 3; i++ {
+		collector.Record(metrics.RequestMetric{
+			Name:      "Upload",
+			StartTime: start,
+			EndTime:   start.Add(time.Millisecond),
+			Error:     `Post "http://localhost:1/upload": dial tcp 127.0.0.1:1: connect: connection refused`,
+		})
+	}
+	collector.Stop()
+	r := New(collector, false)
+
+	path := filepath.Join(t.TempDir(), "report.json")
+	if err := r.SaveReport(path); err != nil {
+		t.Fatalf("SaveReport() error: %v", err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var report struct {
+		Actions map[string]struct {
+			TotalOK       int64                `json:"total_ok"`
+			TotalErrors   int64                `json:"total_errors"`
+			NeverExecuted bool                 `json:"never_executed"`
+			ErrorsByType  []metrics.ErrorCount `json:"errors_by_type"`
+		} `json:"actions"`
+	}
+	if err := json.Unmarshal(data, &report); err != nil {
+		t.Fatal(err)
+	}
+
+	upload, ok := report.Actions["Upload"]
+	if !ok {
+		t.Fatal("Upload missing from the JSON report")
+	}
+	if upload.TotalOK != 0 || upload.TotalErrors != 3 || upload.NeverExecuted {
+		t.Errorf("Upload: %d ok, %d errors, never_executed %v, want 0 ok, 3 errors and executed",
+			upload.TotalOK, upload.TotalErrors, upload.NeverExecuted)
+	}
+	if len(upload.ErrorsByType) != 1 || upload.ErrorsByType[0] != (metrics.ErrorCount{Category: metrics.ErrorConnection, Count: 3}) {
+		t.Errorf("Upload errors_by_type %v, want 3 connection errors", upload.ErrorsByType)
+	}
+	if !report.Actions["Logout"].NeverExecuted {
+		t.Error("Logout not flagged never_executed")
+	}
+
+	output := captureStdout(t, r.PrintFinalReport)
+	var uploadRow, neverExecuted string
+	for _, line := range strings.Split(output, "\n") {
+		switch {
+		case strings.HasPrefix(line, "Upload ") && uploadRow == "":
+			uploadRow = line // The results table comes first
+		case strings.HasPrefix(line, "Never executed:"):
+			neverExecuted = line
+		}
+	}
+	if fields := strings.Fields(uploadRow); len(fields) < 3 || fields[1] != "0" || fields[2] != "3" {
+		t.Errorf("Upload row %q, want 0 successes and 3 errors", uploadRow)
+	}
+	if neverExecuted != "Never executed: Logout (no requests recorded)" {
+		t.Errorf("never executed line %q, want only Logout", neverExecuted)
+	}
+}
+
+func TestWeightShares(t *testing.T) {
+	actions := []script.Action{{Name: "Login", Weight: 3}, {Name: "Search", Weight: 1}, {Name: "Export"}}
+	picker, err := script.NewActionPicker(actions)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := New(fixedCollector(), false)
+	r.SetActionPicker(picker, actions)
+	want := map[string]float64{"Login": 0.75, "Search": 0.25, "Export": 0}
+
+	// Console: a Weight column after Share
+	output := captureStdout(t, r.PrintFinalReport)
+	lines := strings.Split(output, "\n")
+	rows := make(map[string][]string)
+	for _, line := range lines {
+		fields := strings.Fields(line)
+		if len(fields) > 0 && rows[fields[0]] == nil {
+			rows[fields[0]] = fields // The results table comes first
+		}
+	}
+	if header := rows["Action"]; len(header) == 0 || header[len(header)-1] != "Weight" {
+		t.Errorf("header %q, want a trailing Weight column", header)
+	}
+	for name, share := range map[string]string{"Login": "75.0%", "Search": "25.0%", "Export": "0.0%"} {
+		if row := rows[name]; len(row) == 0 || row[len(row)-1] != share {
+			t.Errorf("%s row %q, want weight %s", name, row, share)
+		}
+	}
+
+	// JSON: weight_share on every action
+	path := filepath.Join(t.TempDir(), "report.json")
+	if err := r.SaveReport(path); err != nil {
+		t.Fatalf("SaveReport() error: %v", err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var report struct {
+		Actions map[string]struct {
+			WeightShare *float64 `json:"weight_share"`
+		} `json:"actions"`
+	}
+	if err := json.Unmarshal(data, &report); err != nil {
+		t.Fatal(err)
+	}
+	for name, share := range want {
+		if got := report.Actions[name].WeightShare; got == nil || *got != share {
+			t.Errorf("%s weight_share = %v, want %v", name, got, share)
+		}
+	}
+
+	// CSV: a weight_share column, summing to 1 on the TOTAL row
+	path = filepath.Join(t.TempDir(), "report.csv")
+	if err := r.SaveCSV(path); err != nil {
+		t.Fatalf("SaveCSV() error: %v", err)
+	}
+	file, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	records, err := csv.NewReader(file).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	wantCSV := map[string]string{"action": "weight_share", "Login": "0.7500", "Search": "0.2500", "Export": "0.0000", "TOTAL": "1.0000"}
+	for _, record := range records {
+		if got := record[len(record)-1]; got != wantCSV[record[0]] {
+			t.Errorf("CSV %s weight_share %q, want %q", record[0], got, wantCSV[record[0]])
+		}
+	}
+}
+
+func TestNoWeightSharesUnweighted(t *testing.T) {
+	r := New(fixedCollector(), false)
+	if output := captureStdout(t, r.PrintFinalReport); strings.Contains(output, "Weight") {
+		t.Errorf("unweighted report has a Weight column:\n%s", output)
+	}
+}
+
+func TestBadgeRPSMatchesAchievedRate(t *testing.T) {
+	r := New(fixedCollector(), false)
+	r.SetPacing(5, 2)
+	// 6 requests, one of them failed, over 4 seconds
+	r.startTime = time.Now().Add(-4 * time.Second)
+
+	output := captureStdout(t, r.PrintFinalReport)
+	_, after, ok := strings.Cut(output, "achieved ")
+	if !ok {
+		t.Fatalf("report has no achieved rate:\n%s", output)
+	}
+	achieved, _, _ := strings.Cut(after, " ")
+
+	path := filepath.Join(t.TempDir(), "badge.json")
+	if err := r.SaveBadge(path, "rps"); err != nil {
+		t.Fatalf("SaveBadge() error: %v", err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var b badge
+	if err := json.Unmarshal(data, &b); err != nil {
+		t.Fatal(err)
+	}
+
+	if b.Message != achieved {
+		t.Errorf("badge shows %s rps, report achieved %s actions/s", b.Message, achieved)
+	}
+	if b.Message != "1.5" {
+		t.Errorf("badge shows %s rps, want 1.5 (failed requests included)", b.Message)
+	}
+}
@@ -0,0 +1,84 @@
+package reporter
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"stampede-shooter/internal/metrics"
+	"stampede-shooter/internal/script"
+)
+
+// scenarioStats are the results of one scenario's actions combined
+type scenarioStats struct {
+	name    string
+	users   int
+	share   float64 // Intended share of users
+	ok      int64
+	errors  int64
+	latency []time.Duration // p50, p95, p99 of successful requests
+}
+
+// SetScenarios enables grouping results by the scenarios users are assigned
+// to by assigner
+func (r *Reporter) SetScenarios(assigner *script.ScenarioAssigner) {
+	r.scenarios = assigner
+}
+
+// scenarioResults combines the stats of each scenario's actions, which are
+// named "<scenario>/<action>"
+func (r *Reporter) scenarioResults(stats map[string]*metrics.ActionStats) []scenarioStats {
+	users := r.scenarios.Users()
+	var results []scenarioStats
+	for i, scenario := range r.scenarios.Scenarios() {
+		prefix := scenario.Name + "/"
+		inScenario := func(name string) bool { return strings.HasPrefix(name, prefix) }
+
+		result := scenarioStats{name: scenario.Name, users: users[i], share: r.scenarios.Share(i)}
+		for name, stat := range stats {
+			if inScenario(name) {
+				result.ok += stat.TotalOK
+				result.errors += stat.TotalErrors
+			}
+		}
+		result.latency = r.collector.GetPercentilesWhere(inScenario, 50.0, 95.0, 99.0)
+		results = append(results, result)
+	}
+	return results
+}
+
+// printScenarios shows the combined results of each scenario next to the
+// per-action table, whose rows are grouped by scenario through their names
+func (r *Reporter) printScenarios(stats map[string]*metrics.ActionStats, elapsed float64) {
+	fmt.Printf("\n%-15s %8s %8s %8s %8s %8s %8s %8s\n",
+		"Scenario", "Users", "OK", "ERR", "p50", "p95", "p99", "RPS")
+	for _, s := range r.scenarioResults(stats) {
+		fmt.Printf("%-15s %8d %8d %8d %8s %8s %8s %8.1f\n",
+			truncateString(s.name, 15),
+			s.users,
+			s.ok,
+			s.errors,
+			formatDuration(s.latency[0]),
+			formatDuration(s.latency[1]),
+			formatDuration(s.latency[2]),
+			float64(s.ok)/elapsed)
+	}
+}
+
+// scenariosReport returns the scenarios section of the JSON report
+func (r *Reporter) scenariosReport(stats map[string]*metrics.ActionStats, elapsed float64) map[string]interface{} {
+	report := make(map[string]interface{})
+	for _, s := range r.scenarioResults(stats) {
+		report[s.name] = map[string]interface{}{
+			"users":        s.users,
+			"weight_share": s.share,
+			"total_ok":     s.ok,
+			"total_errors": s.errors,
+			"p50_ms":       s.latency[0].Milliseconds(),
+			"p95_ms":       s.latency[1].Milliseconds(),
+			"p99_ms":       s.latency[2].Milliseconds(),
+			"rps":          float64(s.ok) / elapsed,
+		}
+	}
+	return report
+}
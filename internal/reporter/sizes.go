@@ -0,0 +1,43 @@
+package reporter
+
+import (
+	"fmt"
+
+	"stampede-shooter/internal/metrics"
+)
+
+// printSizes shows the distribution of response body sizes per action. A
+// wide spread between p50 and max often explains latency spikes, e.g. a
+// search that sometimes returns thousands of results.
+func (r *Reporter) printSizes(stats map[string]*metrics.ActionStats, actionNames []string) {
+	fmt.Println("\nResponse sizes:")
+	fmt.Printf("%-15s %8s %10s %10s %10s\n", "Action", "Samples", "p50", "p95", "max")
+
+	for _, name := range actionNames {
+		stat := stats[name]
+		samples := stat.SizeSamples()
+		if samples == 0 {
+			fmt.Printf("%-15s %8d %10s %10s %10s\n", truncateString(name, 15), 0, "-", "-", "-")
+			continue
+		}
+
+		fmt.Printf("%-15s %8d %10s %10s %10s\n",
+			truncateString(name, 15),
+			samples,
+			formatBytes(stat.GetSizePercentile(50.0)),
+			formatBytes(stat.GetSizePercentile(95.0)),
+			formatBytes(stat.GetSizePercentile(100.0)))
+	}
+}
+
+// formatBytes formats a byte count with a binary unit
+func formatBytes(n int64) string {
+	switch {
+	case n < 1024:
+		return fmt.Sprintf("%dB", n)
+	case n < 1024*1024:
+		return fmt.Sprintf("%.1fKB", float64(n)/1024)
+	default:
+		return fmt.Sprintf("%.1fMB", float64(n)/(1024*1024))
+	}
+}
@@ -0,0 +1,75 @@
+package reporter
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ThresholdResult is the outcome of checking one SLA threshold
+type ThresholdResult struct {
+	Name   string `json:"name"`
+	Limit  string `json:"limit"`
+	Actual string `json:"actual"`
+	Passed bool   `json:"passed"`
+}
+
+// CheckSLA evaluates the configured SLA thresholds against the results
+func (r *Reporter) CheckSLA() []ThresholdResult {
+	var results []ThresholdResult
+
+	if r.slaP95 > 0 {
+		p95 := r.collector.GetOverallPercentile(95.0)
+		results = append(results, ThresholdResult{
+			Name:   "p95",
+			Limit:  formatDuration(r.slaP95),
+			Actual: formatDuration(p95),
+			Passed: p95 <= r.slaP95,
+		})
+	}
+
+	if r.slaErrorRate > 0 {
+		errorRate := r.errorRate()
+		results = append(results, ThresholdResult{
+			Name:   "error_rate",
+			Limit:  fmt.Sprintf("%.2f%%", r.slaErrorRate),
+			Actual: fmt.Sprintf("%.2f%%", errorRate),
+			Passed: errorRate <= r.slaErrorRate,
+		})
+	}
+
+	return results
+}
+
+// failedThresholds returns the thresholds that did not pass
+func failedThresholds(results []ThresholdResult) []ThresholdResult {
+	var failed []ThresholdResult
+	for _, result := range results {
+		if !result.Passed {
+			failed = append(failed, result)
+		}
+	}
+	return failed
+}
+
+// printSLA prints the outcome of each configured SLA threshold
+func (r *Reporter) printSLA() {
+	results := r.CheckSLA()
+	if len(results) == 0 {
+		return
+	}
+
+	parts := make([]string, len(results))
+	for i, result := range results {
+		status := "ok"
+		if !result.Passed {
+			status = "FAILED"
+		}
+		parts[i] = fmt.Sprintf("%s %s (limit %s) %s", result.Name, result.Actual, result.Limit, status)
+	}
+
+	verdict := "PASS"
+	if len(failedThresholds(results)) > 0 {
+		verdict = "FAIL"
+	}
+	fmt.Printf("SLA %s: %s\n", verdict, strings.Join(parts, ", "))
+}
@@ -0,0 +1,101 @@
+package reporter
+
+import (
+	"fmt"
+	"time"
+
+	"stampede-shooter/internal/metrics"
+)
+
+// Stage is one step of a stepped load profile: Users workers running from
+// Start into the test for Duration
+type Stage struct {
+	Users    int
+	Start    time.Duration
+	Duration time.Duration
+}
+
+// stageResults are the time series points of one stage combined
+type stageResults struct {
+	ok, errors int64
+	length     time.Duration // Time covered by the stage's points
+	peakP95    time.Duration // Worst p95 of any of the stage's intervals
+}
+
+// rps returns the successful requests per second of the stage
+func (s stageResults) rps() float64 {
+	if s.length <= 0 {
+		return 0
+	}
+	return float64(s.ok) / s.length.Seconds()
+}
+
+// SetStages enables annotating the time series with the load stage that was
+// active in each interval
+func (r *Reporter) SetStages(stages []Stage) {
+	r.stages = stages
+}
+
+// stageAt returns the index of the stage active at offset into the test
+func (r *Reporter) stageAt(offset time.Duration) int {
+	for i := len(r.stages) - 1; i > 0; i-- {
+		if offset >= r.stages[i].Start {
+			return i
+		}
+	}
+	return 0
+}
+
+// stageResults combines the time series points by the stage active when
+// each point started. Points are only as fine as the time series interval,
+// so a point straddling a step counts for the stage it started in.
+func (r *Reporter) stageResults(series []metrics.TimeSeriesPoint) []stageResults {
+	results := make([]stageResults, len(r.stages))
+	for _, point := range series {
+		s := &results[r.stageAt(point.Offset)]
+		s.ok += point.OK
+		s.errors += point.Errors
+		s.length += point.Length
+		if point.P95 > s.peakP95 {
+			s.peakP95 = point.P95
+		}
+	}
+	return results
+}
+
+// printStages shows the throughput and latency reached at each stage, so the
+// step where the target stops keeping up stands out
+func (r *Reporter) printStages() {
+	series := r.collector.GetTimeSeries()
+	if len(series) == 0 {
+		return
+	}
+
+	fmt.Printf("\nStages:\n")
+	fmt.Printf("%6s %8s %10s %10s %8s %8s %8s %10s\n",
+		"Stage", "Users", "Start", "Duration", "OK", "ERR", "RPS", "peak p95")
+	for i, s := range r.stageResults(series) {
+		stage := r.stages[i]
+		fmt.Printf("%6d %8d %10s %10s %8d %8d %8.1f %10s\n",
+			i+1, stage.Users, stage.Start, stage.Duration, s.ok, s.errors, s.rps(), formatDuration(s.peakP95))
+	}
+}
+
+// stagesReport returns the stages section of the JSON report
+func (r *Reporter) stagesReport() []map[string]interface{} {
+	results := r.stageResults(r.collector.GetTimeSeries())
+	report := make([]map[string]interface{}, len(r.stages))
+	for i, stage := range r.stages {
+		report[i] = map[string]interface{}{
+			"stage":        i + 1,
+			"users":        stage.Users,
+			"start_sec":    stage.Start.Seconds(),
+			"duration_sec": stage.Duration.Seconds(),
+			"total_ok":     results[i].ok,
+			"total_errors": results[i].errors,
+			"rps":          results[i].rps(),
+			"peak_p95_ms":  float64(results[i].peakP95.Microseconds()) / 1000,
+		}
+	}
+	return report
+}
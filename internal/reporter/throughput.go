@@ -0,0 +1,133 @@
+package reporter
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"stampede-shooter/internal/metrics"
+)
+
+// sparkBlocks are the sparkline levels from lowest to highest
+var sparkBlocks = []rune("▁▂▃▄▅▆▇█")
+
+// sparklineWidth is the number of buckets per sparkline row
+const sparklineWidth = 60
+
+// throughputRates returns the successful requests per second of each
+// throughput bucket. The last bucket is usually cut short by the end of the
+// test, so it is rated over the time it covered.
+func (r *Reporter) throughputRates(buckets []metrics.ThroughputBucket) []float64 {
+	size := r.collector.ThroughputBucketSize()
+	elapsed := time.Since(r.startTime)
+
+	rates := make([]float64, len(buckets))
+	for i, bucket := range buckets {
+		covered := size
+		if rest := elapsed - bucket.Offset; rest > 0 && rest < size {
+			covered = rest
+		}
+		rates[i] = float64(bucket.OK) / covered.Seconds()
+	}
+	return rates
+}
+
+// printThroughput shows throughput over time as a sparkline, so dips stand
+// out without a graphing tool
+func (r *Reporter) printThroughput() {
+	size := r.collector.ThroughputBucketSize()
+	rates := r.throughputRates(r.collector.GetThroughput())
+	if len(rates) == 0 {
+		return
+	}
+
+	// A final bucket covering too little time gives a meaningless dip
+	rated := rates
+	if len(rates) > 1 && time.Since(r.startTime)-time.Duration(len(rates)-1)*size < size/2 {
+		rated = rates[:len(rates)-1]
+	}
+
+	minAt, maxAt := 0, 0
+	for i, rate := range rated {
+		if rate < rated[minAt] {
+			minAt = i
+		}
+		if rate > rated[maxAt] {
+			maxAt = i
+		}
+	}
+
+	fmt.Printf("\nThroughput (%s buckets, successful req/s):\n", size)
+	for start := 0; start < len(rates); start += sparklineWidth {
+		end := start + sparklineWidth
+		if end > len(rates) {
+			end = len(rates)
+		}
+		fmt.Printf("  %8s %s\n", time.Duration(start)*size, sparkline(rates[start:end], rates[maxAt]))
+	}
+	fmt.Printf("  min %.1f at %s, max %.1f at %s\n",
+		rates[minAt], time.Duration(minAt)*size, rates[maxAt], time.Duration(maxAt)*size)
+}
+
+// sparkline renders values as block characters scaled to max
+func sparkline(values []float64, max float64) string {
+	var sb strings.Builder
+	for _, v := range values {
+		level := 0
+		if max > 0 {
+			level = int(v/max*float64(len(sparkBlocks)-1) + 0.5)
+		}
+		sb.WriteRune(sparkBlocks[level])
+	}
+	return sb.String()
+}
+
+// throughputReport returns the throughput section of the JSON report
+func (r *Reporter) throughputReport() map[string]interface{} {
+	throughput := r.collector.GetThroughput()
+	rates := r.throughputRates(throughput)
+	buckets := make([]map[string]interface{}, len(throughput))
+	for i, bucket := range throughput {
+		buckets[i] = map[string]interface{}{
+			"offset_sec": bucket.Offset.Seconds(),
+			"ok":         bucket.OK,
+			"errors":     bucket.Errors,
+			"rps":        rates[i],
+		}
+	}
+
+	return map[string]interface{}{
+		"bucket_sec": r.collector.ThroughputBucketSize().Seconds(),
+		"buckets":    buckets,
+	}
+}
+
+// timeSeriesReport returns the time series section of the JSON report: the
+// RPS and latency percentiles of each interval, and with --stages the stage
+// active when it started
+func (r *Reporter) timeSeriesReport() map[string]interface{} {
+	series := r.collector.GetTimeSeries()
+	points := make([]map[string]interface{}, len(series))
+	for i, point := range series {
+		points[i] = map[string]interface{}{
+			"offset_sec": point.Offset.Seconds(),
+			"length_sec": point.Length.Seconds(),
+			"ok":         point.OK,
+			"errors":     point.Errors,
+			"rps":        point.RPS(),
+			"p50_ms":     float64(point.P50.Microseconds()) / 1000,
+			"p95_ms":     float64(point.P95.Microseconds()) / 1000,
+			"p99_ms":     float64(point.P99.Microseconds()) / 1000,
+		}
+		if len(r.stages) > 0 {
+			stage := r.stageAt(point.Offset)
+			points[i]["stage"] = stage + 1
+			points[i]["users"] = r.stages[stage].Users
+		}
+	}
+
+	return map[string]interface{}{
+		"interval_sec": r.collector.TimeSeriesInterval().Seconds(),
+		"points":       points,
+	}
+}
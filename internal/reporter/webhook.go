@@ -0,0 +1,92 @@
+package reporter
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"text/template"
+	"time"
+)
+
+// webhookTimeout bounds the failure webhook request
+const webhookTimeout = 10 * time.Second
+
+// WebhookPayload is the data sent to the failure webhook, and the data
+// available to a custom payload template
+type WebhookPayload struct {
+	Status           string            `json:"status"`
+	Timestamp        string            `json:"timestamp"`
+	FailedThresholds []ThresholdResult `json:"failed_thresholds"`
+	Summary          WebhookSummary    `json:"summary"`
+}
+
+// WebhookSummary holds the headline results of the run
+type WebhookSummary struct {
+	DurationSec   float64 `json:"duration_sec"`
+	TotalRequests int64   `json:"total_requests"`
+	ErrorRate     float64 `json:"error_rate"`
+	P95Ms         int64   `json:"p95_ms"`
+	RPS           float64 `json:"rps"`
+	HealthScore   float64 `json:"health_score"`
+}
+
+// SendFailureWebhook POSTs the run summary and failed thresholds to url if
+// any SLA threshold failed. The payload is JSON unless templateFile names a
+// text/template rendered with a WebhookPayload, e.g. for Slack formatting.
+// It reports whether a webhook was sent.
+func (r *Reporter) SendFailureWebhook(url, templateFile string) (bool, error) {
+	failed := failedThresholds(r.CheckSLA())
+	if len(failed) == 0 {
+		return false, nil
+	}
+
+	total := int64(0)
+	for _, stat := range r.collector.GetStats() {
+		total += stat.TotalOK + stat.TotalErrors
+	}
+
+	payload := WebhookPayload{
+		Status:           "failed",
+		Timestamp:        r.startTime.Format(time.RFC3339),
+		FailedThresholds: failed,
+		Summary: WebhookSummary{
+			DurationSec:   r.measuredElapsed().Seconds(),
+			TotalRequests: total,
+			ErrorRate:     r.errorRate(),
+			P95Ms:         r.collector.GetOverallPercentile(95.0).Milliseconds(),
+			RPS:           r.achievedRPS(),
+			HealthScore:   r.HealthScore().Score,
+		},
+	}
+
+	var body bytes.Buffer
+	if templateFile != "" {
+		text, err := os.ReadFile(templateFile)
+		if err != nil {
+			return false, fmt.Errorf("failed to read webhook template: %w", err)
+		}
+		tmpl, err := template.New("webhook").Parse(string(text))
+		if err != nil {
+			return false, fmt.Errorf("failed to parse webhook template: %w", err)
+		}
+		if err := tmpl.Execute(&body, payload); err != nil {
+			return false, fmt.Errorf("failed to render webhook template: %w", err)
+		}
+	} else if err := json.NewEncoder(&body).Encode(payload); err != nil {
+		return false, fmt.Errorf("failed to encode webhook payload: %w", err)
+	}
+
+	client := &http.Client{Timeout: webhookTimeout}
+	resp, err := client.Post(url, "application/json", &body)
+	if err != nil {
+		return false, fmt.Errorf("failed to send webhook: %w", err)
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		return false, fmt.Errorf("webhook returned status %d", resp.StatusCode)
+	}
+	return true, nil
+}
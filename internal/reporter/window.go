@@ -0,0 +1,124 @@
+package reporter
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// windowBounds returns the steady-state window relative to the test start,
+// cut short if the test ended early, and false if no window is enabled
+func (r *Reporter) windowBounds() (start, end time.Duration, ok bool) {
+	from, to, ok := r.collector.Window()
+	if !ok {
+		return 0, 0, false
+	}
+	if now := time.Now(); now.Before(to) {
+		to = now
+	}
+	if to.Before(from) {
+		to = from
+	}
+	return from.Sub(r.startTime), to.Sub(r.startTime), true
+}
+
+// printWindow shows the stats of requests started within the steady-state
+// window next to the full-run table, without warm-up and ramp-down traffic
+func (r *Reporter) printWindow(actionNames []string) {
+	start, end, _ := r.windowBounds()
+	stats := r.collector.GetWindowStats()
+	seconds := (end - start).Seconds()
+
+	fmt.Printf("\nSteady-state window (%.0fs to %.0fs):\n", start.Seconds(), end.Seconds())
+	if seconds <= 0 {
+		fmt.Println("No requests (the test ended before the window started)")
+		return
+	}
+
+	fmt.Printf("%-15s %8s %8s %8s %8s %8s %8s %8s\n",
+		"Action", "OK", "ERR", "p50", "p90", "p95", "p99", "RPS")
+	fmt.Println(strings.Repeat("─", 87))
+
+	totalOK := int64(0)
+	totalErr := int64(0)
+	for _, name := range actionNames {
+		stat, ok := stats[name]
+		if !ok {
+			fmt.Printf("%-15s %8d %8d %8s %8s %8s %8s %8.1f\n", truncateString(name, 15), 0, 0, "-", "-", "-", "-", 0.0)
+			continue
+		}
+
+		fmt.Printf("%-15s %8d %8d %8s %8s %8s %8s %8.1f\n",
+			truncateString(name, 15),
+			stat.TotalOK,
+			stat.TotalErrors,
+			formatDuration(stat.GetLatencyPercentile(50.0)),
+			formatDuration(stat.GetLatencyPercentile(90.0)),
+			formatDuration(stat.GetLatencyPercentile(95.0)),
+			formatDuration(stat.GetLatencyPercentile(99.0)),
+			float64(stat.TotalOK)/seconds)
+
+		totalOK += stat.TotalOK
+		totalErr += stat.TotalErrors
+	}
+	fmt.Println(strings.Repeat("─", 87))
+
+	successRate := float64(100)
+	if totalOK+totalErr > 0 {
+		successRate = float64(totalOK) / float64(totalOK+totalErr) * 100
+	}
+	fmt.Printf("Window totals: %d requests, %.1f%% success, %.0fs, %.1f rps\n",
+		totalOK+totalErr, successRate, seconds, float64(totalOK)/seconds)
+}
+
+// windowReport returns the steady-state window section of the JSON report
+func (r *Reporter) windowReport() map[string]interface{} {
+	start, end, _ := r.windowBounds()
+	seconds := (end - start).Seconds()
+
+	actions := make(map[string]interface{})
+	totalOK := int64(0)
+	totalErr := int64(0)
+	for name, stat := range r.collector.GetWindowStats() {
+		actionReport := map[string]interface{}{
+			"total_ok":     stat.TotalOK,
+			"total_errors": stat.TotalErrors,
+			"p50_ms":       stat.GetLatencyPercentile(50.0).Milliseconds(),
+			"p90_ms":       stat.GetLatencyPercentile(90.0).Milliseconds(),
+			"p95_ms":       stat.GetLatencyPercentile(95.0).Milliseconds(),
+			"p99_ms":       stat.GetLatencyPercentile(99.0).Milliseconds(),
+			"rps":          ratePerSecond(stat.TotalOK, seconds),
+		}
+		actions[name] = actionReport
+
+		totalOK += stat.TotalOK
+		totalErr += stat.TotalErrors
+	}
+
+	successRate := float64(100)
+	if totalOK+totalErr > 0 {
+		successRate = float64(totalOK) / float64(totalOK+totalErr) * 100
+	}
+
+	return map[string]interface{}{
+		"start_sec":    start.Seconds(),
+		"end_sec":      end.Seconds(),
+		"duration_sec": seconds,
+		"actions":      actions,
+		"summary": map[string]interface{}{
+			"total_requests": totalOK + totalErr,
+			"total_ok":       totalOK,
+			"total_errors":   totalErr,
+			"success_rate":   successRate,
+			"avg_rps":        ratePerSecond(totalOK, seconds),
+		},
+	}
+}
+
+// ratePerSecond returns count per second, or 0 over an empty period
+func ratePerSecond(count int64, seconds float64) float64 {
+	if seconds <= 0 {
+		return 0
+	}
+	return float64(count) / seconds
+}
@@ -0,0 +1,72 @@
+package script
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// fileIncludePattern matches a {{file "path"}} include
+var fileIncludePattern = regexp.MustCompile(`\{\{file\s+"([^"]+)"\}\}`)
+
+// loadFiles reads body_file into json_body (for .json files) or body, and
+// inlines {{file "path"}} includes, with paths relative to baseDir. Files
+// are read once at load time and their contents are template-expanded per
+// request like inline bodies. An inline body or json_body takes precedence
+// over body_file, which is still read so a missing file fails the load.
+func (a *Action) loadFiles(baseDir string) error {
+	if a.BodyFile != "" {
+		content, err := readScriptFile(baseDir, a.BodyFile)
+		if err != nil {
+			return fmt.Errorf("failed to read body_file: %w", err)
+		}
+
+		switch {
+		case a.Body != "" || a.JSONBody != "":
+			// The inline body overrides the file
+		case strings.EqualFold(filepath.Ext(a.BodyFile), ".json"):
+			a.JSONBody = content
+		default:
+			a.Body = content
+		}
+	}
+
+	if a.Multipart != nil {
+		if err := a.Multipart.resolveFiles(baseDir); err != nil {
+			return err
+		}
+	}
+
+	var err error
+	include := func(s string) string {
+		return fileIncludePattern.ReplaceAllStringFunc(s, func(match string) string {
+			content, readErr := readScriptFile(baseDir, fileIncludePattern.FindStringSubmatch(match)[1])
+			if readErr != nil && err == nil {
+				err = fmt.Errorf("failed to include file: %w", readErr)
+			}
+			return content
+		})
+	}
+	a.URL = include(a.URL)
+	a.JSONBody = include(a.JSONBody)
+	a.Body = include(a.Body)
+	for key, value := range a.Headers {
+		a.Headers[key] = include(value)
+	}
+	return err
+}
+
+// readScriptFile reads a file referenced by a script, relative to baseDir
+// unless absolute
+func readScriptFile(baseDir, path string) (string, error) {
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(baseDir, path)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
@@ -0,0 +1,56 @@
+package script
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestBodyFilePrecedence(t *testing.T) {
+	tests := []struct {
+		name         string
+		file         string
+		inline       string
+		wantBody     string
+		wantJSONBody string
+	}{
+		{"JSON file", "order.json", "", "", `{"file":true}`},
+		{"text file", "order.txt", "", "file=true", ""},
+		{"JSON file under inline json_body", "order.json", `json_body: '{"inline":true}'`, "", `{"inline":true}`},
+		{"JSON file under inline body", "order.json", "body: inline=true", "inline=true", ""},
+		{"text file under inline body", "order.txt", "body: inline=true", "inline=true", ""},
+		{"text file under inline json_body", "order.txt", `json_body: '{"inline":true}'`, "", `{"inline":true}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			writeFile(t, dir, "order.json", `{"file":true}`)
+			writeFile(t, dir, "order.txt", "file=true")
+			s, err := LoadScript(writeFile(t, dir, "script.yml", `
+- name: CreateOrder
+  method: POST
+  url: http://localhost/orders
+  body_file: `+tt.file+`
+  `+tt.inline+`
+`))
+			if err != nil {
+				t.Fatalf("LoadScript() error: %v", err)
+			}
+			if a := s.Actions[0]; a.Body != tt.wantBody || a.JSONBody != tt.wantJSONBody {
+				t.Errorf("body %q, json_body %q, want %q and %q", a.Body, a.JSONBody, tt.wantBody, tt.wantJSONBody)
+			}
+		})
+	}
+}
+
+func TestBodyFileMissingUnderInlineBody(t *testing.T) {
+	_, err := LoadScript(writeFile(t, t.TempDir(), "script.yml", `
+- name: CreateOrder
+  method: POST
+  url: http://localhost/orders
+  body_file: missing.json
+  json_body: '{"inline":true}'
+`))
+	if err == nil || !strings.Contains(err.Error(), "failed to read body_file") {
+		t.Errorf("LoadScript() = %v, want a body_file read error", err)
+	}
+}
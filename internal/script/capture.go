@@ -0,0 +1,50 @@
+package script
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+)
+
+// captureNamePattern restricts capture names to what {{var name}} can reference
+var captureNamePattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// validateCaptures checks capture names and compiles their extractors
+func (a *Action) validateCaptures() error {
+	a.captureOrder = a.captureOrder[:0]
+	for name, extractor := range a.Capture {
+		if !captureNamePattern.MatchString(name) {
+			return fmt.Errorf("invalid capture name %q: use letters, digits and underscores", name)
+		}
+		if err := extractor.compile(); err != nil {
+			return fmt.Errorf("capture %q: %w", name, err)
+		}
+		a.Capture[name] = extractor
+		a.captureOrder = append(a.captureOrder, name)
+	}
+	sort.Strings(a.captureOrder)
+	return nil
+}
+
+// RunCaptures extracts the action's captures from a response body. Captures
+// run in name order and independently of each other: values that matched are
+// returned even when others missed, and the names of the missed ones are
+// returned in order.
+func (a *Action) RunCaptures(body []byte) (values map[string]string, misses []string) {
+	if len(a.captureOrder) == 0 {
+		return nil, nil
+	}
+
+	shared := &responseBody{raw: body}
+	values = make(map[string]string, len(a.captureOrder))
+	for _, name := range a.captureOrder {
+		extractor := a.Capture[name]
+		value, err := extractor.extract(shared)
+		if err != nil {
+			misses = append(misses, name)
+			continue
+		}
+		values[name] = value
+	}
+	return values, misses
+}
@@ -0,0 +1,140 @@
+package script
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// loadAction loads a script holding a single action and returns it
+func loadAction(t *testing.T, yaml string) Action {
+	t.Helper()
+	s, err := LoadScript(writeFile(t, t.TempDir(), "script.yml", yaml))
+	if err != nil {
+		t.Fatalf("LoadScript() error: %v", err)
+	}
+	return s.Actions[0]
+}
+
+func TestRunCapturesJSON(t *testing.T) {
+	action := loadAction(t, `
+- name: Login
+  method: POST
+  url: http://localhost/login
+  capture:
+    token: {json: $.data.token}
+    user_id: {json: $.data.user.id}
+    first_role: {json: "$.data.user.roles[0]"}
+    last_role: {json: "$.data.user.roles[-1]"}
+    admin: {json: $.data.user.admin}
+    user: {json: $.data.user}
+    session: {regex: '"session":\s*"(\w+)"'}
+`)
+	body := []byte(`{"session": "s3", "data": {"token": "abc", "user": {"id": 42, "admin": false, "roles": ["read", "write"]}}}`)
+
+	values, misses := action.RunCaptures(body)
+	if len(misses) > 0 {
+		t.Fatalf("captures %v missed", misses)
+	}
+	want := map[string]string{
+		"token":      "abc",
+		"user_id":    "42",
+		"first_role": "read",
+		"last_role":  "write",
+		"admin":      "false",
+		"user":       `{"admin":false,"id":42,"roles":["read","write"]}`,
+		"session":    "s3",
+	}
+	if !reflect.DeepEqual(values, want) {
+		t.Errorf("RunCaptures() = %v, want %v", values, want)
+	}
+}
+
+func TestRunCapturesHTML(t *testing.T) {
+	action := loadAction(t, `
+- name: SignIn
+  method: GET
+  url: http://localhost/sign_in
+  capture:
+    csrf: {regex: 'name="csrf-token" content="([^"]+)"'}
+    form_action: {regex: '<form[^>]* action="([^"]+)"'}
+    title: {regex: '<title>(.*?)</title>'}
+    whole_match: {regex: 'v\d+\.\d+'}
+`)
+	body := []byte(`<html><head><title>Sign in</title>
+<meta name="csrf-token" content="tok-123"></head>
+<body><p>v2.14</p><form method="post" action="/session"></form></body></html>`)
+
+	values, misses := action.RunCaptures(body)
+	if len(misses) > 0 {
+		t.Fatalf("captures %v missed", misses)
+	}
+	want := map[string]string{
+		"csrf":        "tok-123",
+		"form_action": "/session",
+		"title":       "Sign in",
+		"whole_match": "v2.14", // No group, so the whole match
+	}
+	if !reflect.DeepEqual(values, want) {
+		t.Errorf("RunCaptures() = %v, want %v", values, want)
+	}
+}
+
+func TestRunCapturesPartialMiss(t *testing.T) {
+	action := loadAction(t, `
+- name: Login
+  method: POST
+  url: http://localhost/login
+  capture:
+    token: {json: $.token}
+    refresh: {json: $.refresh}
+    expires: {json: $.expires}
+    csrf: {regex: 'csrf=(\w+)'}
+`)
+
+	// The captures that match are kept; the missed ones are reported in name order
+	values, misses := action.RunCaptures([]byte(`{"token": "abc"}`))
+	if want := map[string]string{"token": "abc"}; !reflect.DeepEqual(values, want) {
+		t.Errorf("values = %v, want %v", values, want)
+	}
+	if want := []string{"csrf", "expires", "refresh"}; !reflect.DeepEqual(misses, want) {
+		t.Errorf("misses = %v, want %v", misses, want)
+	}
+
+	// A body that is not JSON misses every JSON capture but not the regex
+	values, misses = action.RunCaptures([]byte(`<p>csrf=xyz</p>`))
+	if want := map[string]string{"csrf": "xyz"}; !reflect.DeepEqual(values, want) {
+		t.Errorf("values = %v, want %v", values, want)
+	}
+	if want := []string{"expires", "refresh", "token"}; !reflect.DeepEqual(misses, want) {
+		t.Errorf("misses = %v, want %v", misses, want)
+	}
+}
+
+func TestCaptureValidation(t *testing.T) {
+	tests := []struct {
+		name    string
+		capture string
+		wantErr string
+	}{
+		{"invalid name", `{bad-name: {json: $.id}}`, `invalid capture name "bad-name"`},
+		{"both json and regex", `{id: {json: $.id, regex: 'id'}}`, "set either json or regex, not both"},
+		{"neither", `{id: {}}`, "json or regex is required"},
+		{"bad JSONPath", `{id: {json: id}}`, "must start with $"},
+		{"bad regex", `{id: {regex: '(unclosed'}}`, "invalid regex"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := writeFile(t, t.TempDir(), "script.yml", `
+- name: Login
+  method: GET
+  url: http://localhost/
+  capture: `+tt.capture+`
+`)
+			_, err := LoadScript(path)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("LoadScript() = %v, want error containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
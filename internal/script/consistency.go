@@ -0,0 +1,34 @@
+package script
+
+import "fmt"
+
+// Consistency invariants
+const (
+	// InvariantMonotonic requires each worker to observe non-decreasing values
+	InvariantMonotonic = "monotonic"
+	// InvariantEqual requires all workers to observe the same value
+	InvariantEqual = "equal"
+)
+
+// ConsistencyCheck extracts a value from the response and validates it
+// against an invariant. Checks with the same name share their state, so a
+// value can be tracked across several actions.
+type ConsistencyCheck struct {
+	Name      string `yaml:"name"`
+	Invariant string `yaml:"invariant"`
+	Extractor `yaml:",inline"`
+}
+
+// validate checks and compiles a consistency check
+func (c *ConsistencyCheck) validate() error {
+	if c.Name == "" {
+		return fmt.Errorf("consistency check needs a name")
+	}
+	if c.Invariant != InvariantMonotonic && c.Invariant != InvariantEqual {
+		return fmt.Errorf("consistency check %q: invariant must be %s or %s", c.Name, InvariantMonotonic, InvariantEqual)
+	}
+	if err := c.Extractor.compile(); err != nil {
+		return fmt.Errorf("consistency check %q: %w", c.Name, err)
+	}
+	return nil
+}
@@ -0,0 +1,210 @@
+package script
+
+import (
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Data holds the named lists {{pick <name>}} draws values from. Lists of
+// YAML and JSON files are held in memory. A CSV file is only indexed: the
+// offset of each record is kept and a pick reads its record from disk, so
+// files with millions of rows cost a few bytes per row. The CSV file stays
+// open until Close.
+type Data struct {
+	lists map[string]*dataList
+	file  *os.File // Indexed CSV file, nil for YAML and JSON
+	rows  []int64  // Offset of each CSV record, then the end of the last one
+}
+
+// dataList is a named list of values. A CSV list is a column; rows holds
+// the records with a value in it, or is nil when every record has one.
+type dataList struct {
+	values []string
+	column int
+	rows   []int32
+	count  int
+}
+
+// pickPattern matches a {{pick name}} reference to a data list
+var pickPattern = regexp.MustCompile(`\{\{pick\s+([A-Za-z_][A-Za-z0-9_-]*)\}\}`)
+
+// LoadData loads named lists from a data file. YAML and JSON files map list
+// names to arrays of strings; a CSV file's header row names one list per
+// column, with empty cells skipped.
+func LoadData(filename string) (*Data, error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open data file: %w", err)
+	}
+
+	var data *Data
+	if strings.EqualFold(filepath.Ext(filename), ".csv") {
+		data, err = indexDataCSV(file)
+	} else {
+		defer file.Close()
+		var lists map[string][]string
+		err = yaml.NewDecoder(file).Decode(&lists)
+		if err == io.EOF {
+			err = nil
+		}
+		data = &Data{lists: make(map[string]*dataList, len(lists))}
+		for name, values := range lists {
+			data.lists[name] = &dataList{values: values, count: len(values)}
+		}
+	}
+	if err != nil {
+		file.Close()
+		return nil, fmt.Errorf("failed to parse data file: %w", err)
+	}
+
+	for name, list := range data.lists {
+		if list.count == 0 {
+			data.Close()
+			return nil, fmt.Errorf("data list %q is empty", name)
+		}
+	}
+	return data, nil
+}
+
+// indexDataCSV indexes one list per column of a CSV file, keeping the file
+// open for picks
+func indexDataCSV(file *os.File) (*Data, error) {
+	reader := csv.NewReader(file)
+	reader.FieldsPerRecord = -1
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, err
+	}
+
+	data := &Data{lists: make(map[string]*dataList, len(header)), file: file}
+	columns := make([]*dataList, len(header))
+	for i, name := range header {
+		columns[i] = &dataList{column: i}
+		data.lists[strings.TrimSpace(name)] = columns[i]
+	}
+
+	for {
+		offset := reader.InputOffset()
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		row := int32(len(data.rows))
+		data.rows = append(data.rows, offset)
+
+		for i, list := range columns {
+			if i < len(record) && record[i] != "" {
+				// Only columns with gaps need to know which records to read
+				if list.rows != nil || list.count < int(row) {
+					if list.rows == nil {
+						list.rows = make([]int32, list.count)
+						for r := range list.rows {
+							list.rows[r] = int32(r)
+						}
+					}
+					list.rows = append(list.rows, row)
+				}
+				list.count++
+			}
+		}
+	}
+	data.rows = append(data.rows, reader.InputOffset())
+	return data, nil
+}
+
+// has reports whether the data defines the named list
+func (d *Data) has(name string) bool {
+	if d == nil {
+		return false
+	}
+	_, ok := d.lists[name]
+	return ok
+}
+
+// pick returns a random value of the named list, drawn with rng. Reading a
+// CSV record can only fail on I/O errors, as the file was parsed while
+// indexing.
+func (d *Data) pick(name string, rng *rand.Rand) (string, error) {
+	if d == nil {
+		return "", nil
+	}
+	list := d.lists[name]
+	if list == nil || list.count == 0 {
+		return "", nil
+	}
+
+	i := rng.Intn(list.count)
+	if d.file == nil {
+		return list.values[i], nil
+	}
+
+	row := i
+	if list.rows != nil {
+		row = int(list.rows[i])
+	}
+	buf := make([]byte, d.rows[row+1]-d.rows[row])
+	if _, err := d.file.ReadAt(buf, d.rows[row]); err != nil && err != io.EOF {
+		return "", fmt.Errorf("failed to read data list %q: %w", name, err)
+	}
+	reader := csv.NewReader(bytes.NewReader(buf))
+	reader.FieldsPerRecord = -1
+	record, err := reader.Read()
+	if err != nil {
+		return "", fmt.Errorf("failed to read data list %q: %w", name, err)
+	}
+	return record[list.column], nil
+}
+
+// Close releases the data file of CSV lists
+func (d *Data) Close() error {
+	if d == nil || d.file == nil {
+		return nil
+	}
+	return d.file.Close()
+}
+
+// SetData registers the lists {{pick <name>}} draws from. Every list the
+// script picks from must exist, so a typo fails at load time rather than
+// silently expanding to nothing.
+func (s *Script) SetData(data *Data) error {
+	for i := range s.Actions {
+		action := &s.Actions[i]
+		for _, field := range action.templatedFields() {
+			for _, match := range pickPattern.FindAllStringSubmatch(field, -1) {
+				if !data.has(match[1]) {
+					return fmt.Errorf("action %q picks from unknown list %q; define it in the --data file", action.Name, match[1])
+				}
+			}
+		}
+		action.data = data
+	}
+	return nil
+}
+
+// templatedFields returns the action fields templates are expanded in
+func (a *Action) templatedFields() []string {
+	fields := []string{a.URL, a.Body, a.JSONBody, a.ExpectBodyContains}
+	for _, value := range a.Headers {
+		fields = append(fields, value)
+	}
+	if a.Multipart != nil {
+		fields = append(fields, a.Multipart.templatedFields()...)
+	}
+	if a.WebSocket != nil {
+		fields = append(fields, a.WebSocket.templatedFields()...)
+	}
+	return fields
+}
@@ -0,0 +1,183 @@
+package script
+
+import (
+	"bufio"
+	"fmt"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"sort"
+	"strings"
+	"testing"
+)
+
+// loadTestData writes a data file and loads it, closing it with the test
+func loadTestData(t *testing.T, name, content string) *Data {
+	t.Helper()
+	data, err := LoadData(writeFile(t, t.TempDir(), name, content))
+	if err != nil {
+		t.Fatalf("LoadData() error: %v", err)
+	}
+	t.Cleanup(func() { data.Close() })
+	return data
+}
+
+// pickedValues draws from a list until every value has likely been seen and
+// returns the distinct values in order
+func pickedValues(t *testing.T, data *Data, name string) []string {
+	t.Helper()
+	rng := rand.New(rand.NewSource(1))
+	seen := make(map[string]bool)
+	for i := 0; i < 1000; i++ {
+		value, err := data.pick(name, rng)
+		if err != nil {
+			t.Fatalf("pick(%q) error: %v", name, err)
+		}
+		seen[value] = true
+	}
+	values := make([]string, 0, len(seen))
+	for value := range seen {
+		values = append(values, value)
+	}
+	sort.Strings(values)
+	return values
+}
+
+func TestLoadDataYAML(t *testing.T) {
+	data := loadTestData(t, "data.yaml", "movies: [heat, alien, up]\ncities: [Berlin]\n")
+
+	if got, want := pickedValues(t, data, "movies"), []string{"alien", "heat", "up"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("movies = %v, want %v", got, want)
+	}
+	if got, want := pickedValues(t, data, "cities"), []string{"Berlin"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("cities = %v, want %v", got, want)
+	}
+}
+
+func TestLoadDataCSV(t *testing.T) {
+	data := loadTestData(t, "data.csv", `movie, city ,note
+heat,Berlin,"quoted, with a comma"
+alien,,
+,Lagos,"spans
+two lines"
+
+up,Lima,
+`)
+
+	tests := []struct {
+		list string
+		want []string
+	}{
+		{"movie", []string{"alien", "heat", "up"}},                     // Gap in the middle
+		{"city", []string{"Berlin", "Lagos", "Lima"}},                  // Gap in the middle, name trimmed
+		{"note", []string{"quoted, with a comma", "spans\ntwo lines"}}, // Gaps at the end
+	}
+	for _, tt := range tests {
+		if got := pickedValues(t, data, tt.list); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s = %q, want %q", tt.list, got, tt.want)
+		}
+	}
+}
+
+func TestLoadDataErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		file    string
+		content string
+		wantErr string
+	}{
+		{"empty YAML list", "data.yaml", "movies: []\n", `data list "movies" is empty`},
+		{"empty CSV column", "data.csv", "movie,city\nheat,\n", `data list "city" is empty`},
+		{"invalid CSV", "data.csv", "movie\n\"unterminated\n", "failed to parse data file"},
+		{"invalid YAML", "data.yaml", "movies: [heat\n", "failed to parse data file"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := LoadData(writeFile(t, t.TempDir(), tt.file, tt.content))
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("LoadData() = %v, want error containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestPickReadError(t *testing.T) {
+	data := loadTestData(t, "data.csv", "movie\nheat\nalien\n")
+	s, err := LoadScript(writeFile(t, t.TempDir(), "script.yml", `
+- name: Search
+  method: GET
+  url: http://localhost/search?q={{pick movie}}
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.SetData(data); err != nil {
+		t.Fatal(err)
+	}
+
+	expanded, err := s.Actions[0].ExpandTemplates(1, nil, rand.New(rand.NewSource(1)))
+	if err != nil || (expanded.URL != "http://localhost/search?q=heat" && expanded.URL != "http://localhost/search?q=alien") {
+		t.Fatalf("ExpandTemplates() = %q, %v", expanded.URL, err)
+	}
+
+	// A failed read is reported rather than expanding to nothing
+	data.Close()
+	if _, err := s.Actions[0].ExpandTemplates(1, nil, rand.New(rand.NewSource(1))); err == nil || !strings.Contains(err.Error(), `data list "movie"`) {
+		t.Errorf("ExpandTemplates() after the file closed = %v, want a read error", err)
+	}
+}
+
+// heapInUse returns the live heap after a collection
+func heapInUse() int64 {
+	runtime.GC()
+	var stats runtime.MemStats
+	runtime.ReadMemStats(&stats)
+	return int64(stats.HeapAlloc)
+}
+
+func TestLoadDataCSVBoundedMemory(t *testing.T) {
+	if testing.Short() {
+		t.Skip("writes a large data file")
+	}
+
+	// 200,000 rows of about 90 bytes: a 19 MB file
+	const rows = 200000
+	path := filepath.Join(t.TempDir(), "data.csv")
+	file, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w := bufio.NewWriter(file)
+	fmt.Fprintln(w, "email,query")
+	for i := 0; i < rows; i++ {
+		fmt.Fprintf(w, "user%07d@loadtest.example.com,%s\n", i, strings.Repeat("q", 60))
+	}
+	if err := w.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	file.Close()
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	before := heapInUse()
+	data, err := LoadData(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer data.Close()
+	retained := heapInUse() - before
+
+	// The index keeps one offset per row, not the values
+	if limit := info.Size() / 4; retained > limit {
+		t.Errorf("loading a %d byte file retained %d bytes, want at most %d", info.Size(), retained, limit)
+	}
+	value, err := data.pick("email", rand.New(rand.NewSource(1)))
+	if err != nil || !strings.HasSuffix(value, "@loadtest.example.com") {
+		t.Errorf("pick(email) = %q, %v", value, err)
+	}
+	t.Logf("%d byte file, %d bytes retained (%.1f bytes per row)", info.Size(), retained, float64(retained)/rows)
+}
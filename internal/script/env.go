@@ -0,0 +1,49 @@
+package script
+
+import (
+	"os"
+	"regexp"
+	"sort"
+)
+
+// envPattern matches {{env "NAME"}} and {{env "NAME" "default"}}
+var envPattern = regexp.MustCompile(`\{\{\s*env\s+"([^"]+)"(?:\s+"([^"]*)")?\s*\}\}`)
+
+// expandEnv replaces the {{env}} references of a script file with the
+// values of environment variables before it is parsed, so they work in any
+// field. Unset variables expand to their default, or to an empty string
+// when there is none; their names are returned in sorted order.
+func expandEnv(data []byte) ([]byte, []string) {
+	missing := make(map[string]bool)
+	expanded := envPattern.ReplaceAllFunc(data, func(match []byte) []byte {
+		parts := envPattern.FindSubmatch(match)
+		if value, ok := os.LookupEnv(string(parts[1])); ok {
+			return []byte(value)
+		}
+		if parts[2] == nil {
+			missing[string(parts[1])] = true
+		}
+		return parts[2]
+	})
+
+	names := make([]string, 0, len(missing))
+	for name := range missing {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return expanded, names
+}
+
+// mergeNames returns the sorted union of two name lists
+func mergeNames(a, b []string) []string {
+	seen := make(map[string]bool, len(a)+len(b))
+	var merged []string
+	for _, name := range append(append([]string{}, a...), b...) {
+		if !seen[name] {
+			seen[name] = true
+			merged = append(merged, name)
+		}
+	}
+	sort.Strings(merged)
+	return merged
+}
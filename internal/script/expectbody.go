@@ -0,0 +1,53 @@
+package script
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"unicode/utf8"
+)
+
+// bodySnippetLen is the number of body bytes quoted in body assertion errors
+const bodySnippetLen = 120
+
+// compileExpectBody compiles expect_body_regex
+func (a *Action) compileExpectBody() error {
+	a.bodyRegex = nil
+	if a.ExpectBodyRegex == "" {
+		return nil
+	}
+	re, err := regexp.Compile(a.ExpectBodyRegex)
+	if err != nil {
+		return fmt.Errorf("invalid expect_body_regex: %w", err)
+	}
+	a.bodyRegex = re
+	return nil
+}
+
+// CheckBody returns an error if the response body does not contain
+// expect_body_contains or does not match expect_body_regex. The error quotes
+// the start of the body.
+func (a *Action) CheckBody(body []byte) error {
+	if a.ExpectBodyContains != "" && !bytes.Contains(body, []byte(a.ExpectBodyContains)) {
+		return fmt.Errorf("expected body to contain %q, got %s", a.ExpectBodyContains, bodySnippet(body))
+	}
+	if a.bodyRegex != nil && !a.bodyRegex.Match(body) {
+		return fmt.Errorf("expected body to match %q, got %s", a.ExpectBodyRegex, bodySnippet(body))
+	}
+	return nil
+}
+
+// bodySnippet quotes the start of a body, cut at a character boundary
+func bodySnippet(body []byte) string {
+	if len(body) == 0 {
+		return "an empty body"
+	}
+	if len(body) <= bodySnippetLen {
+		return fmt.Sprintf("%q", body)
+	}
+	cut := bodySnippetLen
+	for cut > 0 && !utf8.RuneStart(body[cut]) {
+		cut--
+	}
+	return fmt.Sprintf("%q... (%d bytes)", body[:cut], len(body))
+}
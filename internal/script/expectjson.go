@@ -0,0 +1,98 @@
+package script
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+)
+
+// jsonCheck is one expect_json entry: the value at a JSONPath must equal
+// want, or an array at the path must contain it
+type jsonCheck struct {
+	path   *JSONPath
+	want   string  // Expected value as JSON text, strings unquoted
+	number float64 // Expected value when isNumber
+	isNum  bool
+}
+
+// compileExpectJSON compiles the expect_json paths in path order
+func (a *Action) compileExpectJSON() error {
+	a.jsonChecks = a.jsonChecks[:0]
+	for expr, value := range a.ExpectJSON {
+		path, err := CompileJSONPath(expr)
+		if err != nil {
+			return fmt.Errorf("expect_json: %w", err)
+		}
+		check := jsonCheck{path: path, want: jsonText(value)}
+		switch v := value.(type) {
+		case int:
+			check.number, check.isNum = float64(v), true
+		case float64:
+			check.number, check.isNum = v, true
+		}
+		a.jsonChecks = append(a.jsonChecks, check)
+	}
+	sort.Slice(a.jsonChecks, func(i, j int) bool { return a.jsonChecks[i].path.expr < a.jsonChecks[j].path.expr })
+	return nil
+}
+
+// CheckJSON returns an error if the response body is not JSON or a value
+// differs from its expect_json entry
+func (a *Action) CheckJSON(body []byte) error {
+	if len(a.jsonChecks) == 0 {
+		return nil
+	}
+
+	doc, err := (&responseBody{raw: body}).json()
+	if err != nil {
+		return fmt.Errorf("expected body to be JSON for expect_json, got %s", bodySnippet(body))
+	}
+	for _, check := range a.jsonChecks {
+		value, ok := check.path.Lookup(doc)
+		if !ok {
+			return fmt.Errorf("expected body JSON %s to be %q, but it is missing", check.path, check.want)
+		}
+		if !check.matches(value) {
+			return fmt.Errorf("expected body JSON %s to be %q, got %q", check.path, check.want, jsonText(value))
+		}
+	}
+	return nil
+}
+
+// matches reports whether a decoded JSON value is the expected one, or an
+// array containing it. Numbers compare by value, so 1.50 matches 1.5.
+func (c *jsonCheck) matches(value interface{}) bool {
+	if array, ok := value.([]interface{}); ok && !c.wantsArray() {
+		for _, element := range array {
+			if c.matches(element) {
+				return true
+			}
+		}
+		return false
+	}
+	if n, ok := value.(json.Number); ok && c.isNum {
+		got, err := strconv.ParseFloat(n.String(), 64)
+		return err == nil && got == c.number
+	}
+	return jsonText(value) == c.want
+}
+
+// wantsArray reports whether the expected value is itself an array
+func (c *jsonCheck) wantsArray() bool {
+	return len(c.want) > 0 && c.want[0] == '['
+}
+
+// jsonText formats a decoded value like captures do: strings unquoted,
+// everything else as compact JSON
+func jsonText(value interface{}) string {
+	switch v := value.(type) {
+	case string:
+		return v
+	case json.Number:
+		return v.String()
+	default:
+		encoded, _ := json.Marshal(v)
+		return string(encoded)
+	}
+}
@@ -0,0 +1,102 @@
+package script
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"regexp"
+)
+
+// Extractor pulls a single value out of a response body, using either a
+// JSONPath expression for JSON responses or a regular expression for any
+// response. A regex yields its first capture group, or the whole match if it
+// has none.
+type Extractor struct {
+	JSON  string `yaml:"json"`
+	Regex string `yaml:"regex"`
+
+	jsonPath *JSONPath
+	regex    *regexp.Regexp
+}
+
+// compile validates and compiles the extractor's expression
+func (e *Extractor) compile() error {
+	switch {
+	case e.JSON != "" && e.Regex != "":
+		return fmt.Errorf("set either json or regex, not both")
+	case e.JSON != "":
+		path, err := CompileJSONPath(e.JSON)
+		if err != nil {
+			return err
+		}
+		e.jsonPath = path
+	case e.Regex != "":
+		re, err := regexp.Compile(e.Regex)
+		if err != nil {
+			return fmt.Errorf("invalid regex: %w", err)
+		}
+		e.regex = re
+	default:
+		return fmt.Errorf("json or regex is required")
+	}
+	return nil
+}
+
+// Extract returns the extracted value as a string. JSON strings are returned
+// unquoted; numbers, booleans and null as their JSON text, and objects and
+// arrays as compact JSON.
+func (e *Extractor) Extract(body []byte) (string, error) {
+	return e.extract(&responseBody{raw: body})
+}
+
+// responseBody is a response body shared by several extractors, decoded as
+// JSON at most once
+type responseBody struct {
+	raw     []byte
+	doc     interface{}
+	err     error
+	decoded bool
+}
+
+// json returns the body decoded as JSON, with numbers kept as json.Number
+func (b *responseBody) json() (interface{}, error) {
+	if !b.decoded {
+		b.decoded = true
+		decoder := json.NewDecoder(bytes.NewReader(b.raw))
+		decoder.UseNumber()
+		if err := decoder.Decode(&b.doc); err != nil {
+			b.err = fmt.Errorf("response is not valid JSON")
+		}
+	}
+	return b.doc, b.err
+}
+
+// extract runs the extractor against a shared response body
+func (e *Extractor) extract(body *responseBody) (string, error) {
+	if e.regex != nil {
+		matches := e.regex.FindSubmatch(body.raw)
+		if matches == nil {
+			return "", fmt.Errorf("regex %q did not match", e.Regex)
+		}
+		if len(matches) > 1 {
+			return string(matches[1]), nil
+		}
+		return string(matches[0]), nil
+	}
+
+	if e.jsonPath == nil {
+		return "", fmt.Errorf("extractor is not compiled")
+	}
+
+	doc, err := body.json()
+	if err != nil {
+		return "", err
+	}
+
+	value, ok := e.jsonPath.Lookup(doc)
+	if !ok {
+		return "", fmt.Errorf("%s not found", e.jsonPath)
+	}
+
+	return jsonText(value), nil
+}
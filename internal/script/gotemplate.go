@@ -0,0 +1,316 @@
+package script
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/rand"
+	"regexp"
+	"strings"
+	"sync"
+	"text/template"
+	"time"
+)
+
+// Template engines selectable with --template-engine
+const (
+	// EngineSimple expands the built-in {{userId}}-style placeholders
+	EngineSimple = "simple"
+	// EngineGo evaluates URLs, headers and bodies as Go text/templates
+	EngineGo = "go"
+)
+
+// TemplateContext is the data Go templates are executed with
+type TemplateContext struct {
+	UserID   int    // Current user ID (1, 2, 3...)
+	Username string // Username of the user's assigned credential
+	Password string // Password of the user's assigned credential
+	Email    string // The credential's email field, or Username without one
+
+	Cred map[string]string // All fields of the user's assigned credential, e.g. .Cred.apikey
+	Vars map[string]string // Values captured from earlier responses
+
+	// CredentialAt returns the fields of the credential on a 0-based line
+	// of the credentials file; nil without a credentials file
+	CredentialAt func(index int) map[string]string
+}
+
+// Credential returns a field (username, password, email or a field of a
+// JSON or CSV-with-header file) of the credential at index, as in
+// {{.Credential 3 "username"}}
+func (c TemplateContext) Credential(index int, field string) (string, error) {
+	if c.CredentialAt == nil {
+		return "", fmt.Errorf("no credentials file loaded")
+	}
+
+	value, ok := c.CredentialAt(index)[strings.ToLower(field)]
+	if !ok {
+		return "", fmt.Errorf("unknown credential field %q", field)
+	}
+	return value, nil
+}
+
+// templateFuncs are the functions available to Go templates. randInt and
+// pick are rebound to the executing worker's random source by withRand.
+var templateFuncs = template.FuncMap{
+	"randInt": func(min, max int) int {
+		if max <= min {
+			return min
+		}
+		return rand.Intn(max-min+1) + min
+	},
+	"epochms": func() int64 { return time.Now().UnixMilli() },
+	// seq returns 0..n-1 for {{range}} loops
+	"seq": func(n int) []int {
+		s := make([]int, n)
+		for i := range s {
+			s[i] = i
+		}
+		return s
+	},
+	"add": func(a, b int) int { return a + b },
+	// json encodes a value as JSON, e.g. to quote strings inside json_body
+	"json": func(v interface{}) (string, error) {
+		data, err := json.Marshal(v)
+		return string(data), err
+	},
+	"join": strings.Join,
+	// pick returns a random value of a --data list, e.g. {{pick "movies"}}
+	"pick": func(name string) (string, error) {
+		return "", fmt.Errorf("no data list %q: no --data file loaded", name)
+	},
+}
+
+// goPickPattern matches a pick of a constant list name in a Go template
+var goPickPattern = regexp.MustCompile(`\bpick\s+"([^"]*)"`)
+
+// randIntFunc returns a randInt template function drawing from rng, which
+// returns a random integer in [min, max]
+func randIntFunc(rng *rand.Rand) func(min, max int) int {
+	return func(min, max int) int {
+		if max <= min {
+			return min
+		}
+		return rng.Intn(max-min+1) + min
+	}
+}
+
+// pickFunc returns a pick template function drawing from data with rng
+func pickFunc(data *Data, rng *rand.Rand) func(name string) (string, error) {
+	return func(name string) (string, error) {
+		if !data.has(name) {
+			return "", fmt.Errorf("unknown data list %q", name)
+		}
+		return data.pick(name, rng)
+	}
+}
+
+// actionTemplates holds an action's compiled Go templates; nil entries are
+// empty fields
+type actionTemplates struct {
+	url        *template.Template
+	jsonBody   *template.Template
+	body       *template.Template
+	expectBody *template.Template
+	headers    map[string]*template.Template
+
+	multipart map[string]*template.Template // Multipart field values and filenames by their text
+	websocket map[string]*template.Template // WebSocket message texts by their text
+
+	seeded sync.Map // *rand.Rand -> *actionTemplates with randInt bound to it
+}
+
+// withRand returns the templates with randInt and pick drawing from rng,
+// pick from data. Funcs cannot be rebound on templates that may be
+// executing, so each random source gets its own clones, made on first use.
+func (t *actionTemplates) withRand(rng *rand.Rand, data *Data) (*actionTemplates, error) {
+	if bound, ok := t.seeded.Load(rng); ok {
+		return bound.(*actionTemplates), nil
+	}
+
+	funcs := template.FuncMap{"randInt": randIntFunc(rng), "pick": pickFunc(data, rng)}
+	clone := func(tmpl *template.Template) (*template.Template, error) {
+		if tmpl == nil {
+			return nil, nil
+		}
+		cloned, err := tmpl.Clone()
+		if err != nil {
+			return nil, err
+		}
+		return cloned.Funcs(funcs), nil
+	}
+
+	bound := &actionTemplates{
+		headers:   make(map[string]*template.Template, len(t.headers)),
+		multipart: make(map[string]*template.Template, len(t.multipart)),
+		websocket: make(map[string]*template.Template, len(t.websocket)),
+	}
+	var err error
+	if bound.url, err = clone(t.url); err != nil {
+		return nil, err
+	}
+	if bound.jsonBody, err = clone(t.jsonBody); err != nil {
+		return nil, err
+	}
+	if bound.body, err = clone(t.body); err != nil {
+		return nil, err
+	}
+	if bound.expectBody, err = clone(t.expectBody); err != nil {
+		return nil, err
+	}
+	for key, tmpl := range t.headers {
+		if bound.headers[key], err = clone(tmpl); err != nil {
+			return nil, err
+		}
+	}
+	for text, tmpl := range t.multipart {
+		if bound.multipart[text], err = clone(tmpl); err != nil {
+			return nil, err
+		}
+	}
+	for text, tmpl := range t.websocket {
+		if bound.websocket[text], err = clone(tmpl); err != nil {
+			return nil, err
+		}
+	}
+
+	actual, _ := t.seeded.LoadOrStore(rng, bound)
+	return actual.(*actionTemplates), nil
+}
+
+// CompileTemplates parses the URL, headers and bodies of every action as Go
+// templates, so they can be executed with ExecuteTemplates. Call SetData
+// first: picks from lists the data doesn't define fail here.
+func (s *Script) CompileTemplates() error {
+	for i := range s.Actions {
+		if err := s.Actions[i].compileTemplates(); err != nil {
+			return fmt.Errorf("action %q: %w", s.Actions[i].Name, err)
+		}
+	}
+	return nil
+}
+
+// compileTemplates parses the action's templated fields
+func (a *Action) compileTemplates() error {
+	parse := func(field, text string) (*template.Template, error) {
+		if text == "" {
+			return nil, nil
+		}
+		for _, match := range goPickPattern.FindAllStringSubmatch(text, -1) {
+			if !a.data.has(match[1]) {
+				return nil, fmt.Errorf("%s picks from unknown list %q; define it in the --data file", field, match[1])
+			}
+		}
+		tmpl, err := template.New(field).Funcs(templateFuncs).Option("missingkey=error").Parse(text)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s template: %w", field, err)
+		}
+		return tmpl, nil
+	}
+
+	templates := &actionTemplates{
+		headers:   make(map[string]*template.Template),
+		multipart: make(map[string]*template.Template),
+		websocket: make(map[string]*template.Template),
+	}
+	var err error
+	if templates.url, err = parse("url", a.URL); err != nil {
+		return err
+	}
+	if templates.jsonBody, err = parse("json_body", a.JSONBody); err != nil {
+		return err
+	}
+	if templates.body, err = parse("body", a.Body); err != nil {
+		return err
+	}
+	if templates.expectBody, err = parse("expect_body_contains", a.ExpectBodyContains); err != nil {
+		return err
+	}
+	for key, value := range a.Headers {
+		if templates.headers[key], err = parse("header "+key, value); err != nil {
+			return err
+		}
+	}
+	if a.Multipart != nil {
+		for _, text := range a.Multipart.templatedFields() {
+			if templates.multipart[text], err = parse("multipart", text); err != nil {
+				return err
+			}
+		}
+	}
+	if a.WebSocket != nil {
+		for _, text := range a.WebSocket.templatedFields() {
+			if templates.websocket[text], err = parse("websocket message", text); err != nil {
+				return err
+			}
+		}
+	}
+
+	a.templates = templates
+	return nil
+}
+
+// HasGoTemplates reports whether the action's Go templates were compiled
+func (a *Action) HasGoTemplates() bool {
+	return a.templates != nil
+}
+
+// ExecuteTemplates returns a copy of the action with its compiled Go
+// templates executed against data, drawing random values from rng
+func (a *Action) ExecuteTemplates(data TemplateContext, rng *rand.Rand) (Action, error) {
+	templates, err := a.templates.withRand(rng, a.data)
+	if err != nil {
+		return Action{}, err
+	}
+
+	execute := func(tmpl *template.Template) (string, error) {
+		if tmpl == nil {
+			return "", nil
+		}
+		var sb strings.Builder
+		if err := tmpl.Execute(&sb, data); err != nil {
+			return "", err
+		}
+		return sb.String(), nil
+	}
+
+	expanded := *a
+	if expanded.URL, err = execute(templates.url); err != nil {
+		return Action{}, err
+	}
+	if expanded.JSONBody, err = execute(templates.jsonBody); err != nil {
+		return Action{}, err
+	}
+	if expanded.Body, err = execute(templates.body); err != nil {
+		return Action{}, err
+	}
+	if expanded.ExpectBodyContains, err = execute(templates.expectBody); err != nil {
+		return Action{}, err
+	}
+
+	expanded.Headers = make(map[string]string)
+	for key, tmpl := range templates.headers {
+		if expanded.Headers[key], err = execute(tmpl); err != nil {
+			return Action{}, err
+		}
+	}
+
+	if a.Multipart != nil {
+		expanded.Multipart, err = a.Multipart.expand(func(text string) (string, error) {
+			return execute(templates.multipart[text])
+		})
+		if err != nil {
+			return Action{}, err
+		}
+	}
+
+	if a.WebSocket != nil {
+		expanded.WebSocket, err = a.WebSocket.expand(func(text string) (string, error) {
+			return execute(templates.websocket[text])
+		})
+		if err != nil {
+			return Action{}, err
+		}
+	}
+
+	return expanded, nil
+}
@@ -0,0 +1,164 @@
+package script
+
+import (
+	"math/rand"
+	"strings"
+	"testing"
+)
+
+// executeBody compiles body as an action's Go template and executes it
+// against data
+func executeBody(t *testing.T, body string, data TemplateContext) (string, error) {
+	t.Helper()
+	action := Action{Name: "Create", Body: body}
+	if err := action.compileTemplates(); err != nil {
+		t.Fatalf("compileTemplates() error: %v", err)
+	}
+	expanded, err := action.ExecuteTemplates(data, rand.New(rand.NewSource(1)))
+	return expanded.Body, err
+}
+
+func TestExecuteTemplates(t *testing.T) {
+	data := TemplateContext{
+		UserID:   2,
+		Username: "bob",
+		Vars:     map[string]string{"token": "abc", "empty": ""},
+		Cred:     map[string]string{"username": "bob", "tier": "gold"},
+	}
+
+	tests := []struct {
+		name string
+		body string
+		want string
+	}{
+		{"fields", `{{.UserID}}:{{.Username}}`, "2:bob"},
+		{"if true", `{{if eq .UserID 2}}second{{end}}`, "second"},
+		{"if false", `{{if eq .UserID 1}}first{{end}}`, ""},
+		{"else", `{{if eq .UserID 1}}first{{else}}other{{end}}`, "other"},
+		{"else if", `{{if eq .Cred.tier "silver"}}s{{else if eq .Cred.tier "gold"}}g{{else}}-{{end}}`, "g"},
+		{"captured var present", `{{if .Vars.token}}Bearer {{.Vars.token}}{{end}}`, "Bearer abc"},
+		{"captured var empty", `{{if .Vars.empty}}set{{else}}unset{{end}}`, "unset"},
+		{"range over seq", `{{range $i := seq 3}}[{{$i}}]{{end}}`, "[0][1][2]"},
+		{"range with add", `{{range $i := seq 3}}{{if $i}},{{end}}{"id":{{add $i 1}}}{{end}}`, `{"id":1},{"id":2},{"id":3}`},
+		{"range over nothing", `{{range seq 0}}x{{else}}none{{end}}`, "none"},
+		{"range over map", `{{range $k, $v := .Cred}}{{$k}}={{$v}};{{end}}`, "tier=gold;username=bob;"},
+		{"json quoting", `{"name":{{json .Username}}}`, `{"name":"bob"}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := executeBody(t, tt.body, data)
+			if err != nil {
+				t.Fatalf("ExecuteTemplates() error: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("body = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestExecuteTemplatesErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		body    string
+		wantErr string
+	}{
+		{"missing captured var", `{{.Vars.missing}}`, `map has no entry for key "missing"`},
+		{"credential without file", `{{.Credential 0 "username"}}`, "no credentials file loaded"},
+		{"range over a string", `{{range .Username}}x{{end}}`, "range can't iterate"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data := TemplateContext{Username: "bob", Vars: map[string]string{}}
+			_, err := executeBody(t, tt.body, data)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("ExecuteTemplates() = %v, want error containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestCompileTemplatesInvalid(t *testing.T) {
+	action := Action{Name: "Create", Body: `{{if .UserID}}unterminated`}
+	err := action.compileTemplates()
+	if err == nil || !strings.Contains(err.Error(), "invalid body template") {
+		t.Errorf("compileTemplates() = %v, want an invalid body template error", err)
+	}
+}
+
+func TestExecuteTemplatesSeededRandInt(t *testing.T) {
+	action := Action{Name: "Create", Body: `{{range seq 5}}{{randInt 1 1000}} {{end}}`}
+	if err := action.compileTemplates(); err != nil {
+		t.Fatal(err)
+	}
+
+	draw := func(seed int64) string {
+		expanded, err := action.ExecuteTemplates(TemplateContext{}, rand.New(rand.NewSource(seed)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return expanded.Body
+	}
+	if a, b := draw(7), draw(7); a != b {
+		t.Errorf("same seed drew %q and %q", a, b)
+	}
+	if a, b := draw(7), draw(8); a == b {
+		t.Errorf("different seeds both drew %q", a)
+	}
+}
+
+func TestExecuteTemplatesPick(t *testing.T) {
+	data := loadTestData(t, "data.csv", "movie,city\nheat,Berlin\nalien,Lagos\n")
+	s, err := LoadScript(writeFile(t, t.TempDir(), "script.yml", `
+- name: Search
+  method: POST
+  url: http://localhost/search?city={{pick "city"}}
+  json_body: '{"movie": {{json (pick "movie")}}}'
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.SetData(data); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.CompileTemplates(); err != nil {
+		t.Fatalf("CompileTemplates() error: %v", err)
+	}
+
+	expanded, err := s.Actions[0].ExecuteTemplates(TemplateContext{UserID: 1}, rand.New(rand.NewSource(1)))
+	if err != nil {
+		t.Fatalf("ExecuteTemplates() error: %v", err)
+	}
+	if expanded.URL != "http://localhost/search?city=Berlin" && expanded.URL != "http://localhost/search?city=Lagos" {
+		t.Errorf("URL = %q, want a city from the data file", expanded.URL)
+	}
+	if expanded.JSONBody != `{"movie": "heat"}` && expanded.JSONBody != `{"movie": "alien"}` {
+		t.Errorf("json_body = %q, want a movie from the data file", expanded.JSONBody)
+	}
+}
+
+func TestCompileTemplatesUnknownPick(t *testing.T) {
+	data := loadTestData(t, "data.yaml", "movies: [heat]\n")
+	s, err := LoadScript(writeFile(t, t.TempDir(), "script.yml", `
+- name: Search
+  method: GET
+  url: http://localhost/search?q={{pick "films"}}
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.SetData(data); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.CompileTemplates(); err == nil || !strings.Contains(err.Error(), `unknown list "films"`) {
+		t.Errorf("CompileTemplates() = %v, want an unknown list error", err)
+	}
+}
+
+func TestExecuteTemplatesPickWithoutData(t *testing.T) {
+	// A list name computed at run time is only checked when executed
+	_, err := executeBody(t, `{{pick (printf "mov%s" "ies")}}`, TemplateContext{})
+	if err == nil || !strings.Contains(err.Error(), `unknown data list "movies"`) {
+		t.Errorf("ExecuteTemplates() = %v, want an unknown data list error", err)
+	}
+}
@@ -0,0 +1,102 @@
+package script
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// jsonPathStep is one step of a JSONPath: an object key or an array index
+type jsonPathStep struct {
+	key     string
+	index   int
+	isIndex bool
+}
+
+// JSONPath is a compiled subset of JSONPath supporting the root ($), child
+// keys (.key or ['key']) and array indexes ([0], [-1] for the last element)
+type JSONPath struct {
+	expr  string
+	steps []jsonPathStep
+}
+
+// CompileJSONPath parses a JSONPath expression such as $.data.items[0].id
+func CompileJSONPath(expr string) (*JSONPath, error) {
+	if !strings.HasPrefix(expr, "$") {
+		return nil, fmt.Errorf("invalid JSONPath %q: must start with $", expr)
+	}
+
+	path := &JSONPath{expr: expr}
+	rest := expr[1:]
+	for rest != "" {
+		switch rest[0] {
+		case '.':
+			end := strings.IndexAny(rest[1:], ".[")
+			if end == -1 {
+				end = len(rest) - 1
+			}
+			key := rest[1 : end+1]
+			if key == "" {
+				return nil, fmt.Errorf("invalid JSONPath %q: empty key", expr)
+			}
+			path.steps = append(path.steps, jsonPathStep{key: key})
+			rest = rest[end+1:]
+		case '[':
+			end := strings.Index(rest, "]")
+			if end == -1 {
+				return nil, fmt.Errorf("invalid JSONPath %q: unclosed [", expr)
+			}
+			inner := strings.TrimSpace(rest[1:end])
+			if len(inner) >= 2 && (inner[0] == '\'' || inner[0] == '"') && inner[len(inner)-1] == inner[0] {
+				path.steps = append(path.steps, jsonPathStep{key: inner[1 : len(inner)-1]})
+			} else {
+				index, err := strconv.Atoi(inner)
+				if err != nil {
+					return nil, fmt.Errorf("invalid JSONPath %q: bad index [%s]", expr, inner)
+				}
+				path.steps = append(path.steps, jsonPathStep{index: index, isIndex: true})
+			}
+			rest = rest[end+1:]
+		default:
+			return nil, fmt.Errorf("invalid JSONPath %q: unexpected %q", expr, rest[0])
+		}
+	}
+
+	return path, nil
+}
+
+// String returns the original expression
+func (p *JSONPath) String() string {
+	return p.expr
+}
+
+// Lookup evaluates the path against a decoded JSON document
+func (p *JSONPath) Lookup(doc interface{}) (interface{}, bool) {
+	current := doc
+	for _, step := range p.steps {
+		if step.isIndex {
+			array, ok := current.([]interface{})
+			if !ok {
+				return nil, false
+			}
+			index := step.index
+			if index < 0 {
+				index += len(array)
+			}
+			if index < 0 || index >= len(array) {
+				return nil, false
+			}
+			current = array[index]
+			continue
+		}
+
+		object, ok := current.(map[string]interface{})
+		if !ok {
+			return nil, false
+		}
+		if current, ok = object[step.key]; !ok {
+			return nil, false
+		}
+	}
+	return current, true
+}
@@ -0,0 +1,60 @@
+package script
+
+import (
+	"fmt"
+	"math/rand"
+	"sort"
+	"strings"
+)
+
+// weightedMethod is one entry of an action's method_weights
+type weightedMethod struct {
+	method string
+	weight float64
+}
+
+// compileMethodWeights validates method_weights and orders the entries so
+// the pick does not depend on map iteration order
+func (a *Action) compileMethodWeights() error {
+	a.methods = a.methods[:0]
+	a.methodTotal = 0
+	for method, weight := range a.MethodWeights {
+		if strings.TrimSpace(method) == "" {
+			return fmt.Errorf("method_weights has an empty method")
+		}
+		if weight < 0 {
+			return fmt.Errorf("method_weights weight of %s must not be negative", method)
+		}
+		if weight > 0 {
+			a.methods = append(a.methods, weightedMethod{method: strings.ToUpper(method), weight: weight})
+			a.methodTotal += weight
+		}
+	}
+	if len(a.MethodWeights) > 0 && a.methodTotal == 0 {
+		return fmt.Errorf("method_weights needs at least one positive weight")
+	}
+	sort.Slice(a.methods, func(i, j int) bool { return a.methods[i].method < a.methods[j].method })
+	return nil
+}
+
+// HasMethodWeights reports whether the action picks its method per request
+func (a *Action) HasMethodWeights() bool {
+	return len(a.methods) > 0
+}
+
+// PickMethod draws a method from method_weights using rng
+func (a *Action) PickMethod(rng *rand.Rand) string {
+	x := rng.Float64() * a.methodTotal
+	for _, m := range a.methods {
+		if x < m.weight {
+			return m.method
+		}
+		x -= m.weight
+	}
+	return a.methods[len(a.methods)-1].method
+}
+
+// MethodAllowsBody reports whether a request body is sent with method
+func MethodAllowsBody(method string) bool {
+	return method != "GET" && method != "HEAD"
+}
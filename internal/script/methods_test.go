@@ -0,0 +1,94 @@
+package script
+
+import (
+	"math"
+	"math/rand"
+	"strings"
+	"testing"
+)
+
+func TestPickMethodConverges(t *testing.T) {
+	const draws = 100000
+	action := loadAction(t, `
+- name: Items
+  url: http://localhost/items
+  method_weights: {GET: 70, post: 20, DELETE: 10, PATCH: 0}
+`)
+	if !action.HasMethodWeights() {
+		t.Fatal("HasMethodWeights() = false")
+	}
+
+	rng := rand.New(rand.NewSource(1))
+	counts := make(map[string]int)
+	for i := 0; i < draws; i++ {
+		counts[action.PickMethod(rng)]++
+	}
+
+	// Methods are upper-cased and zero weights are never drawn
+	want := map[string]float64{"GET": 0.7, "POST": 0.2, "DELETE": 0.1}
+	for method, count := range counts {
+		share, ok := want[method]
+		if !ok {
+			t.Errorf("drew %s %d times, want it never drawn", method, count)
+			continue
+		}
+		if got := float64(count) / draws; math.Abs(got-share) > 0.01 {
+			t.Errorf("%s drawn %.3f of the time, want %.2f", method, got, share)
+		}
+	}
+	for method := range want {
+		if counts[method] == 0 {
+			t.Errorf("%s never drawn", method)
+		}
+	}
+}
+
+func TestPickMethodSeeded(t *testing.T) {
+	action := loadAction(t, `
+- name: Items
+  url: http://localhost/items
+  method_weights: {GET: 1, POST: 1, PUT: 1}
+`)
+
+	// Entries are sorted when compiled, so a seed gives the same methods
+	// whatever order the map iterates in
+	draw := func() string {
+		rng := rand.New(rand.NewSource(42))
+		var methods []string
+		for i := 0; i < 20; i++ {
+			methods = append(methods, action.PickMethod(rng))
+		}
+		return strings.Join(methods, " ")
+	}
+	first := draw()
+	for i := 0; i < 5; i++ {
+		if again := draw(); again != first {
+			t.Fatalf("same seed drew %q, then %q", first, again)
+		}
+	}
+}
+
+func TestMethodWeightsValidation(t *testing.T) {
+	tests := []struct {
+		name    string
+		weights string
+		wantErr string
+	}{
+		{"negative", `{GET: 1, POST: -1}`, "weight of POST must not be negative"},
+		{"all zero", `{GET: 0, POST: 0}`, "needs at least one positive weight"},
+		{"empty method", `{" ": 1}`, "empty method"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := writeFile(t, t.TempDir(), "script.yml", `
+- name: Items
+  url: http://localhost/items
+  method_weights: `+tt.weights+`
+`)
+			_, err := LoadScript(path)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("LoadScript() = %v, want error containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,202 @@
+package script
+
+import (
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/textproto"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Multipart is a multipart/form-data request body of text fields and files
+type Multipart struct {
+	Fields map[string]string `yaml:"fields"` // Text fields by name, sent in name order
+	Files  []MultipartFile   `yaml:"files"`
+}
+
+// MultipartFile is a file field of a multipart body. The file is streamed
+// from disk on every request rather than held in memory.
+type MultipartFile struct {
+	Field       string `yaml:"field"`        // Form field name
+	Path        string `yaml:"path"`         // File to upload, relative to the script
+	Filename    string `yaml:"filename"`     // Filename sent to the server; defaults to the base name of path
+	ContentType string `yaml:"content_type"` // Defaults to application/octet-stream
+}
+
+// defaultFileContentType is the Content-Type of file parts without one
+const defaultFileContentType = "application/octet-stream"
+
+// quoteEscaper escapes quoted Content-Disposition parameters like
+// mime/multipart does
+var quoteEscaper = strings.NewReplacer("\\", "\\\\", `"`, "\\\"")
+
+// validate checks the multipart fields for configuration mistakes
+func (m *Multipart) validate() error {
+	for i, file := range m.Files {
+		if file.Field == "" {
+			return fmt.Errorf("multipart file %d has no field name", i+1)
+		}
+		if file.Path == "" {
+			return fmt.Errorf("multipart file %s has no path", file.Field)
+		}
+	}
+	return nil
+}
+
+// resolveFiles makes file paths relative to baseDir and checks that every
+// file can be uploaded
+func (m *Multipart) resolveFiles(baseDir string) error {
+	for i := range m.Files {
+		file := &m.Files[i]
+		if !filepath.IsAbs(file.Path) {
+			file.Path = filepath.Join(baseDir, file.Path)
+		}
+		info, err := os.Stat(file.Path)
+		if err != nil {
+			return fmt.Errorf("multipart file %s: %w", file.Field, err)
+		}
+		if !info.Mode().IsRegular() {
+			return fmt.Errorf("multipart file %s: %s is not a regular file", file.Field, file.Path)
+		}
+		if file.Filename == "" {
+			file.Filename = filepath.Base(file.Path)
+		}
+	}
+	return nil
+}
+
+// templatedFields returns the multipart values templates are expanded in:
+// text field values and filenames
+func (m *Multipart) templatedFields() []string {
+	var fields []string
+	for _, value := range m.Fields {
+		fields = append(fields, value)
+	}
+	for _, file := range m.Files {
+		fields = append(fields, file.Filename)
+	}
+	return fields
+}
+
+// expand returns a copy of the multipart body with expand applied to its
+// text field values and filenames
+func (m *Multipart) expand(expand func(string) (string, error)) (*Multipart, error) {
+	expanded := &Multipart{
+		Fields: make(map[string]string, len(m.Fields)),
+		Files:  make([]MultipartFile, len(m.Files)),
+	}
+	var err error
+	for name, value := range m.Fields {
+		if expanded.Fields[name], err = expand(value); err != nil {
+			return nil, err
+		}
+	}
+	for i, file := range m.Files {
+		expanded.Files[i] = file
+		if expanded.Files[i].Filename, err = expand(file.Filename); err != nil {
+			return nil, err
+		}
+	}
+	return expanded, nil
+}
+
+// Map returns a copy of the multipart body with f applied to its text field
+// values and filenames
+func (m *Multipart) Map(f func(string) string) *Multipart {
+	expanded, _ := m.expand(func(s string) (string, error) { return f(s), nil })
+	return expanded
+}
+
+// Open returns a reader streaming the encoded body, with its Content-Type
+// and length. Files are read as the reader is consumed; closing the reader
+// stops the encoding.
+func (m *Multipart) Open() (body io.ReadCloser, contentType string, length int64, err error) {
+	boundary := multipart.NewWriter(io.Discard).Boundary()
+
+	// The length is the encoded size with each file's content replaced by
+	// its size on disk, so the body can be sent with a Content-Length
+	counter := &countingWriter{}
+	sizes := multipart.NewWriter(counter)
+	if err := sizes.SetBoundary(boundary); err != nil {
+		return nil, "", 0, err
+	}
+	err = m.write(sizes, func(_ io.Writer, path string) error {
+		info, err := os.Stat(path)
+		if err != nil {
+			return err
+		}
+		counter.n += info.Size()
+		return nil
+	})
+	if err != nil {
+		return nil, "", 0, fmt.Errorf("multipart body: %w", err)
+	}
+
+	pr, pw := io.Pipe()
+	writer := multipart.NewWriter(pw)
+	if err := writer.SetBoundary(boundary); err != nil {
+		return nil, "", 0, err
+	}
+	go func() {
+		pw.CloseWithError(m.write(writer, copyFile))
+	}()
+	return pr, writer.FormDataContentType(), counter.n, nil
+}
+
+// write encodes the fields in name order, then the files, with content
+// writing each file's content
+func (m *Multipart) write(writer *multipart.Writer, content func(w io.Writer, path string) error) error {
+	names := make([]string, 0, len(m.Fields))
+	for name := range m.Fields {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if err := writer.WriteField(name, m.Fields[name]); err != nil {
+			return err
+		}
+	}
+
+	for _, file := range m.Files {
+		contentType := file.ContentType
+		if contentType == "" {
+			contentType = defaultFileContentType
+		}
+		header := make(textproto.MIMEHeader)
+		header.Set("Content-Disposition", fmt.Sprintf(`form-data; name="%s"; filename="%s"`,
+			quoteEscaper.Replace(file.Field), quoteEscaper.Replace(file.Filename)))
+		header.Set("Content-Type", contentType)
+		part, err := writer.CreatePart(header)
+		if err != nil {
+			return err
+		}
+		if err := content(part, file.Path); err != nil {
+			return err
+		}
+	}
+	return writer.Close()
+}
+
+// copyFile streams the file at path to w
+func copyFile(w io.Writer, path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	_, err = io.Copy(w, f)
+	return err
+}
+
+// countingWriter counts the bytes written to it
+type countingWriter struct {
+	n int64
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	c.n += int64(len(p))
+	return len(p), nil
+}
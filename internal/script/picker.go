@@ -0,0 +1,68 @@
+package script
+
+import (
+	"fmt"
+	"math/rand"
+	"sort"
+)
+
+// Action modes: how a worker walks the script on each iteration
+const (
+	ModeSequential = "sequential" // Run every action in order
+	ModeWeighted   = "weighted"   // Run one action picked by weight
+)
+
+// ActionPicker picks actions at random in proportion to their weight. If no
+// action has a weight, all actions are equally likely; otherwise actions
+// without a weight are never picked.
+type ActionPicker struct {
+	actions    []Action
+	cumulative []float64 // Running weight total up to and including each action
+}
+
+// NewActionPicker creates a picker over actions
+func NewActionPicker(actions []Action) (*ActionPicker, error) {
+	if len(actions) == 0 {
+		return nil, fmt.Errorf("script has no actions to pick from")
+	}
+
+	uniform := true
+	for _, action := range actions {
+		if action.Weight > 0 {
+			uniform = false
+			break
+		}
+	}
+
+	p := &ActionPicker{actions: actions, cumulative: make([]float64, len(actions))}
+	total := 0.0
+	for i, action := range actions {
+		if uniform {
+			total++
+		} else {
+			total += action.Weight
+		}
+		p.cumulative[i] = total
+	}
+	return p, nil
+}
+
+// Pick returns a random action using rng
+func (p *ActionPicker) Pick(rng *rand.Rand) Action {
+	total := p.cumulative[len(p.cumulative)-1]
+	x := rng.Float64() * total
+	i := sort.Search(len(p.cumulative), func(i int) bool { return p.cumulative[i] > x })
+	if i == len(p.actions) {
+		i--
+	}
+	return p.actions[i]
+}
+
+// Share returns the probability of picking the action at index i
+func (p *ActionPicker) Share(i int) float64 {
+	prev := 0.0
+	if i > 0 {
+		prev = p.cumulative[i-1]
+	}
+	return (p.cumulative[i] - prev) / p.cumulative[len(p.cumulative)-1]
+}
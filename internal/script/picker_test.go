@@ -0,0 +1,82 @@
+package script
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+)
+
+// pickShares draws n actions and returns the fraction each name was picked
+func pickShares(p *ActionPicker, rng *rand.Rand, n int) map[string]float64 {
+	counts := make(map[string]int)
+	for i := 0; i < n; i++ {
+		counts[p.Pick(rng).Name]++
+	}
+	shares := make(map[string]float64, len(counts))
+	for name, count := range counts {
+		shares[name] = float64(count) / float64(n)
+	}
+	return shares
+}
+
+func TestActionPickerWeighted(t *testing.T) {
+	actions := []Action{
+		{Name: "Browse", Weight: 6},
+		{Name: "Search", Weight: 3},
+		{Name: "Checkout", Weight: 1},
+		{Name: "Admin"}, // No weight while others have one: never picked
+	}
+	p, err := NewActionPicker(actions)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := map[string]float64{"Browse": 0.6, "Search": 0.3, "Checkout": 0.1, "Admin": 0}
+	shares := pickShares(p, rand.New(rand.NewSource(1)), 100000)
+	for i, action := range actions {
+		if got := p.Share(i); math.Abs(got-want[action.Name]) > 1e-9 {
+			t.Errorf("Share(%d) = %v, want %v", i, got, want[action.Name])
+		}
+		if got := shares[action.Name]; math.Abs(got-want[action.Name]) > 0.01 {
+			t.Errorf("%s picked %.3f of the time, want %.2f", action.Name, got, want[action.Name])
+		}
+	}
+}
+
+func TestActionPickerUniform(t *testing.T) {
+	actions := []Action{{Name: "A"}, {Name: "B"}, {Name: "C"}, {Name: "D"}}
+	p, err := NewActionPicker(actions)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	shares := pickShares(p, rand.New(rand.NewSource(1)), 100000)
+	for i, action := range actions {
+		if got := p.Share(i); got != 0.25 {
+			t.Errorf("Share(%d) = %v, want 0.25", i, got)
+		}
+		if got := shares[action.Name]; math.Abs(got-0.25) > 0.01 {
+			t.Errorf("%s picked %.3f of the time, want 0.25", action.Name, got)
+		}
+	}
+}
+
+func TestActionPickerSeeded(t *testing.T) {
+	p, err := NewActionPicker([]Action{{Name: "A", Weight: 1}, {Name: "B", Weight: 2}, {Name: "C", Weight: 3}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	a, b := rand.New(rand.NewSource(7)), rand.New(rand.NewSource(7))
+	for i := 0; i < 100; i++ {
+		if x, y := p.Pick(a).Name, p.Pick(b).Name; x != y {
+			t.Fatalf("pick %d: %s and %s with the same seed", i, x, y)
+		}
+	}
+}
+
+func TestActionPickerEmpty(t *testing.T) {
+	if _, err := NewActionPicker(nil); err == nil {
+		t.Error("NewActionPicker(nil) succeeded, want error")
+	}
+}
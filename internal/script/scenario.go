@@ -0,0 +1,170 @@
+package script
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Scenario is a named user journey of a script with several scenarios. Each
+// worker runs one scenario for the whole test.
+type Scenario struct {
+	Name    string
+	Weight  float64  // Relative share of users
+	Actions []Action // The scenario's actions, a part of Script.Actions
+}
+
+// scenarioFile is the shape of a script with several scenarios
+type scenarioFile struct {
+	Scenarios []struct {
+		Name    string   `yaml:"name"`
+		Weight  float64  `yaml:"weight"`
+		Actions []Action `yaml:"actions"`
+	} `yaml:"scenarios"`
+}
+
+// scenarioBounds locates a scenario's actions in the script's action list
+type scenarioBounds struct {
+	name       string
+	weight     float64
+	start, end int
+}
+
+// parseScript decodes a script file: either a list of actions, or a mapping
+// with a list of scenarios. Action names of a scenario are prefixed with
+// "<scenario>/" so results stay apart when scenarios share action names.
+func parseScript(data []byte) ([]Action, []scenarioBounds, error) {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, nil, err
+	}
+	if len(doc.Content) == 0 || doc.Content[0].Kind != yaml.MappingNode {
+		var actions []Action
+		if err := yaml.Unmarshal(data, &actions); err != nil {
+			return nil, nil, err
+		}
+		return actions, nil, nil
+	}
+
+	var file scenarioFile
+	if err := doc.Content[0].Decode(&file); err != nil {
+		return nil, nil, err
+	}
+	if len(file.Scenarios) == 0 {
+		return nil, nil, fmt.Errorf("script must be a list of actions or have a scenarios list")
+	}
+
+	var (
+		actions []Action
+		bounds  []scenarioBounds
+		seen    = make(map[string]bool)
+	)
+	for i, scenario := range file.Scenarios {
+		switch {
+		case scenario.Name == "":
+			return nil, nil, fmt.Errorf("scenario %d has no name", i+1)
+		case strings.Contains(scenario.Name, "/"):
+			return nil, nil, fmt.Errorf("scenario name %q must not contain /", scenario.Name)
+		case seen[scenario.Name]:
+			return nil, nil, fmt.Errorf("duplicate scenario name %q", scenario.Name)
+		case scenario.Weight < 0:
+			return nil, nil, fmt.Errorf("scenario %q: weight must not be negative", scenario.Name)
+		case len(scenario.Actions) == 0:
+			return nil, nil, fmt.Errorf("scenario %q has no actions", scenario.Name)
+		}
+		seen[scenario.Name] = true
+
+		weight := scenario.Weight
+		if weight == 0 {
+			weight = 1
+		}
+		b := scenarioBounds{name: scenario.Name, weight: weight, start: len(actions)}
+		for _, action := range scenario.Actions {
+			action.Name = scenario.Name + "/" + action.Name
+			actions = append(actions, action)
+		}
+		b.end = len(actions)
+		bounds = append(bounds, b)
+	}
+	return actions, bounds, nil
+}
+
+// setScenarios points the script's scenarios at their parts of Actions
+func (s *Script) setScenarios(bounds []scenarioBounds) {
+	for _, b := range bounds {
+		s.Scenarios = append(s.Scenarios, Scenario{
+			Name:    b.name,
+			Weight:  b.weight,
+			Actions: s.Actions[b.start:b.end:b.end],
+		})
+	}
+}
+
+// ForScenario returns a script running only the actions of scenario i
+func (s *Script) ForScenario(i int) *Script {
+	return &Script{
+		Actions:    s.Scenarios[i].Actions,
+		Files:      s.Files,
+		MissingEnv: s.MissingEnv,
+	}
+}
+
+// ScenarioAssigner assigns users to scenarios in proportion to their
+// weights. Assignment is deterministic: every user goes to the scenario
+// furthest below its share, so after any number of users each scenario is
+// within one user of its share.
+type ScenarioAssigner struct {
+	scenarios []Scenario
+	total     float64
+	users     []int
+	assigned  int
+	mu        sync.Mutex
+}
+
+// NewScenarioAssigner creates an assigner over the script's scenarios
+func NewScenarioAssigner(scenarios []Scenario) *ScenarioAssigner {
+	a := &ScenarioAssigner{scenarios: scenarios, users: make([]int, len(scenarios))}
+	for _, scenario := range scenarios {
+		a.total += scenario.Weight
+	}
+	return a
+}
+
+// Next assigns the next user and returns the index of its scenario
+func (a *ScenarioAssigner) Next() int {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	a.assigned++
+	best, bestDeficit := 0, 0.0
+	for i, scenario := range a.scenarios {
+		deficit := scenario.Weight/a.total*float64(a.assigned) - float64(a.users[i])
+		if i == 0 || deficit > bestDeficit {
+			best, bestDeficit = i, deficit
+		}
+	}
+	a.users[best]++
+	return best
+}
+
+// Scenarios returns the scenarios users are assigned to
+func (a *ScenarioAssigner) Scenarios() []Scenario {
+	return a.scenarios
+}
+
+// Users returns how many users have been assigned to each scenario
+func (a *ScenarioAssigner) Users() []int {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	users := make([]int, len(a.users))
+	copy(users, a.users)
+	return users
+}
+
+// Share returns the intended fraction of users of scenario i
+func (a *ScenarioAssigner) Share(i int) float64 {
+	return a.scenarios[i].Weight / a.total
+}
@@ -0,0 +1,65 @@
+package script
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/santhosh-tekuri/jsonschema/v5"
+)
+
+// compileSchemas compiles the expect_schema file of every action, resolving
+// relative paths against the script directory. Each schema file is compiled
+// once and shared by all actions referencing it.
+func compileSchemas(actions []Action, baseDir string) error {
+	compiled := make(map[string]*jsonschema.Schema)
+
+	for i := range actions {
+		path := actions[i].ExpectSchema
+		if path == "" {
+			continue
+		}
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(baseDir, path)
+		}
+
+		schema, ok := compiled[path]
+		if !ok {
+			var err error
+			schema, err = jsonschema.Compile(path)
+			if err != nil {
+				return fmt.Errorf("action %q: failed to compile schema: %w", actions[i].Name, err)
+			}
+			compiled[path] = schema
+		}
+		actions[i].schema = schema
+	}
+
+	return nil
+}
+
+// ValidateSchema validates a response body against the action's JSON schema.
+// Bodies that are not valid JSON fail validation.
+func (a *Action) ValidateSchema(body []byte) error {
+	if a.schema == nil {
+		return nil
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	decoder.UseNumber()
+
+	var doc interface{}
+	if err := decoder.Decode(&doc); err != nil {
+		return fmt.Errorf("response is not valid JSON: %v", err)
+	}
+
+	if err := a.schema.Validate(doc); err != nil {
+		// Keep only the first line of the hierarchical validation error
+		msg, _, _ := strings.Cut(err.Error(), "\n")
+		return fmt.Errorf("%s", msg)
+	}
+
+	return nil
+}
@@ -0,0 +1,113 @@
+package script
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const userSchema = `{
+  "type": "object",
+  "required": ["id", "name"],
+  "properties": {
+    "id": {"type": "integer"},
+    "name": {"type": "string"}
+  }
+}`
+
+// writeFile writes content to name in dir and returns its path
+func writeFile(t *testing.T, dir, name, content string) string {
+	t.Helper()
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestValidateSchema(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, dir, "user.json", userSchema)
+
+	actions := []Action{{Name: "GetUser", ExpectSchema: "user.json"}}
+	if err := compileSchemas(actions, dir); err != nil {
+		t.Fatalf("compileSchemas() error: %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		body    string
+		wantErr string // Substring of the error, empty for a pass
+	}{
+		{"valid", `{"id": 7, "name": "Ada"}`, ""},
+		{"extra fields allowed", `{"id": 7, "name": "Ada", "admin": false}`, ""},
+		{"missing field", `{"id": 7}`, "missing properties"},
+		{"wrong type", `{"id": "7", "name": "Ada"}`, "expected integer"},
+		{"not JSON", `<html>oops</html>`, "not valid JSON"},
+		{"empty body", ``, "not valid JSON"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := actions[0].ValidateSchema([]byte(tt.body))
+			switch {
+			case tt.wantErr == "" && err != nil:
+				t.Errorf("ValidateSchema() = %v, want pass", err)
+			case tt.wantErr != "" && err == nil:
+				t.Errorf("ValidateSchema() passed, want error containing %q", tt.wantErr)
+			case tt.wantErr != "" && !strings.Contains(err.Error(), tt.wantErr):
+				t.Errorf("ValidateSchema() = %v, want error containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestValidateSchemaWithoutSchema(t *testing.T) {
+	action := Action{Name: "Home"}
+	if err := action.ValidateSchema([]byte("not json")); err != nil {
+		t.Errorf("ValidateSchema() without expect_schema = %v, want pass", err)
+	}
+}
+
+func TestCompileSchemasSharesCompiledSchema(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, dir, "user.json", userSchema)
+
+	actions := []Action{
+		{Name: "GetUser", ExpectSchema: "user.json"},
+		{Name: "GetMe", ExpectSchema: filepath.Join(dir, "user.json")},
+	}
+	if err := compileSchemas(actions, dir); err != nil {
+		t.Fatalf("compileSchemas() error: %v", err)
+	}
+	if actions[0].schema == nil || actions[0].schema != actions[1].schema {
+		t.Error("actions referencing the same schema file should share one compiled schema")
+	}
+}
+
+func TestCompileSchemasBadSchema(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, dir, "broken.json", `{"type": "object", "properties": `)
+	writeFile(t, dir, "invalid.json", `{"type": "no-such-type"}`)
+
+	tests := []struct {
+		name   string
+		schema string
+	}{
+		{"malformed JSON", "broken.json"},
+		{"invalid schema", "invalid.json"},
+		{"missing file", "missing.json"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			actions := []Action{{Name: "GetUser", ExpectSchema: tt.schema}}
+			err := compileSchemas(actions, dir)
+			if err == nil {
+				t.Fatal("compileSchemas() succeeded, want error")
+			}
+			if !strings.Contains(err.Error(), `action "GetUser"`) {
+				t.Errorf("error %q does not name the action", err)
+			}
+		})
+	}
+}
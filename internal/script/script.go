@@ -4,74 +4,372 @@ import (
 	"fmt"
 	"io/ioutil"
 	"math/rand"
+	"os"
+	"path/filepath"
+	"regexp"
 	"strconv"
 	"strings"
 	"time"
 
-	"gopkg.in/yaml.v3"
+	"github.com/santhosh-tekuri/jsonschema/v5"
 )
 
 // Action represents a single HTTP action in the test script
 type Action struct {
-	Name         string            `yaml:"name"`
-	Method       string            `yaml:"method"`
-	URL          string            `yaml:"url"`
-	JSONBody     string            `yaml:"json_body"`
-	Body         string            `yaml:"body"`
-	Headers      map[string]string `yaml:"headers"`
-	ExpectStatus int               `yaml:"expect_status"`
-	Timeout      string            `yaml:"timeout"`
-	Delay        string            `yaml:"delay"`     // Fixed delay (e.g., "2s", "500ms")
-	DelayMin     string            `yaml:"delay_min"` // Minimum random delay
-	DelayMax     string            `yaml:"delay_max"` // Maximum random delay
+	Name           string            `yaml:"name"`
+	Method         string            `yaml:"method"`
+	URL            string            `yaml:"url"`
+	JSONBody       string            `yaml:"json_body"`
+	Body           string            `yaml:"body"`
+	Headers        map[string]string `yaml:"headers"`
+	ExpectStatus   int               `yaml:"expect_status"`
+	ExpectStatusIn []int             `yaml:"expect_status_in"` // Any of these statuses counts as success
+	Timeout        string            `yaml:"timeout"`
+	InsecureTLS    *bool             `yaml:"insecure_tls"`   // Overrides --insecure-tls for this action
+	Delay          string            `yaml:"delay"`          // Fixed delay (e.g., "2s", "500ms")
+	DelayMin       string            `yaml:"delay_min"`      // Minimum random delay
+	DelayMax       string            `yaml:"delay_max"`      // Maximum random delay
+	DelayDist      string            `yaml:"delay_dist"`     // Think-time distribution: exponential or normal
+	DelayMean      string            `yaml:"delay_mean"`     // Mean delay of delay_dist
+	DelayStddev    string            `yaml:"delay_stddev"`   // Standard deviation of a normal delay_dist
+	ExpectSchema   string            `yaml:"expect_schema"`  // JSON schema file the response must satisfy
+	ParallelGroup  string            `yaml:"parallel_group"` // Adjacent actions in the same group run concurrently
+	Weight         float64           `yaml:"weight"`         // Relative share of traffic, e.g. derived with --weights-from
+
+	ExpectContentType  string `yaml:"expect_content_type"`  // Content-Type the response must start with, e.g. application/json
+	ExpectBodyContains string `yaml:"expect_body_contains"` // Substring the response body must contain; templates are expanded
+	ExpectBodyRegex    string `yaml:"expect_body_regex"`    // Regular expression the response body must match
+
+	ExpectJSON map[string]interface{} `yaml:"expect_json"` // Expected values by JSONPath, e.g. {$.data.status: active}
+
+	BodyFile  string     `yaml:"body_file"` // File loaded into json_body (.json files) or body unless set inline, relative to the script
+	Multipart *Multipart `yaml:"multipart"` // multipart/form-data body of fields and files; replaces body and json_body
+	WebSocket *WebSocket `yaml:"websocket"` // Opens the URL as a WebSocket and exchanges these messages instead of an HTTP request
+
+	MethodWeights map[string]float64 `yaml:"method_weights"` // Picks the method per request, e.g. {GET: 90, HEAD: 10}; overrides method
+
+	RetryIfBodyContains string `yaml:"retry_if_body_contains"` // Retry even 2xx responses whose body contains this
+
+	// Idempotency testing: resend a fraction of requests with a previously
+	// sent idempotency key and expect the original response back
+	ResendFraction    float64 `yaml:"resend_fraction"`
+	IdempotencyHeader string  `yaml:"idempotency_header"` // Defaults to Idempotency-Key
+
+	BodyTransform []string `yaml:"body_transform"` // Unwraps the response body before validation and extraction
+
+	Consistency []ConsistencyCheck   `yaml:"consistency"` // Invariants on values extracted from the response
+	Capture     map[string]Extractor `yaml:"capture"`     // Named values extracted from the response for later actions
+
+	schema    *jsonschema.Schema // Compiled ExpectSchema
+	templates *actionTemplates   // Compiled Go templates (--template-engine go)
+
+	captureOrder []string         // Capture names in evaluation order
+	transforms   []bodyTransform  // Compiled BodyTransform chain
+	timeout      time.Duration    // Parsed Timeout
+	methods      []weightedMethod // Positive MethodWeights sorted by method
+	methodTotal  float64          // Sum of the weights in methods
+	bodyRegex    *regexp.Regexp   // Compiled ExpectBodyRegex
+	jsonChecks   []jsonCheck      // Compiled ExpectJSON in path order
+	delayMean    time.Duration    // Parsed DelayMean
+	delayStddev  time.Duration    // Parsed DelayStddev
+	data         *Data            // Lists for {{pick <name>}}, set by SetData
 }
 
+// Think-time distributions selectable with delay_dist
+const (
+	DelayExponential = "exponential"
+	DelayNormal      = "normal"
+)
+
+// DefaultIdempotencyHeader carries the idempotency key of resent requests
+// unless an action sets idempotency_header
+const DefaultIdempotencyHeader = "Idempotency-Key"
+
 // Script holds the parsed test script
 type Script struct {
-	Actions []Action
+	Actions    []Action
+	Scenarios  []Scenario // Named user journeys; empty for a plain list of actions
+	Files      []string   // Script files the actions were loaded from
+	MissingEnv []string   // Unset variables referenced with {{env}} and no default
+}
+
+// Steps splits the script into execution steps. Adjacent actions sharing a
+// non-empty parallel_group form one step whose actions run concurrently;
+// every other action is a step of its own.
+func (s *Script) Steps() [][]Action {
+	var steps [][]Action
+	for i, action := range s.Actions {
+		if i > 0 && action.ParallelGroup != "" && action.ParallelGroup == s.Actions[i-1].ParallelGroup {
+			steps[len(steps)-1] = append(steps[len(steps)-1], action)
+			continue
+		}
+		steps = append(steps, []Action{action})
+	}
+	return steps
+}
+
+// ActionNames returns the names of the script's actions, followed by the
+// report names of each WebSocket action's awaited messages
+func (s *Script) ActionNames() []string {
+	names := make([]string, 0, len(s.Actions))
+	for _, action := range s.Actions {
+		names = append(names, action.Name)
+		if action.WebSocket != nil {
+			names = append(names, action.WebSocket.metricNames(action.Name)...)
+		}
+	}
+	return names
 }
 
 // LoadScript loads and parses a YAML script file
 func LoadScript(filename string) (*Script, error) {
+	actions, scenarios, missing, err := loadActions(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	script := &Script{Actions: actions, Files: []string{filename}, MissingEnv: missing}
+	script.setScenarios(scenarios)
+	return script, nil
+}
+
+// LoadScriptDir loads every *.yaml/*.yml file in dir in lexical order and
+// concatenates their actions into a single script. Other files are skipped.
+func LoadScriptDir(dir string) (*Script, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read script directory: %w", err)
+	}
+
+	script := &Script{}
+	for _, entry := range entries { // ReadDir returns entries sorted by filename
+		ext := filepath.Ext(entry.Name())
+		if entry.IsDir() || (ext != ".yaml" && ext != ".yml") {
+			continue
+		}
+
+		filename := filepath.Join(dir, entry.Name())
+		actions, scenarios, missing, err := loadActions(filename)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", entry.Name(), err)
+		}
+		if len(scenarios) > 0 {
+			return nil, fmt.Errorf("%s: scenarios are only supported in a single --script file", entry.Name())
+		}
+
+		script.Actions = append(script.Actions, actions...)
+		script.Files = append(script.Files, filename)
+		script.MissingEnv = mergeNames(script.MissingEnv, missing)
+	}
+
+	if len(script.Files) == 0 {
+		return nil, fmt.Errorf("no YAML script files found in %s", dir)
+	}
+
+	return script, nil
+}
+
+// loadActions parses the actions, and scenarios if any, of a single YAML
+// script file after expanding its {{env}} references, and returns the unset
+// variables
+func loadActions(filename string) ([]Action, []scenarioBounds, []string, error) {
 	data, err := ioutil.ReadFile(filename)
 	if err != nil {
-		return nil, fmt.Errorf("failed to read script file: %w", err)
+		return nil, nil, nil, fmt.Errorf("failed to read script file: %w", err)
 	}
+	data, missing := expandEnv(data)
 
-	var actions []Action
-	if err := yaml.Unmarshal(data, &actions); err != nil {
-		return nil, fmt.Errorf("failed to parse YAML: %w", err)
+	actions, scenarios, err := parseScript(data)
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("failed to parse YAML: %w", err)
 	}
 
-	return &Script{Actions: actions}, nil
+	for i := range actions {
+		if err := actions[i].validate(); err != nil {
+			return nil, nil, nil, fmt.Errorf("action %q: %w", actions[i].Name, err)
+		}
+		if err := actions[i].loadFiles(filepath.Dir(filename)); err != nil {
+			return nil, nil, nil, fmt.Errorf("action %q: %w", actions[i].Name, err)
+		}
+	}
+
+	if err := compileSchemas(actions, filepath.Dir(filename)); err != nil {
+		return nil, nil, nil, err
+	}
+
+	return actions, scenarios, missing, nil
 }
 
-// ExpandTemplates replaces template variables in the action
-func (a *Action) ExpandTemplates(userID int) Action {
+// validate checks the action's fields for configuration mistakes
+func (a *Action) validate() error {
+	if a.ExpectStatusIn != nil && len(a.ExpectStatusIn) == 0 {
+		return fmt.Errorf("expect_status_in must list at least one status code")
+	}
+	if a.Timeout != "" {
+		timeout, err := time.ParseDuration(a.Timeout)
+		if err != nil || timeout <= 0 {
+			return fmt.Errorf("invalid timeout %q: expected a positive duration such as 5s", a.Timeout)
+		}
+		a.timeout = timeout
+	}
+	if a.Weight < 0 {
+		return fmt.Errorf("weight must not be negative")
+	}
+	if a.ResendFraction < 0 || a.ResendFraction > 1 {
+		return fmt.Errorf("resend_fraction must be between 0 and 1")
+	}
+	if a.ResendFraction > 0 && a.IdempotencyHeader == "" {
+		a.IdempotencyHeader = DefaultIdempotencyHeader
+	}
+	for i := range a.Consistency {
+		if err := a.Consistency[i].validate(); err != nil {
+			return err
+		}
+	}
+	if err := a.compileTransforms(); err != nil {
+		return err
+	}
+	if err := a.compileMethodWeights(); err != nil {
+		return err
+	}
+	if err := a.validateDelayDist(); err != nil {
+		return err
+	}
+	if err := a.compileExpectBody(); err != nil {
+		return err
+	}
+	if err := a.compileExpectJSON(); err != nil {
+		return err
+	}
+	if a.Multipart != nil {
+		if a.Body != "" || a.JSONBody != "" || a.BodyFile != "" {
+			return fmt.Errorf("multipart cannot be combined with body, json_body or body_file")
+		}
+		if err := a.Multipart.validate(); err != nil {
+			return err
+		}
+	}
+	if a.WebSocket != nil {
+		if a.Body != "" || a.JSONBody != "" || a.BodyFile != "" || a.Multipart != nil || a.MethodWeights != nil {
+			return fmt.Errorf("websocket cannot be combined with body, json_body, body_file, multipart or method_weights")
+		}
+		if a.Method != "" && !strings.EqualFold(a.Method, "GET") {
+			return fmt.Errorf("websocket actions open with GET, not %s", a.Method)
+		}
+		if err := a.WebSocket.validate(); err != nil {
+			return err
+		}
+	}
+	return a.validateCaptures()
+}
+
+// CheckStatus returns an error if code is not an expected status for the
+// action. Both expect_status and every code in expect_status_in are accepted;
+// with neither set any status passes.
+func (a *Action) CheckStatus(code int) error {
+	if a.ExpectStatus == 0 && len(a.ExpectStatusIn) == 0 {
+		return nil
+	}
+	if code == a.ExpectStatus {
+		return nil
+	}
+	for _, expected := range a.ExpectStatusIn {
+		if code == expected {
+			return nil
+		}
+	}
+
+	if len(a.ExpectStatusIn) == 0 {
+		return fmt.Errorf("expected status %d, got %d", a.ExpectStatus, code)
+	}
+
+	expected := a.ExpectStatusIn
+	if a.ExpectStatus > 0 {
+		expected = append([]int{a.ExpectStatus}, expected...)
+	}
+	return fmt.Errorf("expected status in %v, got %d", expected, code)
+}
+
+// CheckContentType returns an error if the response Content-Type header does
+// not start with expect_content_type. The comparison ignores case, so
+// application/json matches "application/json; charset=utf-8".
+func (a *Action) CheckContentType(contentType string) error {
+	if a.ExpectContentType == "" {
+		return nil
+	}
+	if strings.HasPrefix(strings.ToLower(strings.TrimSpace(contentType)), strings.ToLower(a.ExpectContentType)) {
+		return nil
+	}
+	if contentType == "" {
+		return fmt.Errorf("expected content type %s, got none", a.ExpectContentType)
+	}
+	return fmt.Errorf("expected content type %s, got %s", a.ExpectContentType, contentType)
+}
+
+// ExpandTemplates replaces template variables in the action, resolving
+// {{var name}} from the user's captured variables and drawing random values
+// from rng. It fails only if a {{pick}} can't read its data file.
+func (a *Action) ExpandTemplates(userID int, vars map[string]string, rng *rand.Rand) (Action, error) {
 	expanded := *a
 
+	var err error
+	expand := func(s string) string {
+		result, expandErr := expandString(s, userID, vars, a.data, rng)
+		if expandErr != nil && err == nil {
+			err = expandErr
+		}
+		return result
+	}
+
 	// Replace template variables in URL
-	expanded.URL = expandString(a.URL, userID)
+	expanded.URL = expand(a.URL)
 
 	// Replace template variables in JSON body
-	expanded.JSONBody = expandString(a.JSONBody, userID)
+	expanded.JSONBody = expand(a.JSONBody)
 
 	// Replace template variables in body
-	expanded.Body = expandString(a.Body, userID)
+	expanded.Body = expand(a.Body)
 
 	// Replace template variables in headers
 	expanded.Headers = make(map[string]string)
 	for key, value := range a.Headers {
-		expanded.Headers[key] = expandString(value, userID)
+		expanded.Headers[key] = expand(value)
 	}
 
-	return expanded
+	// Replace template variables in the expected body content
+	expanded.ExpectBodyContains = expand(a.ExpectBodyContains)
+
+	// Replace template variables in multipart fields and filenames
+	if a.Multipart != nil {
+		expanded.Multipart = a.Multipart.Map(expand)
+	}
+
+	// Replace template variables in WebSocket messages
+	if a.WebSocket != nil {
+		expanded.WebSocket = a.WebSocket.Map(expand)
+	}
+
+	if err != nil {
+		return Action{}, err
+	}
+	return expanded, nil
 }
 
-// expandString processes template variables in a string
-func expandString(s string, userID int) string {
+// varPattern matches a {{var name}} reference to a captured variable
+var varPattern = regexp.MustCompile(`\{\{var\s+([A-Za-z_][A-Za-z0-9_]*)\}\}`)
+
+// expandString processes template variables in a string. Variables that
+// were never captured expand to an empty string.
+func expandString(s string, userID int, vars map[string]string, lists *Data, rng *rand.Rand) (string, error) {
 	result := s
 
+	// Replace {{var name}} with captured values
+	if strings.Contains(result, "{{var") {
+		result = varPattern.ReplaceAllStringFunc(result, func(match string) string {
+			return vars[varPattern.FindStringSubmatch(match)[1]]
+		})
+	}
+
 	// Replace {{userId}} with the actual user ID
 	result = strings.ReplaceAll(result, "{{userId}}", strconv.Itoa(userID))
 
@@ -100,7 +398,7 @@ func expandString(s string, userID int) string {
 			max, err2 := strconv.Atoi(parts[1])
 
 			if err1 == nil && err2 == nil && max > min {
-				randVal := rand.Intn(max-min+1) + min
+				randVal := rng.Intn(max-min+1) + min
 				result = result[:start] + strconv.Itoa(randVal) + result[end:]
 			} else {
 				// If parsing fails, just remove the template
@@ -112,11 +410,16 @@ func expandString(s string, userID int) string {
 		}
 	}
 
-	// Handle {{pick movies}} - simple implementation that picks from a predefined list
-	movieList := []string{"movie1", "movie2", "movie3", "movie4", "movie5"}
-	if strings.Contains(result, "{{pick movies}}") {
-		picked := movieList[rand.Intn(len(movieList))]
-		result = strings.ReplaceAll(result, "{{pick movies}}", picked)
+	// Replace each {{pick name}} with a random value of the named data list
+	var pickErr error
+	if strings.Contains(result, "{{pick") {
+		result = pickPattern.ReplaceAllStringFunc(result, func(match string) string {
+			value, err := lists.pick(pickPattern.FindStringSubmatch(match)[1], rng)
+			if err != nil && pickErr == nil {
+				pickErr = err
+			}
+			return value
+		})
 	}
 
 	// Handle {{randDelay min max}} - generates random delay in milliseconds
@@ -141,7 +444,7 @@ func expandString(s string, userID int) string {
 			max, err2 := strconv.Atoi(parts[1])
 
 			if err1 == nil && err2 == nil && max > min {
-				randVal := rand.Intn(max-min+1) + min
+				randVal := rng.Intn(max-min+1) + min
 				result = result[:start] + strconv.Itoa(randVal) + result[end:]
 			} else {
 				// If parsing fails, just remove the template
@@ -153,11 +456,46 @@ func expandString(s string, userID int) string {
 		}
 	}
 
-	return result
+	return result, pickErr
+}
+
+// RequestTimeout returns the action's timeout, or 0 to use the default
+func (a *Action) RequestTimeout() time.Duration {
+	return a.timeout
+}
+
+// GetDelay calculates the delay duration for this action, multiplied by
+// scale so all think times can be stretched or compressed uniformly. Random
+// delays are drawn from rng.
+func (a *Action) GetDelay(scale float64, rng *rand.Rand) time.Duration {
+	return time.Duration(float64(a.baseDelay(rng)) * scale)
+}
+
+// MeanDelay returns the expected delay after this action, scaled by scale
+func (a *Action) MeanDelay(scale float64) time.Duration {
+	if a.DelayDist != "" {
+		return time.Duration(float64(a.delayMean) * scale)
+	}
+
+	if a.Delay != "" {
+		if delay, err := time.ParseDuration(a.Delay); err == nil {
+			return time.Duration(float64(delay) * scale)
+		}
+	}
+
+	if a.DelayMin != "" && a.DelayMax != "" {
+		minDelay, err1 := time.ParseDuration(a.DelayMin)
+		maxDelay, err2 := time.ParseDuration(a.DelayMax)
+		if err1 == nil && err2 == nil && maxDelay > minDelay {
+			return time.Duration(float64(minDelay+maxDelay) / 2 * scale)
+		}
+	}
+
+	return 0
 }
 
-// GetDelay calculates the delay duration for this action
-func (a *Action) GetDelay() time.Duration {
+// baseDelay calculates the unscaled delay duration for this action
+func (a *Action) baseDelay(rng *rand.Rand) time.Duration {
 	// If fixed delay is specified, use it
 	if a.Delay != "" {
 		if delay, err := time.ParseDuration(a.Delay); err == nil {
@@ -165,6 +503,18 @@ func (a *Action) GetDelay() time.Duration {
 		}
 	}
 
+	// Sample the think-time distribution; normal samples below zero are
+	// clamped to no delay
+	switch a.DelayDist {
+	case DelayExponential:
+		return time.Duration(rng.ExpFloat64() * float64(a.delayMean))
+	case DelayNormal:
+		if delay := time.Duration(rng.NormFloat64()*float64(a.delayStddev)) + a.delayMean; delay > 0 {
+			return delay
+		}
+		return 0
+	}
+
 	// If random delay range is specified, pick a random value
 	if a.DelayMin != "" && a.DelayMax != "" {
 		minDelay, err1 := time.ParseDuration(a.DelayMin)
@@ -174,7 +524,7 @@ func (a *Action) GetDelay() time.Duration {
 			// Convert to nanoseconds for random calculation
 			minNanos := minDelay.Nanoseconds()
 			maxNanos := maxDelay.Nanoseconds()
-			randomNanos := rand.Int63n(maxNanos-minNanos+1) + minNanos
+			randomNanos := rng.Int63n(maxNanos-minNanos+1) + minNanos
 			return time.Duration(randomNanos)
 		}
 	}
@@ -182,3 +532,39 @@ func (a *Action) GetDelay() time.Duration {
 	// No delay specified
 	return 0
 }
+
+// validateDelayDist checks and parses delay_dist and its parameters
+func (a *Action) validateDelayDist() error {
+	if a.DelayDist == "" {
+		if a.DelayMean != "" || a.DelayStddev != "" {
+			return fmt.Errorf("delay_mean and delay_stddev require delay_dist")
+		}
+		return nil
+	}
+
+	if a.DelayDist != DelayExponential && a.DelayDist != DelayNormal {
+		return fmt.Errorf("invalid delay_dist %q: expected %s or %s", a.DelayDist, DelayExponential, DelayNormal)
+	}
+	if a.Delay != "" || a.DelayMin != "" || a.DelayMax != "" {
+		return fmt.Errorf("delay_dist cannot be combined with delay, delay_min or delay_max")
+	}
+	mean, err := time.ParseDuration(a.DelayMean)
+	if err != nil || mean <= 0 {
+		return fmt.Errorf("delay_dist %s needs a positive delay_mean such as 3s", a.DelayDist)
+	}
+	a.delayMean = mean
+
+	switch a.DelayDist {
+	case DelayExponential:
+		if a.DelayStddev != "" {
+			return fmt.Errorf("delay_stddev only applies to delay_dist %s", DelayNormal)
+		}
+	case DelayNormal:
+		stddev, err := time.ParseDuration(a.DelayStddev)
+		if err != nil || stddev < 0 {
+			return fmt.Errorf("delay_dist %s needs a delay_stddev such as 1s", DelayNormal)
+		}
+		a.delayStddev = stddev
+	}
+	return nil
+}
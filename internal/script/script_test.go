@@ -0,0 +1,173 @@
+package script
+
+import (
+	"math/rand"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCheckStatus(t *testing.T) {
+	tests := []struct {
+		name     string
+		expect   int
+		expectIn []int
+		code     int
+		wantErr  string // The error message, empty for a pass
+	}{
+		{"no expectation", 0, nil, 503, ""},
+		{"single code matches", 200, nil, 200, ""},
+		{"single code mismatch", 200, nil, 201, "expected status 200, got 201"},
+		{"list matches first", 0, []int{200, 404}, 200, ""},
+		{"list matches last", 0, []int{200, 404}, 404, ""},
+		{"list mismatch", 0, []int{200, 404}, 500, "expected status in [200 404], got 500"},
+		{"single code and list, single matches", 201, []int{200, 204}, 201, ""},
+		{"single code and list, list matches", 201, []int{200, 204}, 204, ""},
+		{"single code and list mismatch", 201, []int{200, 204}, 409, "expected status in [201 200 204], got 409"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			action := Action{Name: "Create", ExpectStatus: tt.expect, ExpectStatusIn: tt.expectIn}
+			err := action.CheckStatus(tt.code)
+			switch {
+			case tt.wantErr == "" && err != nil:
+				t.Errorf("CheckStatus(%d) = %v, want pass", tt.code, err)
+			case tt.wantErr != "" && err == nil:
+				t.Errorf("CheckStatus(%d) passed, want %q", tt.code, tt.wantErr)
+			case tt.wantErr != "" && err.Error() != tt.wantErr:
+				t.Errorf("CheckStatus(%d) = %q, want %q", tt.code, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestCheckContentType(t *testing.T) {
+	tests := []struct {
+		name        string
+		expect      string
+		contentType string
+		wantErr     string // The error message, empty for a pass
+	}{
+		{"no expectation", "", "text/html", ""},
+		{"exact match", "application/json", "application/json", ""},
+		{"parameters ignored", "application/json", "application/json; charset=utf-8", ""},
+		{"case insensitive", "application/json", "Application/JSON", ""},
+		{"surrounding whitespace", "application/json", "  application/json ", ""},
+		{"mismatch", "application/json", "text/html; charset=utf-8", "expected content type application/json, got text/html; charset=utf-8"},
+		{"missing header", "application/json", "", "expected content type application/json, got none"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			action := Action{Name: "GetUser", ExpectContentType: tt.expect}
+			err := action.CheckContentType(tt.contentType)
+			switch {
+			case tt.wantErr == "" && err != nil:
+				t.Errorf("CheckContentType(%q) = %v, want pass", tt.contentType, err)
+			case tt.wantErr != "" && err == nil:
+				t.Errorf("CheckContentType(%q) passed, want %q", tt.contentType, tt.wantErr)
+			case tt.wantErr != "" && err.Error() != tt.wantErr:
+				t.Errorf("CheckContentType(%q) = %q, want %q", tt.contentType, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestEmptyExpectStatusIn(t *testing.T) {
+	path := writeFile(t, t.TempDir(), "script.yml", `
+- name: Create
+  method: POST
+  url: http://localhost/items
+  expect_status_in: []
+`)
+	_, err := LoadScript(path)
+	if err == nil || !strings.Contains(err.Error(), "expect_status_in must list at least one status code") {
+		t.Errorf("LoadScript() = %v, want an error for the empty expect_status_in", err)
+	}
+}
+
+// delayActions are one action of each delay kind: fixed, uniform and both
+// distributions
+const delayActions = `
+- name: Fixed
+  method: GET
+  url: http://localhost/
+  delay: 2s
+- name: Uniform
+  method: GET
+  url: http://localhost/
+  delay_min: 1s
+  delay_max: 3s
+- name: Exponential
+  method: GET
+  url: http://localhost/
+  delay_dist: exponential
+  delay_mean: 2s
+- name: Normal
+  method: GET
+  url: http://localhost/
+  delay_dist: normal
+  delay_mean: 2s
+  delay_stddev: 500ms
+`
+
+func TestGetDelayScale(t *testing.T) {
+	s, err := LoadScript(writeFile(t, t.TempDir(), "script.yml", delayActions))
+	if err != nil {
+		t.Fatalf("LoadScript() error: %v", err)
+	}
+
+	for _, action := range s.Actions {
+		for _, scale := range []float64{0, 0.1, 0.5, 1, 2.5} {
+			// The same seed draws the same unscaled delays, so every
+			// scaled delay must be exactly the multiple of its unscaled one
+			scaled := rand.New(rand.NewSource(1))
+			unscaled := rand.New(rand.NewSource(1))
+			for i := 0; i < 100; i++ {
+				base := action.GetDelay(1, unscaled)
+				want := time.Duration(float64(base) * scale)
+				if got := action.GetDelay(scale, scaled); got != want {
+					t.Fatalf("%s: GetDelay(%v) = %s, want %v x %s = %s", action.Name, scale, got, scale, base, want)
+				}
+			}
+
+			if got, want := action.MeanDelay(scale), time.Duration(float64(2*time.Second)*scale); got != want {
+				t.Errorf("%s: MeanDelay(%v) = %s, want %s", action.Name, scale, got, want)
+			}
+		}
+	}
+}
+
+func TestGetDelayRange(t *testing.T) {
+	s, err := LoadScript(writeFile(t, t.TempDir(), "script.yml", delayActions))
+	if err != nil {
+		t.Fatalf("LoadScript() error: %v", err)
+	}
+	rng := rand.New(rand.NewSource(1))
+	const scale = 0.5
+
+	fixed, uniform := s.Actions[0], s.Actions[1]
+	for i := 0; i < 1000; i++ {
+		if got := fixed.GetDelay(scale, rng); got != time.Second {
+			t.Fatalf("Fixed: GetDelay(%v) = %s, want 1s", scale, got)
+		}
+		// delay_min and delay_max are scaled along with the draw
+		if got := uniform.GetDelay(scale, rng); got < 500*time.Millisecond || got > 1500*time.Millisecond {
+			t.Fatalf("Uniform: GetDelay(%v) = %s, want between 500ms and 1.5s", scale, got)
+		}
+	}
+
+	for _, action := range s.Actions[2:] {
+		var sum time.Duration
+		const samples = 10000
+		for i := 0; i < samples; i++ {
+			delay := action.GetDelay(scale, rng)
+			if delay < 0 {
+				t.Fatalf("%s: GetDelay(%v) = %s, want no negative delays", action.Name, scale, delay)
+			}
+			sum += delay
+		}
+		if mean := sum / samples; mean < 950*time.Millisecond || mean > 1050*time.Millisecond {
+			t.Errorf("%s: mean of GetDelay(%v) = %s, want about 1s", action.Name, scale, mean)
+		}
+	}
+}
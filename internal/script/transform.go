@@ -0,0 +1,108 @@
+package script
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// Body transforms, applied in order to a response body before it is
+// validated or values are extracted from it
+const (
+	// TransformStripPrefix removes a literal prefix: "strip-prefix:)]}'"
+	TransformStripPrefix = "strip-prefix"
+	// TransformXSSI removes an anti-XSSI prefix such as )]}' or while(1);
+	TransformXSSI = "xssi"
+	// TransformBase64 decodes a standard or URL-safe base64 body
+	TransformBase64 = "base64-decode"
+	// TransformJSONP unwraps a JSONP callback: cb({...}); becomes {...}
+	TransformJSONP = "jsonp-unwrap"
+)
+
+// xssiPrefixes are the anti-XSSI prefixes removed by the xssi transform
+var xssiPrefixes = []string{")]}',", ")]}'", "while(1);", "for(;;);"}
+
+// jsonpPattern matches a JSONP response: an optional /**/ comment, a
+// callback name, and the payload in parentheses
+var jsonpPattern = regexp.MustCompile(`(?s)^\s*(?:/\*\*/\s*)?[A-Za-z_$][\w$.]*\s*\((.*)\)\s*;?\s*$`)
+
+// bodyTransform rewrites a response body
+type bodyTransform func([]byte) ([]byte, error)
+
+// compileTransforms parses the action's body_transform chain
+func (a *Action) compileTransforms() error {
+	a.transforms = a.transforms[:0]
+	for _, spec := range a.BodyTransform {
+		name, arg, hasArg := strings.Cut(spec, ":")
+
+		var transform bodyTransform
+		switch name {
+		case TransformStripPrefix:
+			if !hasArg || arg == "" {
+				return fmt.Errorf("body_transform %s needs a prefix, e.g. %s:)]}'", name, name)
+			}
+			prefix := []byte(arg)
+			transform = func(body []byte) ([]byte, error) {
+				return bytes.TrimPrefix(body, prefix), nil
+			}
+		case TransformXSSI:
+			transform = stripXSSI
+		case TransformBase64:
+			transform = decodeBase64
+		case TransformJSONP:
+			transform = unwrapJSONP
+		default:
+			return fmt.Errorf("unknown body_transform %q: expected %s:<prefix>, %s, %s or %s",
+				spec, TransformStripPrefix, TransformXSSI, TransformBase64, TransformJSONP)
+		}
+		if hasArg && name != TransformStripPrefix {
+			return fmt.Errorf("body_transform %s takes no argument", name)
+		}
+		a.transforms = append(a.transforms, transform)
+	}
+	return nil
+}
+
+// TransformBody applies the action's body_transform chain to a response body
+func (a *Action) TransformBody(body []byte) ([]byte, error) {
+	for i, transform := range a.transforms {
+		var err error
+		if body, err = transform(body); err != nil {
+			return nil, fmt.Errorf("body_transform %s: %w", a.BodyTransform[i], err)
+		}
+	}
+	return body, nil
+}
+
+// stripXSSI removes a leading anti-XSSI prefix and the whitespace after it
+func stripXSSI(body []byte) ([]byte, error) {
+	trimmed := bytes.TrimLeft(body, " \t\r\n")
+	for _, prefix := range xssiPrefixes {
+		if bytes.HasPrefix(trimmed, []byte(prefix)) {
+			return bytes.TrimLeft(trimmed[len(prefix):], " \t\r\n"), nil
+		}
+	}
+	return body, nil
+}
+
+// decodeBase64 decodes a body in any of the common base64 alphabets
+func decodeBase64(body []byte) ([]byte, error) {
+	text := string(bytes.TrimSpace(body))
+	for _, encoding := range []*base64.Encoding{base64.StdEncoding, base64.URLEncoding, base64.RawStdEncoding, base64.RawURLEncoding} {
+		if decoded, err := encoding.DecodeString(text); err == nil {
+			return decoded, nil
+		}
+	}
+	return nil, fmt.Errorf("body is not valid base64")
+}
+
+// unwrapJSONP returns the payload of a JSONP callback
+func unwrapJSONP(body []byte) ([]byte, error) {
+	matches := jsonpPattern.FindSubmatch(body)
+	if matches == nil {
+		return nil, fmt.Errorf("body is not a JSONP callback")
+	}
+	return bytes.TrimSpace(matches[1]), nil
+}
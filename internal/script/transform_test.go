@@ -0,0 +1,104 @@
+package script
+
+import (
+	"strings"
+	"testing"
+)
+
+// transformBody compiles a body_transform chain and applies it to body
+func transformBody(t *testing.T, chain []string, body string) (string, error) {
+	t.Helper()
+	action := Action{Name: "Feed", BodyTransform: chain}
+	if err := action.compileTransforms(); err != nil {
+		t.Fatalf("compileTransforms(%q) error: %v", chain, err)
+	}
+	out, err := action.TransformBody([]byte(body))
+	return string(out), err
+}
+
+func TestTransforms(t *testing.T) {
+	tests := []struct {
+		name  string
+		chain []string
+		body  string
+		want  string
+	}{
+		{"no transforms", nil, `{"a":1}`, `{"a":1}`},
+
+		{"strip-prefix", []string{"strip-prefix:)]}'"}, ")]}'{\"a\":1}", `{"a":1}`},
+		{"strip-prefix absent", []string{"strip-prefix:)]}'"}, `{"a":1}`, `{"a":1}`},
+		{"strip-prefix only once", []string{"strip-prefix:ab"}, "ababc", "abc"},
+		{"strip-prefix with colon", []string{"strip-prefix:data:"}, "data:{}", "{}"},
+
+		{"xssi angular", []string{"xssi"}, ")]}',\n{\"a\":1}", `{"a":1}`},
+		{"xssi without comma", []string{"xssi"}, ")]}'\n[1,2]", `[1,2]`},
+		{"xssi while", []string{"xssi"}, `while(1);{"a":1}`, `{"a":1}`},
+		{"xssi for", []string{"xssi"}, `for(;;); {"a":1}`, `{"a":1}`},
+		{"xssi after whitespace", []string{"xssi"}, "\r\n  )]}'\n{}", `{}`},
+		{"xssi absent", []string{"xssi"}, ` {"a":")]}'"}`, ` {"a":")]}'"}`},
+
+		{"base64 standard", []string{"base64-decode"}, "eyJhIjoiPz4ifQ==", `{"a":"?>"}`},
+		{"base64 URL-safe", []string{"base64-decode"}, "Pz4_", "?>?"},
+		{"base64 unpadded", []string{"base64-decode"}, "eyJhIjoxfQ", `{"a":1}`},
+		{"base64 trailing newline", []string{"base64-decode"}, "eyJhIjoxfQ==\n", `{"a":1}`},
+
+		{"jsonp", []string{"jsonp-unwrap"}, `callback({"a":1});`, `{"a":1}`},
+		{"jsonp without semicolon", []string{"jsonp-unwrap"}, `cb([1,2])`, `[1,2]`},
+		{"jsonp comment and namespace", []string{"jsonp-unwrap"}, "/**/ jQuery.cb_1 (\n{\"a\":1}\n);\n", `{"a":1}`},
+
+		{"chain in order", []string{"xssi", "base64-decode", "jsonp-unwrap"}, ")]}'\nY2IoeyJhIjoxfSk=", `{"a":1}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := transformBody(t, tt.chain, tt.body)
+			if err != nil {
+				t.Fatalf("TransformBody() error: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("TransformBody(%q) = %q, want %q", tt.body, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTransformErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		chain   []string
+		body    string
+		wantErr string
+	}{
+		{"invalid base64", []string{"base64-decode"}, "not base64!", "body_transform base64-decode: body is not valid base64"},
+		{"not JSONP", []string{"jsonp-unwrap"}, `{"a":1}`, "body_transform jsonp-unwrap: body is not a JSONP callback"},
+		{"names the failing step", []string{"xssi", "jsonp-unwrap"}, ")]}'\n{}", "body_transform jsonp-unwrap"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := transformBody(t, tt.chain, tt.body)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("TransformBody() = %v, want error containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestCompileTransformsInvalid(t *testing.T) {
+	tests := []struct {
+		spec    string
+		wantErr string
+	}{
+		{"strip-prefix", "needs a prefix"},
+		{"strip-prefix:", "needs a prefix"},
+		{"xssi:)]}'", "takes no argument"},
+		{"gunzip", `unknown body_transform "gunzip"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.spec, func(t *testing.T) {
+			action := Action{Name: "Feed", BodyTransform: []string{tt.spec}}
+			err := action.compileTransforms()
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("compileTransforms(%q) = %v, want error containing %q", tt.spec, err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,108 @@
+package script
+
+import (
+	"fmt"
+	"strings"
+)
+
+// WebSocket is the message exchange of a WebSocket action. The action's URL
+// (ws://, wss://, or http(s):// upgraded to them) is opened once per
+// iteration and the messages are exchanged in order on that connection.
+type WebSocket struct {
+	Messages []WebSocketMessage `yaml:"messages"`
+}
+
+// WebSocketMessage is one step of a WebSocket exchange: a text message sent,
+// a reply awaited, or both
+type WebSocketMessage struct {
+	Name   string `yaml:"name"`   // Reported as <action>/<name>; defaults to msg<N>
+	Send   string `yaml:"send"`   // Text message to send; templates are expanded
+	Expect string `yaml:"expect"` // Substring of the reply to wait for; templates are expanded
+}
+
+// validate checks the exchange for configuration mistakes and names unnamed
+// messages after their position
+func (ws *WebSocket) validate() error {
+	if len(ws.Messages) == 0 {
+		return fmt.Errorf("websocket must list at least one message")
+	}
+
+	seen := make(map[string]bool, len(ws.Messages))
+	for i := range ws.Messages {
+		msg := &ws.Messages[i]
+		if msg.Send == "" && msg.Expect == "" {
+			return fmt.Errorf("websocket message %d needs send, expect or both", i+1)
+		}
+		if msg.Name == "" {
+			msg.Name = fmt.Sprintf("msg%d", i+1)
+		}
+		if strings.Contains(msg.Name, "/") {
+			return fmt.Errorf("websocket message name %q must not contain /", msg.Name)
+		}
+		if seen[msg.Name] {
+			return fmt.Errorf("duplicate websocket message name %q", msg.Name)
+		}
+		seen[msg.Name] = true
+	}
+	return nil
+}
+
+// MessageMetricName returns the name the round trip of an awaited message is
+// reported under, keeping it apart from the action's connection time
+func MessageMetricName(action string, msg WebSocketMessage) string {
+	return action + "/" + msg.Name
+}
+
+// metricNames returns the report names of the awaited messages
+func (ws *WebSocket) metricNames(action string) []string {
+	var names []string
+	for _, msg := range ws.Messages {
+		if msg.Expect != "" {
+			names = append(names, MessageMetricName(action, msg))
+		}
+	}
+	return names
+}
+
+// templatedFields returns the message texts templates are expanded in
+func (ws *WebSocket) templatedFields() []string {
+	var fields []string
+	for _, msg := range ws.Messages {
+		fields = append(fields, msg.Send, msg.Expect)
+	}
+	return fields
+}
+
+// expand returns a copy of the exchange with expand applied to the sent and
+// expected texts of its messages
+func (ws *WebSocket) expand(expand func(string) (string, error)) (*WebSocket, error) {
+	expanded := &WebSocket{Messages: make([]WebSocketMessage, len(ws.Messages))}
+	var err error
+	for i, msg := range ws.Messages {
+		expanded.Messages[i] = msg
+		if expanded.Messages[i].Send, err = expand(msg.Send); err != nil {
+			return nil, err
+		}
+		if expanded.Messages[i].Expect, err = expand(msg.Expect); err != nil {
+			return nil, err
+		}
+	}
+	return expanded, nil
+}
+
+// Map returns a copy of the exchange with f applied to the sent and expected
+// texts of its messages
+func (ws *WebSocket) Map(f func(string) string) *WebSocket {
+	expanded, _ := ws.expand(func(s string) (string, error) { return f(s), nil })
+	return expanded
+}
+
+// HasWebSocket reports whether any action of the script is a WebSocket action
+func (s *Script) HasWebSocket() bool {
+	for _, action := range s.Actions {
+		if action.WebSocket != nil {
+			return true
+		}
+	}
+	return false
+}
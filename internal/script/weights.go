@@ -0,0 +1,165 @@
+package script
+
+import (
+	"bufio"
+	"fmt"
+	"net/url"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// DerivedWeight is an action's weight derived from a traffic log
+type DerivedWeight struct {
+	Action   string
+	Requests int64   // Log requests matched to the action
+	Weight   float64 // Percentage of all matched requests
+}
+
+// WeightReport summarizes how a traffic log mapped onto the script
+type WeightReport struct {
+	Weights   []DerivedWeight // In script order
+	Total     int64           // Requests read from the log
+	Unmatched int64           // Requests matching no action
+}
+
+// accessLogRequest matches the request line of nginx/Apache common and
+// combined log formats: "GET /path HTTP/1.1"
+var accessLogRequest = regexp.MustCompile(`"([A-Z]+) (\S+) HTTP/[0-9.]+"`)
+
+// placeholderPattern matches a template placeholder in an action URL
+var placeholderPattern = regexp.MustCompile(`\{\{[^}]*\}\}`)
+
+// DeriveWeights sets each action's Weight from the share of requests it
+// received in a traffic log. The log is either an nginx/Apache access log or
+// a CSV of "endpoint,count" lines, where endpoint is a path optionally
+// prefixed by a method ("GET /users/42,1200"). Log paths are matched against
+// action URL paths, with each template placeholder matching one path
+// segment; a request is counted for the first matching action.
+func (s *Script) DeriveWeights(filename string) (*WeightReport, error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open traffic log: %w", err)
+	}
+	defer file.Close()
+
+	patterns := make([]*regexp.Regexp, len(s.Actions))
+	for i, action := range s.Actions {
+		patterns[i] = actionPathPattern(action.URL)
+	}
+
+	report := &WeightReport{Weights: make([]DerivedWeight, len(s.Actions))}
+	for i, action := range s.Actions {
+		report.Weights[i].Action = action.Name
+	}
+
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	lineNum := 0
+
+	for scanner.Scan() {
+		lineNum++
+		line := strings.TrimSpace(scanner.Text())
+
+		// Skip empty lines and comments
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		method, path, count, ok := parseTrafficLine(line)
+		if !ok {
+			if lineNum == 1 {
+				continue // CSV header
+			}
+			return nil, fmt.Errorf("unrecognized traffic log line %d: %q", lineNum, line)
+		}
+
+		report.Total += count
+		matched := false
+		for i, action := range s.Actions {
+			if method != "" && !strings.EqualFold(method, actionMethod(action)) {
+				continue
+			}
+			if patterns[i].MatchString(path) {
+				report.Weights[i].Requests += count
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			report.Unmatched += count
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("error reading traffic log: %w", err)
+	}
+
+	matchedTotal := report.Total - report.Unmatched
+	if matchedTotal == 0 {
+		return nil, fmt.Errorf("no requests in %s matched any action", filename)
+	}
+
+	for i := range report.Weights {
+		report.Weights[i].Weight = 100 * float64(report.Weights[i].Requests) / float64(matchedTotal)
+		s.Actions[i].Weight = report.Weights[i].Weight
+	}
+
+	return report, nil
+}
+
+// actionMethod returns the action's method, which defaults to GET
+func actionMethod(action Action) string {
+	if action.Method == "" {
+		return "GET"
+	}
+	return action.Method
+}
+
+// parseTrafficLine parses an access log line or an "endpoint,count" CSV line
+func parseTrafficLine(line string) (method, path string, count int64, ok bool) {
+	if m := accessLogRequest.FindStringSubmatch(line); m != nil {
+		return m[1], stripQuery(m[2]), 1, true
+	}
+
+	endpoint, countStr, found := strings.Cut(line, ",")
+	if !found {
+		return "", "", 0, false
+	}
+	count, err := strconv.ParseInt(strings.TrimSpace(countStr), 10, 64)
+	if err != nil || count < 0 {
+		return "", "", 0, false
+	}
+
+	endpoint = strings.TrimSpace(endpoint)
+	if m, p, found := strings.Cut(endpoint, " "); found {
+		method, endpoint = m, strings.TrimSpace(p)
+	}
+	return method, stripQuery(endpoint), count, true
+}
+
+// stripQuery removes the query string of a request path
+func stripQuery(path string) string {
+	path, _, _ = strings.Cut(path, "?")
+	return path
+}
+
+// actionPathPattern turns the path of an action URL into a regexp matching
+// log paths, with each placeholder matching a single path segment
+func actionPathPattern(rawURL string) *regexp.Regexp {
+	// Protect placeholders from URL parsing and regexp quoting
+	const marker = "STAMPEDEPLACEHOLDER"
+	path := placeholderPattern.ReplaceAllString(rawURL, marker)
+	if u, err := url.Parse(path); err == nil && u.Host != "" {
+		path = u.Path
+	}
+	path = stripQuery(path)
+	if path == "" {
+		path = "/"
+	}
+
+	quoted := regexp.QuoteMeta(path)
+	quoted = strings.ReplaceAll(quoted, marker, `[^/]+`)
+	return regexp.MustCompile("^" + quoted + "/?$")
+}
@@ -0,0 +1,128 @@
+package util
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// BearerProvider supplies one bearer token shared by all users, optionally
+// replacing it from a refresh endpoint once the server rejects it.
+// Usernames and passwords come from an optional credentials file.
+type BearerProvider struct {
+	refreshURL string
+	field      []string // Path of the token in the refresh response, e.g. [data token]
+	client     *http.Client
+	base       *CredentialsManager // Supplies usernames and passwords, nil without a credentials file
+
+	// mu serializes refreshes, so users that were rejected together wait for
+	// a single refresh instead of each sending one
+	mu        sync.Mutex
+	token     string
+	rejected  bool // The server rejected token; refresh before the next use
+	refreshes atomic.Int64
+}
+
+// NewBearerProvider creates a provider starting with token. Without a
+// refreshURL the token is never replaced; otherwise field is the dotted
+// path of the new token in the endpoint's JSON response, e.g. access_token
+// or data.token. client sends the refresh requests; base may be nil.
+func NewBearerProvider(token, refreshURL, field string, client *http.Client, base *CredentialsManager) *BearerProvider {
+	return &BearerProvider{
+		refreshURL: refreshURL,
+		field:      strings.Split(field, "."),
+		client:     client,
+		base:       base,
+		token:      token,
+	}
+}
+
+// Credentials returns the user's credentials with the current token,
+// refreshing it first if it was rejected or there is none yet
+func (p *BearerProvider) Credentials(ctx context.Context, userID int) (Credentials, error) {
+	var creds Credentials
+	if p.base != nil {
+		creds = p.base.GetCredentialsForUser(userID)
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.refreshURL != "" && (p.token == "" || p.rejected) {
+		token, err := p.refresh(ctx)
+		if err != nil {
+			return Credentials{}, err
+		}
+		p.token, p.rejected = token, false
+	}
+
+	creds.Token = p.token
+	return creds, nil
+}
+
+// Invalidate marks token as rejected so the next request refreshes it. A
+// token that was already replaced is ignored, so a burst of 401s for the
+// same token leads to one refresh.
+func (p *BearerProvider) Invalidate(_ int, token string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if token == p.token {
+		p.rejected = true
+	}
+}
+
+// Refreshes returns the number of refresh requests sent
+func (p *BearerProvider) Refreshes() int64 {
+	return p.refreshes.Load()
+}
+
+// refresh requests a new token, authenticating with the current one
+func (p *BearerProvider) refresh(ctx context.Context) (string, error) {
+	req, err := http.NewRequestWithContext(ctx, "POST", p.refreshURL, nil)
+	if err != nil {
+		return "", fmt.Errorf("failed to create refresh request: %w", err)
+	}
+	req.Header.Set("Accept", "application/json")
+	if p.token != "" {
+		req.Header.Set("Authorization", "Bearer "+p.token)
+	}
+
+	p.refreshes.Add(1)
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("token refresh failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", fmt.Errorf("failed to read refresh response: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("token refresh endpoint returned status %d", resp.StatusCode)
+	}
+
+	var doc interface{}
+	if err := json.Unmarshal(body, &doc); err != nil {
+		return "", fmt.Errorf("failed to parse refresh response: %w", err)
+	}
+	for _, key := range p.field {
+		object, ok := doc.(map[string]interface{})
+		if !ok {
+			doc = nil
+			break
+		}
+		doc = object[key]
+	}
+	token, ok := doc.(string)
+	if !ok || token == "" {
+		return "", fmt.Errorf("refresh response has no %s string", strings.Join(p.field, "."))
+	}
+	return token, nil
+}
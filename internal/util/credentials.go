@@ -2,107 +2,428 @@ package util
 
 import (
 	"bufio"
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
 	"fmt"
+	"io"
+	"log"
 	"os"
 	"strings"
 	"sync"
 )
 
-// Credentials represents a username/password pair
+// Credentials represents a username/password pair, and the access token of
+// providers that issue one
 type Credentials struct {
 	Username string
 	Password string
+	Token    string            // Bearer token sent with every request, if any
+	Extra    map[string]string // Other fields of JSON and CSV-with-header files, keyed by lowercase name
+}
+
+// Field returns a field of the credentials by name: username, password,
+// email (the username unless the file has an email field) or an extra field.
+// Unknown fields are empty.
+func (c Credentials) Field(name string) string {
+	switch name {
+	case "username":
+		return c.Username
+	case "password":
+		return c.Password
+	}
+	if value, ok := c.Extra[name]; ok {
+		return value
+	}
+	if name == "email" {
+		return c.Username
+	}
+	return ""
+}
+
+// Fields returns all fields of the credentials by name, as Field
+func (c Credentials) Fields() map[string]string {
+	fields := map[string]string{"username": c.Username, "password": c.Password, "email": c.Username}
+	for name, value := range c.Extra {
+		fields[name] = value
+	}
+	return fields
+}
+
+// CredentialProvider supplies the credentials each user authenticates with.
+// Implementations must be safe for concurrent use.
+type CredentialProvider interface {
+	// Credentials returns the current credentials of a user
+	Credentials(ctx context.Context, userID int) (Credentials, error)
+
+	// Invalidate discards a user's token after the server rejected it.
+	// Tokens that were already replaced are ignored.
+	Invalidate(userID int, token string)
 }
 
 // CredentialsManager handles loading and round-robin assignment of credentials
 type CredentialsManager struct {
 	credentials []Credentials
-	mu          sync.Mutex
+	mu          sync.Mutex // Guards current
 	current     int
+
+	// Streaming mode: the file stays open and each lookup reads its line
+	file   *os.File
+	lines  []lineSpan
+	header []string // Lowercased column names of a CSV file with a header row
+
+	readErrMu sync.Mutex // Guards readErr, taken only when a read fails
+	readErr   error      // First failed streaming read, see Err
 }
 
-// LoadCredentials loads credentials from a file
+// lineSpan locates a credentials line in the file
+type lineSpan struct {
+	offset int64
+	length int32
+	line   int32 // 1-based line number, counting the header, comments and blank lines
+}
+
+// LoadCredentials loads credentials from a file. Three formats are
+// accepted: username,password lines, a CSV file whose header row names a
+// username or email column, and a JSON array of objects. Columns and keys
+// other than username and password end up in Credentials.Extra.
 func LoadCredentials(filepath string) (*CredentialsManager, error) {
-	file, err := os.Open(filepath)
+	data, err := os.ReadFile(filepath)
 	if err != nil {
 		return nil, fmt.Errorf("failed to open credentials file: %w", err)
 	}
-	defer file.Close()
 
 	var credentials []Credentials
-	scanner := bufio.NewScanner(file)
-	lineNum := 0
-
-	for scanner.Scan() {
-		lineNum++
-		line := strings.TrimSpace(scanner.Text())
+	if isJSONArray(data) {
+		if credentials, err = parseCredentialsJSON(data); err != nil {
+			return nil, err
+		}
+	} else {
+		var header []string
+		scanner := bufio.NewScanner(bytes.NewReader(data))
+		lineNum := 0
 
-		// Skip empty lines and comments
-		if line == "" || strings.HasPrefix(line, "#") {
-			continue
+		for scanner.Scan() {
+			lineNum++
+			if header == nil && credentials == nil {
+				if header = parseCredentialsHeader(scanner.Text()); header != nil {
+					continue
+				}
+			}
+			creds, ok, err := parseCredentialsLine(scanner.Text(), lineNum, header)
+			if err != nil {
+				return nil, err
+			}
+			if ok {
+				credentials = append(credentials, creds)
+			}
 		}
 
-		// Parse username,password format
-		parts := strings.Split(line, ",")
-		if len(parts) != 2 {
-			return nil, fmt.Errorf("invalid credentials format on line %d: expected 'username,password', got '%s'", lineNum, line)
+		if err := scanner.Err(); err != nil {
+			return nil, fmt.Errorf("error reading credentials file: %w", err)
 		}
+	}
 
-		username := strings.TrimSpace(parts[0])
-		password := strings.TrimSpace(parts[1])
+	if len(credentials) == 0 {
+		return nil, fmt.Errorf("no valid credentials found in file")
+	}
 
-		if username == "" || password == "" {
-			return nil, fmt.Errorf("empty username or password on line %d", lineNum)
-		}
+	return &CredentialsManager{
+		credentials: credentials,
+		current:     0,
+	}, nil
+}
 
-		credentials = append(credentials, Credentials{
-			Username: username,
-			Password: password,
-		})
+// LoadCredentialsStreaming indexes a credentials file without keeping the
+// credentials in memory. Only the offset of each line is kept and lookups
+// read the line from disk, for files with millions of accounts. The file
+// stays open until Close.
+func LoadCredentialsStreaming(filepath string) (*CredentialsManager, error) {
+	file, err := os.Open(filepath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open credentials file: %w", err)
 	}
 
-	if err := scanner.Err(); err != nil {
-		return nil, fmt.Errorf("error reading credentials file: %w", err)
+	var lines []lineSpan
+	var header []string
+	reader := bufio.NewReader(file)
+	offset := int64(0)
+	lineNum := 0
+
+	// JSON can't be read one line at a time
+	if start, _ := reader.Peek(512); isJSONArray(start) {
+		file.Close()
+		return nil, fmt.Errorf("streaming credentials requires a CSV credentials file, not JSON")
 	}
 
-	if len(credentials) == 0 {
+	for {
+		raw, readErr := reader.ReadString('\n')
+		if readErr != nil && readErr != io.EOF {
+			file.Close()
+			return nil, fmt.Errorf("error reading credentials file: %w", readErr)
+		}
+		if raw != "" {
+			lineNum++
+			if header == nil && lines == nil {
+				// The next read returns io.EOF if the header was the last line
+				if header = parseCredentialsHeader(raw); header != nil {
+					offset += int64(len(raw))
+					continue
+				}
+			}
+
+			// Validate every line up front so lookups cannot fail on bad data
+			_, ok, err := parseCredentialsLine(raw, lineNum, header)
+			if err != nil {
+				file.Close()
+				return nil, err
+			}
+			if ok {
+				lines = append(lines, lineSpan{offset: offset, length: int32(len(raw)), line: int32(lineNum)})
+			}
+			offset += int64(len(raw))
+		}
+		if readErr == io.EOF {
+			break
+		}
+	}
+
+	if len(lines) == 0 {
+		file.Close()
 		return nil, fmt.Errorf("no valid credentials found in file")
 	}
 
 	return &CredentialsManager{
-		credentials: credentials,
-		current:     0,
+		file:   file,
+		lines:  lines,
+		header: header,
 	}, nil
 }
 
-// GetCredentials returns the next credentials in round-robin fashion
+// parseCredentialsLine parses a "username,password" line, or a CSV record
+// with the given header. ok is false for empty lines and comments.
+func parseCredentialsLine(line string, lineNum int, header []string) (creds Credentials, ok bool, err error) {
+	line = strings.TrimSpace(line)
+
+	// Skip empty lines and comments
+	if line == "" || strings.HasPrefix(line, "#") {
+		return Credentials{}, false, nil
+	}
+
+	if header != nil {
+		record, err := csv.NewReader(strings.NewReader(line)).Read()
+		if err != nil {
+			return Credentials{}, false, fmt.Errorf("invalid CSV on line %d: %w", lineNum, err)
+		}
+		if len(record) != len(header) {
+			return Credentials{}, false, fmt.Errorf("line %d has %d fields, the header has %d", lineNum, len(record), len(header))
+		}
+		fields := make(map[string]string, len(header))
+		for i, name := range header {
+			fields[name] = strings.TrimSpace(record[i])
+		}
+		creds, err := credentialsFromFields(fields)
+		if err != nil {
+			return Credentials{}, false, fmt.Errorf("%v on line %d", err, lineNum)
+		}
+		return creds, true, nil
+	}
+
+	// Parse username,password format
+	parts := strings.Split(line, ",")
+	if len(parts) != 2 {
+		return Credentials{}, false, fmt.Errorf("invalid credentials format on line %d: expected 'username,password', got '%s'", lineNum, line)
+	}
+
+	username := strings.TrimSpace(parts[0])
+	password := strings.TrimSpace(parts[1])
+
+	if username == "" || password == "" {
+		return Credentials{}, false, fmt.Errorf("empty username or password on line %d", lineNum)
+	}
+
+	return Credentials{Username: username, Password: password}, true, nil
+}
+
+// parseCredentialsHeader returns the lowercased column names of line if it
+// is a CSV header naming a username or email column, and nil otherwise
+func parseCredentialsHeader(line string) []string {
+	line = strings.TrimSpace(line)
+	if line == "" || strings.HasPrefix(line, "#") {
+		return nil
+	}
+
+	record, err := csv.NewReader(strings.NewReader(line)).Read()
+	if err != nil {
+		return nil
+	}
+	isHeader := false
+	for i, name := range record {
+		record[i] = strings.ToLower(strings.TrimSpace(name))
+		if record[i] == "username" || record[i] == "email" {
+			isHeader = true
+		}
+	}
+	if !isHeader {
+		return nil
+	}
+	return record
+}
+
+// parseCredentialsJSON parses a JSON array of objects. Non-string values
+// are kept in their JSON form, e.g. 42 or true.
+func parseCredentialsJSON(data []byte) ([]Credentials, error) {
+	var objects []map[string]interface{}
+	if err := json.Unmarshal(data, &objects); err != nil {
+		return nil, fmt.Errorf("invalid JSON credentials file: %w", err)
+	}
+
+	credentials := make([]Credentials, 0, len(objects))
+	for i, object := range objects {
+		fields := make(map[string]string, len(object))
+		for key, value := range object {
+			if str, ok := value.(string); ok {
+				fields[strings.ToLower(key)] = str
+				continue
+			}
+			encoded, _ := json.Marshal(value)
+			fields[strings.ToLower(key)] = string(encoded)
+		}
+		creds, err := credentialsFromFields(fields)
+		if err != nil {
+			return nil, fmt.Errorf("%v in credential %d", err, i)
+		}
+		credentials = append(credentials, creds)
+	}
+	return credentials, nil
+}
+
+// credentialsFromFields builds credentials from named fields. The username
+// comes from the username field, or from email without one; all fields but
+// username and password are kept as extras.
+func credentialsFromFields(fields map[string]string) (Credentials, error) {
+	creds := Credentials{Username: fields["username"], Password: fields["password"]}
+	if creds.Username == "" {
+		creds.Username = fields["email"]
+	}
+	if creds.Username == "" {
+		return Credentials{}, fmt.Errorf("empty username")
+	}
+
+	for name, value := range fields {
+		if name == "username" || name == "password" {
+			continue
+		}
+		if creds.Extra == nil {
+			creds.Extra = make(map[string]string)
+		}
+		creds.Extra[name] = value
+	}
+	return creds, nil
+}
+
+// isJSONArray reports whether data starts with a JSON array
+func isJSONArray(data []byte) bool {
+	trimmed := bytes.TrimLeft(data, " \t\r\n")
+	return len(trimmed) > 0 && trimmed[0] == '['
+}
+
+// at returns the credentials at index, which must be in range. Lines were
+// validated while indexing, so a streaming lookup only fails on I/O errors.
+// The index is immutable and ReadAt is safe for concurrent use, so lookups
+// take no lock.
+func (cm *CredentialsManager) at(index int) (Credentials, error) {
+	if cm.file == nil {
+		return cm.credentials[index], nil
+	}
+
+	span := cm.lines[index]
+	buf := make([]byte, span.length)
+	if _, err := cm.file.ReadAt(buf, span.offset); err != nil && err != io.EOF {
+		err = fmt.Errorf("failed to read line %d of the credentials file: %w", span.line, err)
+		cm.readErrMu.Lock()
+		if cm.readErr == nil {
+			cm.readErr = err
+			log.Printf("Warning: %v; users get empty credentials", err)
+		}
+		cm.readErrMu.Unlock()
+		return Credentials{}, err
+	}
+	creds, _, err := parseCredentialsLine(string(buf), int(span.line), cm.header)
+	return creds, err
+}
+
+// GetCredentials returns the next credentials in round-robin fashion.
+// Streaming lookups that fail to read return empty credentials; see Err.
 func (cm *CredentialsManager) GetCredentials() Credentials {
 	cm.mu.Lock()
-	defer cm.mu.Unlock()
+	index := cm.current
+	cm.current = (cm.current + 1) % cm.Count()
+	cm.mu.Unlock()
 
-	creds := cm.credentials[cm.current]
-	cm.current = (cm.current + 1) % len(cm.credentials)
+	creds, _ := cm.at(index)
 	return creds
 }
 
-// GetCredentialsForUser returns credentials for a specific user ID
+// GetCredentialsForUser returns credentials for a specific user ID. User
+// IDs start at 1, so user 1 gets the first credentials in the file.
 func (cm *CredentialsManager) GetCredentialsForUser(userID int) Credentials {
-	cm.mu.Lock()
-	defer cm.mu.Unlock()
+	creds, _ := cm.at(cm.userIndex(userID))
+	return creds
+}
+
+// userIndex returns the index of a user's credentials
+func (cm *CredentialsManager) userIndex(userID int) int {
+	n := cm.Count()
+	return ((userID-1)%n + n) % n
+}
 
-	index := userID % len(cm.credentials)
-	return cm.credentials[index]
+// Credentials returns the credentials assigned to a user, as
+// GetCredentialsForUser, failing if a streaming lookup can't read them
+func (cm *CredentialsManager) Credentials(_ context.Context, userID int) (Credentials, error) {
+	return cm.at(cm.userIndex(userID))
+}
+
+// Invalidate does nothing; file credentials don't expire
+func (cm *CredentialsManager) Invalidate(int, string) {}
+
+// GetCredentialsAt returns the credentials at index in the file, wrapping
+// around (in both directions) when index is out of range
+func (cm *CredentialsManager) GetCredentialsAt(index int) Credentials {
+	n := cm.Count()
+	creds, _ := cm.at(((index % n) + n) % n)
+	return creds
+}
+
+// Err returns the first error reading a streaming credentials file, after
+// which the failed lookups returned empty credentials
+func (cm *CredentialsManager) Err() error {
+	cm.readErrMu.Lock()
+	defer cm.readErrMu.Unlock()
+	return cm.readErr
 }
 
 // Count returns the number of available credentials
 func (cm *CredentialsManager) Count() int {
+	if cm.file != nil {
+		return len(cm.lines)
+	}
 	return len(cm.credentials)
 }
 
-// Validate checks if we have enough credentials for the requested number of users
-func (cm *CredentialsManager) Validate(userCount int) error {
-	if userCount > len(cm.credentials) {
-		return fmt.Errorf("requested %d users but only %d credentials available. Users will be assigned credentials in round-robin fashion", userCount, len(cm.credentials))
+// Validate checks that users 1 to lastUserID each get distinct credentials
+func (cm *CredentialsManager) Validate(lastUserID int) error {
+	if lastUserID > cm.Count() {
+		return fmt.Errorf("user IDs up to %d need %d credentials but only %d are available", lastUserID, lastUserID, cm.Count())
 	}
 	return nil
 }
+
+// Close releases the credentials file of a streaming manager
+func (cm *CredentialsManager) Close() error {
+	if cm.file == nil {
+		return nil
+	}
+	return cm.file.Close()
+}
@@ -0,0 +1,176 @@
+package util
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+const credentialsCSV = `username,password,tier
+alice,a1,gold
+# Comments and blank lines are not credentials
+
+bob,b2,silver
+carol,c3,bronze
+`
+
+// loadCredentialModes loads the same credentials file in memory and
+// streaming, so tests can check both modes give the same answers
+func loadCredentialModes(t *testing.T, content string) map[string]*CredentialsManager {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "creds.csv")
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	inMemory, err := LoadCredentials(path)
+	if err != nil {
+		t.Fatalf("LoadCredentials() error: %v", err)
+	}
+	streaming, err := LoadCredentialsStreaming(path)
+	if err != nil {
+		t.Fatalf("LoadCredentialsStreaming() error: %v", err)
+	}
+	t.Cleanup(func() { streaming.Close() })
+	return map[string]*CredentialsManager{"in memory": inMemory, "streaming": streaming}
+}
+
+func TestGetCredentialsAt(t *testing.T) {
+	tests := []struct {
+		name  string
+		index int
+		want  string
+	}{
+		{"first", 0, "alice"},
+		{"indexed", 1, "bob"},
+		{"last", 2, "carol"},
+		{"wraps past the end", 3, "alice"},
+		{"wraps several times", 7, "bob"},
+		{"negative", -1, "carol"},
+		{"negative wraps", -4, "carol"},
+		{"negative multiple of count", -3, "alice"},
+	}
+	for mode, cm := range loadCredentialModes(t, credentialsCSV) {
+		if cm.Count() != 3 {
+			t.Fatalf("%s: Count() = %d, want 3", mode, cm.Count())
+		}
+		for _, tt := range tests {
+			creds := cm.GetCredentialsAt(tt.index)
+			if creds.Username != tt.want {
+				t.Errorf("%s: GetCredentialsAt(%d) = %q, want %q", mode, tt.index, creds.Username, tt.want)
+			}
+		}
+		if creds := cm.GetCredentialsAt(1); creds.Password != "b2" || creds.Field("tier") != "silver" {
+			t.Errorf("%s: GetCredentialsAt(1) = %+v, want bob's password and tier", mode, creds)
+		}
+	}
+}
+
+func TestGetCredentialsForUser(t *testing.T) {
+	for mode, cm := range loadCredentialModes(t, credentialsCSV) {
+		// User IDs start at 1 and wrap around the file
+		for userID, want := range map[int]string{1: "alice", 2: "bob", 3: "carol", 4: "alice", 0: "carol"} {
+			if got := cm.GetCredentialsForUser(userID).Username; got != want {
+				t.Errorf("%s: GetCredentialsForUser(%d) = %q, want %q", mode, userID, got, want)
+			}
+		}
+	}
+}
+
+func TestGetCredentialsRoundRobin(t *testing.T) {
+	for mode, cm := range loadCredentialModes(t, credentialsCSV) {
+		for i, want := range []string{"alice", "bob", "carol", "alice", "bob"} {
+			if got := cm.GetCredentials().Username; got != want {
+				t.Errorf("%s: call %d of GetCredentials() = %q, want %q", mode, i+1, got, want)
+			}
+		}
+	}
+}
+
+func TestStreamingReadError(t *testing.T) {
+	cm := loadCredentialModes(t, credentialsCSV)["streaming"]
+	cm.Close() // Every lookup now fails to read
+
+	if err := cm.Err(); err != nil {
+		t.Fatalf("Err() = %v before any lookup failed", err)
+	}
+
+	// bob is on line 5, after the header, a comment and a blank line
+	if creds := cm.GetCredentialsAt(1); creds.Username != "" {
+		t.Errorf("GetCredentialsAt(1) = %q after a read error, want empty credentials", creds.Username)
+	}
+	if err := cm.Err(); err == nil || !strings.Contains(err.Error(), "failed to read line 5 of the credentials file") {
+		t.Errorf("Err() = %v, want a read error for line 5", err)
+	}
+
+	// Providers report the failure of each lookup
+	if _, err := cm.Credentials(context.Background(), 1); err == nil {
+		t.Error("Credentials() succeeded after a read error, want the error")
+	}
+}
+
+// heapInUse returns the live heap after a collection
+func heapInUse() int64 {
+	runtime.GC()
+	var stats runtime.MemStats
+	runtime.ReadMemStats(&stats)
+	return int64(stats.HeapAlloc)
+}
+
+func TestLoadCredentialsStreamingBoundedMemory(t *testing.T) {
+	if testing.Short() {
+		t.Skip("writes a large credentials file")
+	}
+
+	// 200,000 accounts of about 80 bytes: a 16 MB file
+	const rows = 200000
+	path := filepath.Join(t.TempDir(), "creds.csv")
+	file, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w := bufio.NewWriter(file)
+	fmt.Fprintln(w, "email,password,tier")
+	for i := 0; i < rows; i++ {
+		fmt.Fprintf(w, "user%07d@loadtest.example.com,%s%07d,gold\n", i, strings.Repeat("p", 32), i)
+	}
+	if err := w.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	file.Close()
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	before := heapInUse()
+	cm, err := LoadCredentialsStreaming(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cm.Close()
+	retained := heapInUse() - before
+
+	// The index keeps a 16 byte span per line, not the credentials, which
+	// in memory take several times the file size
+	if limit := info.Size() / 2; retained > limit {
+		t.Errorf("loading a %d byte file retained %d bytes, want at most %d", info.Size(), retained, limit)
+	}
+	if cm.Count() != rows {
+		t.Fatalf("Count() = %d, want %d", cm.Count(), rows)
+	}
+	for _, index := range []int{0, rows / 2, rows - 1} {
+		creds := cm.GetCredentialsAt(index)
+		wantUser := fmt.Sprintf("user%07d@loadtest.example.com", index)
+		wantPassword := fmt.Sprintf("%s%07d", strings.Repeat("p", 32), index)
+		if creds.Username != wantUser || creds.Password != wantPassword || creds.Field("tier") != "gold" {
+			t.Errorf("GetCredentialsAt(%d) = %+v, want %s", index, creds, wantUser)
+		}
+	}
+	t.Logf("%d byte file, %d bytes retained (%.1f bytes per line)", info.Size(), retained, float64(retained)/rows)
+}
@@ -0,0 +1,27 @@
+package util
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+)
+
+// Log formats selectable with --log-format
+const (
+	LogFormatText = "text"
+	LogFormatJSON = "json"
+)
+
+// SetupLogging configures the process-wide logger. The text format keeps
+// the standard log output; the JSON format writes one JSON object per line
+// to stderr, including for messages logged through the log package.
+func SetupLogging(format string) error {
+	switch format {
+	case LogFormatText:
+		return nil
+	case LogFormatJSON:
+		slog.SetDefault(slog.New(slog.NewJSONHandler(os.Stderr, nil)))
+		return nil
+	}
+	return fmt.Errorf("invalid --log-format %q: expected %s or %s", format, LogFormatText, LogFormatJSON)
+}
@@ -0,0 +1,170 @@
+package util
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// OAuthConfig configures an OAuth2 client-credentials token endpoint
+type OAuthConfig struct {
+	TokenURL      string
+	ClientID      string
+	ClientSecret  string
+	Scope         string        // Space-separated scopes to request, if any
+	RefreshMargin time.Duration // Fetch a new token this long before expiry
+}
+
+// OAuthProvider mints an access token per user from an OAuth2
+// client-credentials endpoint, caching it until shortly before it expires.
+// Usernames and passwords come from an optional credentials file.
+type OAuthProvider struct {
+	cfg    OAuthConfig
+	client *http.Client
+	base   *CredentialsManager // Supplies usernames and passwords, nil without a credentials file
+
+	mu      sync.Mutex
+	tokens  map[int]*oauthToken
+	fetches atomic.Int64
+}
+
+// oauthToken is the cached token of one user; mu serializes fetching it so
+// parallel requests of a user don't mint several tokens
+type oauthToken struct {
+	mu      sync.Mutex
+	value   string
+	refresh time.Time // When to fetch a new token
+}
+
+// tokenResponse is the token endpoint's JSON response
+type tokenResponse struct {
+	AccessToken string `json:"access_token"`
+	ExpiresIn   int64  `json:"expires_in"`
+}
+
+// NewOAuthProvider creates an OAuth2 client-credentials provider. client
+// sends the token requests; base may be nil.
+func NewOAuthProvider(cfg OAuthConfig, client *http.Client, base *CredentialsManager) *OAuthProvider {
+	return &OAuthProvider{
+		cfg:    cfg,
+		client: client,
+		base:   base,
+		tokens: make(map[int]*oauthToken),
+	}
+}
+
+// Credentials returns the user's credentials with a valid access token,
+// fetching a new token if the cached one is missing or about to expire
+func (p *OAuthProvider) Credentials(ctx context.Context, userID int) (Credentials, error) {
+	var creds Credentials
+	if p.base != nil {
+		creds = p.base.GetCredentialsForUser(userID)
+	}
+
+	p.mu.Lock()
+	token, ok := p.tokens[userID]
+	if !ok {
+		token = &oauthToken{}
+		p.tokens[userID] = token
+	}
+	p.mu.Unlock()
+
+	token.mu.Lock()
+	defer token.mu.Unlock()
+
+	if token.value == "" || !time.Now().Before(token.refresh) {
+		value, expiresIn, err := p.fetch(ctx)
+		if err != nil {
+			return Credentials{}, err
+		}
+		token.value = value
+		token.refresh = time.Now().Add(expiresIn - p.margin(expiresIn))
+	}
+
+	creds.Token = token.value
+	return creds, nil
+}
+
+// Invalidate discards the user's cached token so the next request fetches a
+// new one, unless a parallel request already replaced it
+func (p *OAuthProvider) Invalidate(userID int, rejected string) {
+	p.mu.Lock()
+	token, ok := p.tokens[userID]
+	p.mu.Unlock()
+	if !ok {
+		return
+	}
+
+	token.mu.Lock()
+	if token.value == rejected {
+		token.value = ""
+	}
+	token.mu.Unlock()
+}
+
+// Fetches returns the number of tokens requested from the endpoint
+func (p *OAuthProvider) Fetches() int64 {
+	return p.fetches.Load()
+}
+
+// margin returns how long before expiry a token is refreshed, at most half
+// its lifetime so short-lived tokens aren't refetched for every request
+func (p *OAuthProvider) margin(expiresIn time.Duration) time.Duration {
+	if p.cfg.RefreshMargin > expiresIn/2 {
+		return expiresIn / 2
+	}
+	return p.cfg.RefreshMargin
+}
+
+// fetch requests a new access token, returning it with its lifetime. Tokens
+// without expires_in are treated as valid for an hour.
+func (p *OAuthProvider) fetch(ctx context.Context) (string, time.Duration, error) {
+	form := url.Values{"grant_type": {"client_credentials"}}
+	if p.cfg.Scope != "" {
+		form.Set("scope", p.cfg.Scope)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", p.cfg.TokenURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", 0, fmt.Errorf("failed to create token request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	req.SetBasicAuth(url.QueryEscape(p.cfg.ClientID), url.QueryEscape(p.cfg.ClientSecret))
+
+	p.fetches.Add(1)
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return "", 0, fmt.Errorf("token request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", 0, fmt.Errorf("failed to read token response: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", 0, fmt.Errorf("token endpoint returned status %d", resp.StatusCode)
+	}
+
+	var token tokenResponse
+	if err := json.Unmarshal(body, &token); err != nil {
+		return "", 0, fmt.Errorf("failed to parse token response: %w", err)
+	}
+	if token.AccessToken == "" {
+		return "", 0, fmt.Errorf("token response has no access_token")
+	}
+
+	expiresIn := time.Hour
+	if token.ExpiresIn > 0 {
+		expiresIn = time.Duration(token.ExpiresIn) * time.Second
+	}
+	return token.AccessToken, expiresIn, nil
+}
@@ -0,0 +1,196 @@
+package util
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync/atomic"
+)
+
+// proxyFailureThreshold is the number of consecutive failed requests after
+// which a proxy is considered unreachable
+const proxyFailureThreshold = 3
+
+// proxy is a single proxy of a ProxyPool
+type proxy struct {
+	url      *url.URL
+	requests atomic.Int64
+	failures atomic.Int64 // Consecutive failed requests
+	down     atomic.Bool
+}
+
+// ProxyStats reports how a proxy was used during the test
+type ProxyStats struct {
+	URL      string `json:"url"`
+	Requests int64  `json:"requests"`
+	Down     bool   `json:"unreachable"`
+}
+
+// ProxyPool assigns proxies to workers round-robin and tracks their health
+type ProxyPool struct {
+	proxies []*proxy
+	drop    bool // Reassign workers away from unreachable proxies
+}
+
+// LoadProxyPool loads proxy URLs, one per line, from a file. Lines without a
+// scheme are treated as http:// proxies. With drop, workers whose proxy
+// becomes unreachable move on to the next reachable one.
+func LoadProxyPool(filename string, drop bool) (*ProxyPool, error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open proxy list: %w", err)
+	}
+	defer file.Close()
+
+	pool := &ProxyPool{drop: drop}
+	scanner := bufio.NewScanner(file)
+	lineNum := 0
+
+	for scanner.Scan() {
+		lineNum++
+		line := strings.TrimSpace(scanner.Text())
+
+		// Skip empty lines and comments
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		proxyURL, err := ParseProxyURL(line)
+		if err != nil {
+			return nil, fmt.Errorf("invalid proxy on line %d: %w", lineNum, err)
+		}
+		pool.proxies = append(pool.proxies, &proxy{url: proxyURL})
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("error reading proxy list: %w", err)
+	}
+
+	if len(pool.proxies) == 0 {
+		return nil, fmt.Errorf("no proxies found in %s", filename)
+	}
+
+	return pool, nil
+}
+
+// ParseProxyURL parses an http, https, socks5 or socks5h proxy URL. A URL
+// without a scheme is treated as an http:// proxy.
+func ParseProxyURL(raw string) (*url.URL, error) {
+	if !strings.Contains(raw, "://") {
+		raw = "http://" + raw
+	}
+	proxyURL, err := url.Parse(raw)
+	if err != nil || proxyURL.Host == "" {
+		return nil, fmt.Errorf("%q is not a proxy URL such as http://host:port", raw)
+	}
+	switch proxyURL.Scheme {
+	case "http", "https", "socks5", "socks5h":
+		return proxyURL, nil
+	}
+	return nil, fmt.Errorf("unsupported proxy scheme %q in %q: use http, https, socks5 or socks5h", proxyURL.Scheme, raw)
+}
+
+// Count returns the number of proxies in the pool
+func (p *ProxyPool) Count() int {
+	return len(p.proxies)
+}
+
+// assign returns the proxy of a worker: its round-robin proxy, or with drop
+// enabled the next one that is still reachable
+func (p *ProxyPool) assign(workerID int) *proxy {
+	start := (workerID - 1) % len(p.proxies)
+	if !p.drop {
+		return p.proxies[start]
+	}
+
+	for i := range p.proxies {
+		candidate := p.proxies[(start+i)%len(p.proxies)]
+		if !candidate.down.Load() {
+			return candidate
+		}
+	}
+	// Every proxy is down; keep using the assigned one
+	return p.proxies[start]
+}
+
+// proxyChoiceKey is the context key of a request's proxyChoice
+type proxyChoiceKey struct{}
+
+// proxyChoice records the proxy ProxyFunc picked for a request
+type proxyChoice struct {
+	proxy atomic.Pointer[proxy]
+}
+
+// WithProxyChoice returns a context that records which proxy a request sent
+// with it goes through, so RecordResult charges that proxy even if the
+// worker has since been reassigned
+func WithProxyChoice(ctx context.Context) context.Context {
+	return context.WithValue(ctx, proxyChoiceKey{}, &proxyChoice{})
+}
+
+// ProxyFunc returns an http.Transport Proxy function for a worker
+func (p *ProxyPool) ProxyFunc(workerID int) func(*http.Request) (*url.URL, error) {
+	return func(req *http.Request) (*url.URL, error) {
+		proxy := p.assign(workerID)
+		proxy.requests.Add(1)
+		if choice, ok := req.Context().Value(proxyChoiceKey{}).(*proxyChoice); ok {
+			choice.proxy.Store(proxy)
+		}
+		return proxy.url, nil
+	}
+}
+
+// RecordResult records the outcome of a request sent with a context from
+// WithProxyChoice against the proxy it went through. Only failing to
+// connect to the proxy counts: errors beyond it, such as target timeouts,
+// say nothing about the proxy. A proxy is marked unreachable after
+// proxyFailureThreshold consecutive failures.
+func (p *ProxyPool) RecordResult(ctx context.Context, err error) {
+	choice, ok := ctx.Value(proxyChoiceKey{}).(*proxyChoice)
+	if !ok {
+		return
+	}
+	proxy := choice.proxy.Load()
+	if proxy == nil {
+		return // The request failed before a proxy was picked
+	}
+	if !isProxyConnectError(err) {
+		proxy.failures.Store(0)
+		return
+	}
+
+	if proxy.failures.Add(1) >= proxyFailureThreshold && proxy.down.CompareAndSwap(false, true) {
+		if p.drop {
+			log.Printf("Proxy %s unreachable, dropping it from rotation: %v", proxy.url.Redacted(), err)
+		} else {
+			log.Printf("Proxy %s appears unreachable: %v", proxy.url.Redacted(), err)
+		}
+	}
+}
+
+// isProxyConnectError reports whether err is a failure to connect to the
+// proxy itself, which http.Transport reports as a proxyconnect error
+func isProxyConnectError(err error) bool {
+	var opErr *net.OpError
+	return errors.As(err, &opErr) && opErr.Op == "proxyconnect"
+}
+
+// Stats returns per-proxy request counts, with credentials redacted
+func (p *ProxyPool) Stats() []ProxyStats {
+	stats := make([]ProxyStats, len(p.proxies))
+	for i, proxy := range p.proxies {
+		stats[i] = ProxyStats{
+			URL:      proxy.url.Redacted(),
+			Requests: proxy.requests.Load(),
+			Down:     proxy.down.Load(),
+		}
+	}
+	return stats
+}
@@ -0,0 +1,126 @@
+package util
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func loadTestProxyPool(t *testing.T, drop bool, proxies ...string) *ProxyPool {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "proxies.txt")
+	if err := os.WriteFile(path, []byte(strings.Join(proxies, "\n")), 0644); err != nil {
+		t.Fatal(err)
+	}
+	pool, err := LoadProxyPool(path, drop)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return pool
+}
+
+// closedAddr returns a local address nothing listens on
+func closedAddr(t *testing.T) string {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := ln.Addr().String()
+	ln.Close()
+	return addr
+}
+
+// sendThroughPool sends a request as a worker and records its result
+func sendThroughPool(t *testing.T, pool *ProxyPool, workerID int, timeout time.Duration) {
+	t.Helper()
+	client := &http.Client{Transport: &http.Transport{Proxy: pool.ProxyFunc(workerID)}}
+	ctx, cancel := context.WithTimeout(WithProxyChoice(context.Background()), timeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, "http://target.invalid/", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := client.Do(req)
+	if err == nil {
+		resp.Body.Close()
+	}
+	pool.RecordResult(req.Context(), err)
+}
+
+func TestProxyPoolCountsOnlyConnectFailures(t *testing.T) {
+	release := make(chan struct{})
+	slow := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release // The target behind the proxy never answers in time
+	}))
+	defer slow.Close()
+	defer close(release)
+
+	pool := loadTestProxyPool(t, true, slow.URL, closedAddr(t))
+	for i := 0; i < proxyFailureThreshold; i++ {
+		sendThroughPool(t, pool, 1, 50*time.Millisecond)
+		sendThroughPool(t, pool, 2, time.Second)
+	}
+
+	stats := pool.Stats()
+	if stats[0].Down {
+		t.Error("proxy whose target timed out was marked unreachable")
+	}
+	if !stats[1].Down {
+		t.Error("proxy refusing connections was not marked unreachable")
+	}
+
+	// With drop, worker 2 moves on to the reachable proxy
+	sendThroughPool(t, pool, 2, 50*time.Millisecond)
+	if got := pool.Stats()[0].Requests; got != proxyFailureThreshold+1 {
+		t.Errorf("reachable proxy got %d requests, want %d", got, proxyFailureThreshold+1)
+	}
+}
+
+func TestProxyPoolChargesChosenProxy(t *testing.T) {
+	pool := loadTestProxyPool(t, true, "first:8080", "second:8080")
+	connectErr := &net.OpError{Op: "proxyconnect", Net: "tcp", Err: errors.New("connection refused")}
+
+	choose := func() context.Context {
+		ctx := WithProxyChoice(context.Background())
+		req := httptest.NewRequest(http.MethodGet, "http://target.invalid/", nil).WithContext(ctx)
+		if _, err := pool.ProxyFunc(1)(req); err != nil {
+			t.Fatal(err)
+		}
+		return ctx
+	}
+
+	// Requests in flight through the first proxy finish after it was dropped
+	inFlight := make([]context.Context, proxyFailureThreshold)
+	for i := range inFlight {
+		inFlight[i] = choose()
+	}
+	for i := 0; i < proxyFailureThreshold; i++ {
+		pool.RecordResult(choose(), connectErr)
+	}
+	for _, ctx := range inFlight {
+		pool.RecordResult(ctx, connectErr)
+	}
+
+	stats := pool.Stats()
+	if !stats[0].Down {
+		t.Error("first proxy was not marked unreachable")
+	}
+	if stats[1].Down {
+		t.Error("failures through the first proxy were charged to the second")
+	}
+
+	// Results of requests sent without a recorded choice are ignored
+	pool.RecordResult(context.Background(), connectErr)
+	pool.RecordResult(WithProxyChoice(context.Background()), connectErr)
+	if pool.Stats()[1].Down {
+		t.Error("result without a proxy choice was charged")
+	}
+}
@@ -1,25 +1,37 @@
 package util
 
 import (
+	"context"
 	"sync"
 	"time"
 )
 
-// RateLimiter implements a token bucket rate limiter
+// RateLimiter implements a token bucket rate limiter. It is safe for
+// concurrent use, so one limiter can pace many workers.
 type RateLimiter struct {
-	rate     int       // tokens per second
-	capacity int       // bucket capacity
-	tokens   int       // current tokens
+	rate     float64   // tokens per second
+	capacity float64   // bucket capacity
+	tokens   float64   // current tokens, fractional between whole tokens
 	lastTime time.Time // last refill time
 	mu       sync.Mutex
 }
 
-// NewRateLimiter creates a new rate limiter
+// NewRateLimiter creates a new rate limiter allowing bursts of up to one
+// second's worth of requests
 func NewRateLimiter(rps int) *RateLimiter {
+	return NewRateLimiterBurst(rps, rps)
+}
+
+// NewRateLimiterBurst creates a rate limiter allowing bursts of at most
+// burst requests
+func NewRateLimiterBurst(rps, burst int) *RateLimiter {
+	if burst < 1 {
+		burst = 1
+	}
 	return &RateLimiter{
-		rate:     rps,
-		capacity: rps,
-		tokens:   rps,
+		rate:     float64(rps),
+		capacity: float64(burst),
+		tokens:   float64(burst),
 		lastTime: time.Now(),
 	}
 }
@@ -29,21 +41,10 @@ func (rl *RateLimiter) Allow() bool {
 	rl.mu.Lock()
 	defer rl.mu.Unlock()
 
-	now := time.Now()
-	elapsed := now.Sub(rl.lastTime)
-
-	// Add tokens based on elapsed time
-	tokensToAdd := int(elapsed.Seconds() * float64(rl.rate))
-	if tokensToAdd > 0 {
-		rl.tokens += tokensToAdd
-		if rl.tokens > rl.capacity {
-			rl.tokens = rl.capacity
-		}
-		rl.lastTime = now
-	}
+	rl.refill()
 
 	// Check if we have tokens available
-	if rl.tokens > 0 {
+	if rl.tokens >= 1 {
 		rl.tokens--
 		return true
 	}
@@ -53,7 +54,67 @@ func (rl *RateLimiter) Allow() bool {
 
 // Wait blocks until a token is available
 func (rl *RateLimiter) Wait() {
-	for !rl.Allow() {
-		time.Sleep(time.Millisecond * 10)
+	rl.WaitContext(context.Background())
+}
+
+// WaitContext blocks until a token is available or ctx is done. Instead of
+// polling, it reserves the next token and sleeps exactly until it is due.
+func (rl *RateLimiter) WaitContext(ctx context.Context) error {
+	delay := rl.reserve()
+	if delay <= 0 {
+		return nil
+	}
+
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		rl.cancel()
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// reserve takes a token, borrowing against future refills when none is
+// available, and returns how long until the borrowed token is due. Waiters
+// queue up behind each other's debt, so each gets its own slot.
+func (rl *RateLimiter) reserve() time.Duration {
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+
+	if rl.rate <= 0 {
+		return 0
+	}
+
+	rl.refill()
+	rl.tokens--
+	if rl.tokens >= 0 {
+		return 0
+	}
+	return time.Duration(-rl.tokens / rl.rate * float64(time.Second))
+}
+
+// cancel returns the token of a reservation that was given up
+func (rl *RateLimiter) cancel() {
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+
+	rl.tokens++
+	if rl.tokens > rl.capacity {
+		rl.tokens = rl.capacity
+	}
+}
+
+// refill adds the tokens accrued since the last refill. Refilling by the
+// exact elapsed time and keeping fractional tokens means frequent calls from
+// many goroutines don't round the refill away. The caller must hold mu.
+func (rl *RateLimiter) refill() {
+	now := time.Now()
+	rl.tokens += now.Sub(rl.lastTime).Seconds() * rl.rate
+	if rl.tokens > rl.capacity {
+		rl.tokens = rl.capacity
 	}
+	rl.lastTime = now
 }
@@ -0,0 +1,132 @@
+package util
+
+import (
+	"context"
+	"errors"
+	"runtime"
+	"runtime/metrics"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestRateLimiterPacing(t *testing.T) {
+	const rps, requests = 200, 41
+
+	rl := NewRateLimiterBurst(rps, 1)
+	start := time.Now()
+	for i := 0; i < requests; i++ {
+		rl.Wait()
+	}
+	elapsed := time.Since(start)
+
+	// The first request uses the initial token, the rest are paced 5ms apart
+	want := time.Duration(requests-1) * time.Second / rps
+	if elapsed < want-10*time.Millisecond || elapsed > want+50*time.Millisecond {
+		t.Errorf("%d requests at %d rps took %s, want about %s", requests, rps, elapsed, want)
+	}
+}
+
+func TestRateLimiterPacingConcurrent(t *testing.T) {
+	const rps, workers, perWorker = 500, 10, 10
+
+	rl := NewRateLimiterBurst(rps, 1)
+	start := time.Now()
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < perWorker; j++ {
+				rl.Wait()
+			}
+		}()
+	}
+	wg.Wait()
+	elapsed := time.Since(start)
+
+	want := time.Duration(workers*perWorker-1) * time.Second / rps
+	if elapsed < want-10*time.Millisecond || elapsed > want+100*time.Millisecond {
+		t.Errorf("%d requests from %d workers at %d rps took %s, want about %s",
+			workers*perWorker, workers, rps, elapsed, want)
+	}
+}
+
+func TestRateLimiterWaitContextCancelReturnsToken(t *testing.T) {
+	rl := NewRateLimiterBurst(10, 1) // One token every 100ms
+	if !rl.Allow() {
+		t.Fatal("initial token not available")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := rl.WaitContext(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("WaitContext() = %v, want %v", err, context.DeadlineExceeded)
+	}
+
+	// Had the cancelled wait kept its reservation, the next token would be
+	// due ~190ms from now instead of ~90ms
+	if delay := rl.reserve(); delay > 100*time.Millisecond {
+		t.Errorf("next token due in %s after a cancelled wait, want under 100ms", delay)
+	}
+}
+
+func TestRateLimiterWaitContextCancelled(t *testing.T) {
+	rl := NewRateLimiterBurst(1, 1)
+	rl.Allow()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	start := time.Now()
+	if err := rl.WaitContext(ctx); !errors.Is(err, context.Canceled) {
+		t.Fatalf("WaitContext() = %v, want %v", err, context.Canceled)
+	}
+	if elapsed := time.Since(start); elapsed > 50*time.Millisecond {
+		t.Errorf("cancelled WaitContext returned after %s", elapsed)
+	}
+}
+
+// userCPU returns the CPU time spent running Go code so far. The runtime
+// updates its CPU estimates at garbage collection, so it collects first.
+func userCPU() time.Duration {
+	runtime.GC()
+	sample := []metrics.Sample{{Name: "/cpu/classes/user:cpu-seconds"}}
+	metrics.Read(sample)
+	if sample[0].Value.Kind() != metrics.KindFloat64 {
+		return 0
+	}
+	return time.Duration(sample[0].Value.Float64() * float64(time.Second))
+}
+
+// BenchmarkRateLimiterWait1000RPS paces 100 workers sharing a 1000 RPS
+// limiter, the setup of --actions-per-second 1000. Waiting workers sleep,
+// so the reported cpu-ms/s (CPU time per second of waiting) stays low.
+func BenchmarkRateLimiterWait1000RPS(b *testing.B) {
+	const workers = 100
+
+	rl := NewRateLimiterBurst(1000, 1)
+	requests := make(chan struct{}, b.N)
+	for i := 0; i < b.N; i++ {
+		requests <- struct{}{}
+	}
+	close(requests)
+
+	cpuBefore := userCPU()
+	b.ResetTimer()
+
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for range requests {
+				rl.Wait()
+			}
+		}()
+	}
+	wg.Wait()
+
+	b.StopTimer()
+	cpu := userCPU() - cpuBefore
+	b.ReportMetric(cpu.Seconds()*1000/b.Elapsed().Seconds(), "cpu-ms/s")
+}
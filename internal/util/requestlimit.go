@@ -0,0 +1,30 @@
+package util
+
+import "sync/atomic"
+
+// RequestLimit caps the number of requests sent across all workers, for
+// tests that run for a request count instead of a duration
+type RequestLimit struct {
+	max   int64
+	taken atomic.Int64
+}
+
+// NewRequestLimit creates a limit allowing max requests
+func NewRequestLimit(max int64) *RequestLimit {
+	return &RequestLimit{max: max}
+}
+
+// Take reserves one request, returning false once the limit is used up
+func (l *RequestLimit) Take() bool {
+	return l.taken.Add(1) <= l.max
+}
+
+// Exhausted reports whether every request has been taken
+func (l *RequestLimit) Exhausted() bool {
+	return l.taken.Load() >= l.max
+}
+
+// Max returns the number of requests allowed
+func (l *RequestLimit) Max() int64 {
+	return l.max
+}
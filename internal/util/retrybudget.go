@@ -0,0 +1,69 @@
+package util
+
+import "sync/atomic"
+
+// retryBudgetMinRequests is the request count the budget assumes until real
+// traffic exceeds it, so the first failures of a test can still be retried
+const retryBudgetMinRequests = 100
+
+// RetryBudget caps retries to a fraction of all requests sent during the test.
+// Once a retry is refused the budget trips and stays exhausted for the rest of
+// the run, acting as a circuit breaker against retry storms.
+type RetryBudget struct {
+	ratio     float64
+	requests  atomic.Int64
+	retries   atomic.Int64
+	exhausted atomic.Bool
+}
+
+// NewRetryBudget creates a retry budget allowing retries up to ratio of total
+// requests. A ratio of zero or less disables the budget.
+func NewRetryBudget(ratio float64) *RetryBudget {
+	return &RetryBudget{ratio: ratio}
+}
+
+// Enabled reports whether the budget limits retries at all
+func (rb *RetryBudget) Enabled() bool {
+	return rb.ratio > 0
+}
+
+// RecordRequest counts a request attempt (including retries) against the budget
+func (rb *RetryBudget) RecordRequest() {
+	rb.requests.Add(1)
+}
+
+// Allow reserves a retry if the budget permits it
+func (rb *RetryBudget) Allow() bool {
+	if !rb.Enabled() {
+		return true
+	}
+	if rb.exhausted.Load() {
+		return false
+	}
+
+	if rb.retries.Add(1) > rb.Allowed() {
+		rb.retries.Add(-1)
+		rb.exhausted.Store(true)
+		return false
+	}
+	return true
+}
+
+// Allowed returns the number of retries currently permitted by the budget
+func (rb *RetryBudget) Allowed() int64 {
+	requests := rb.requests.Load()
+	if requests < retryBudgetMinRequests {
+		requests = retryBudgetMinRequests
+	}
+	return int64(rb.ratio * float64(requests))
+}
+
+// Used returns the number of retries consumed from the budget
+func (rb *RetryBudget) Used() int64 {
+	return rb.retries.Load()
+}
+
+// Exhausted reports whether the budget has tripped
+func (rb *RetryBudget) Exhausted() bool {
+	return rb.exhausted.Load()
+}
@@ -0,0 +1,56 @@
+package worker
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"stampede-shooter/internal/script"
+)
+
+// defaultRequestTimeout bounds requests of actions without a timeout
+const defaultRequestTimeout = 30 * time.Second
+
+// clientKey identifies the client settings an action can override
+type clientKey struct {
+	insecureTLS bool
+}
+
+// clientFor returns the HTTP client for an action: the worker's default
+// client, or a cached variant for actions that override insecure_tls.
+// Variants share the worker's cookie jar but use their own transport.
+func (w *Worker) clientFor(action script.Action) *http.Client {
+	key := clientKey{insecureTLS: w.insecureTLS}
+	if action.InsecureTLS != nil {
+		key.insecureTLS = *action.InsecureTLS
+	}
+	if key.insecureTLS == w.insecureTLS {
+		return w.client
+	}
+
+	w.clientsMu.Lock()
+	defer w.clientsMu.Unlock()
+
+	if client, ok := w.clients[key]; ok {
+		return client
+	}
+
+	client := *w.client
+	client.Transport = withInsecureTLS(w.client.Transport, key.insecureTLS)
+	w.clients[key] = &client
+	return &client
+}
+
+// requestTimeout returns the action's timeout, or the default if it has none
+func requestTimeout(action script.Action) time.Duration {
+	if timeout := action.RequestTimeout(); timeout > 0 {
+		return timeout
+	}
+	return defaultRequestTimeout
+}
+
+// requestContext derives the context of a single request attempt from the
+// action context, bounded by the action's timeout
+func requestContext(ctx context.Context, action script.Action) (context.Context, context.CancelFunc) {
+	return context.WithTimeout(ctx, requestTimeout(action))
+}
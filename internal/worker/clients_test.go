@@ -0,0 +1,166 @@
+package worker
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"stampede-shooter/internal/config"
+	"stampede-shooter/internal/metrics"
+)
+
+func TestInsecureTLSOverride(t *testing.T) {
+	// httptest's certificate is self-signed, so only skipping verification
+	// gets through
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, "ok")
+	}))
+	server.Config.ErrorLog = log.New(io.Discard, "", 0) // Rejected handshakes are expected
+	server.StartTLS()
+	defer server.Close()
+
+	s := loadTestScript(t, `
+- name: Default
+  method: GET
+  url: `+server.URL+`
+- name: Strict
+  method: GET
+  url: `+server.URL+`
+  insecure_tls: false
+- name: Insecure
+  method: GET
+  url: `+server.URL+`
+  insecure_tls: true
+`)
+
+	tests := []struct {
+		name        string
+		insecureTLS bool // --insecure-tls
+		wantOK      map[string]bool
+	}{
+		{"strict by default", false, map[string]bool{"Default": false, "Strict": false, "Insecure": true}},
+		{"insecure by default", true, map[string]bool{"Default": true, "Strict": false, "Insecure": true}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w, collector := newTestWorker(t, config.Config{InsecureTLS: tt.insecureTLS}, s)
+			stats := runActions(t, w, collector)
+
+			for name, ok := range tt.wantOK {
+				if ok {
+					requireCounts(t, stats, name, 1, 0)
+					continue
+				}
+				requireCounts(t, stats, name, 0, 1)
+				if stats[name].ErrorCategories[metrics.ErrorTLS] != 1 {
+					t.Errorf("%s failed with %v, want a TLS error", name, stats[name].ErrorCategories)
+				}
+			}
+		})
+	}
+}
+
+func TestInsecureTLSOverrideSharesCookies(t *testing.T) {
+	// Cookies ignore the port, so a session set by the plain HTTP server
+	// applies to the TLS server on the same host
+	login := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.SetCookie(w, &http.Cookie{Name: "session", Value: "abc", Path: "/"})
+	}))
+	defer login.Close()
+	internal := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if c, err := r.Cookie("session"); err != nil || c.Value != "abc" {
+			http.Error(w, "no session", http.StatusUnauthorized)
+		}
+	}))
+	defer internal.Close()
+
+	s := loadTestScript(t, `
+- name: Login
+  method: GET
+  url: `+login.URL+`
+- name: Internal
+  method: GET
+  url: `+internal.URL+`
+  insecure_tls: true
+`)
+	w, collector := newTestWorker(t, config.Config{}, s)
+	stats := runActions(t, w, collector)
+
+	requireCounts(t, stats, "Login", 1, 0)
+	requireCounts(t, stats, "Internal", 1, 0)
+}
+
+func TestActionTimeoutOverride(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-time.After(200 * time.Millisecond):
+		case <-r.Context().Done():
+		}
+		fmt.Fprint(w, "ok")
+	}))
+	defer server.Close()
+
+	s := loadTestScript(t, `
+- name: Fast
+  method: GET
+  url: `+server.URL+`
+  timeout: 50ms
+- name: Slow
+  method: GET
+  url: `+server.URL+`
+  timeout: 2s
+`)
+	w, collector := newTestWorker(t, config.Config{}, s)
+	stats := runActions(t, w, collector)
+
+	requireCounts(t, stats, "Fast", 0, 1)
+	if stats["Fast"].ErrorCategories[metrics.ErrorTimeout] != 1 {
+		t.Errorf("Fast failed with %v, want a timeout", stats["Fast"].ErrorCategories)
+	}
+	requireCounts(t, stats, "Slow", 1, 0)
+}
+
+func TestHTTP10(t *testing.T) {
+	var protos, bodies []string
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		protos = append(protos, r.Proto)
+		bodies = append(bodies, string(body))
+		fmt.Fprint(w, "ok")
+	})
+	plain := httptest.NewServer(handler)
+	defer plain.Close()
+	secure := httptest.NewTLSServer(handler)
+	defer secure.Close()
+
+	s := loadTestScript(t, `
+- name: Plain
+  method: POST
+  url: `+plain.URL+`/items
+  body: 'name=widget'
+- name: Secure
+  method: GET
+  url: `+secure.URL+`/items?page=2
+`)
+	w, collector := newTestWorker(t, config.Config{HTTP10: true, InsecureTLS: true}, s)
+	stats := runActions(t, w, collector)
+
+	for _, name := range []string{"Plain", "Secure"} {
+		requireCounts(t, stats, name, 1, 0)
+		if stats[name].ConnClosed != 1 {
+			t.Errorf("%s: %d responses closed the connection, want 1", name, stats[name].ConnClosed)
+		}
+	}
+	for i, proto := range protos {
+		if proto != "HTTP/1.0" {
+			t.Errorf("request %d sent as %s, want HTTP/1.0", i, proto)
+		}
+	}
+	if len(bodies) != 2 || bodies[0] != "name=widget" || bodies[1] != "" {
+		t.Errorf("server received bodies %q, want the POST body only", bodies)
+	}
+}
@@ -0,0 +1,90 @@
+package worker
+
+import (
+	"fmt"
+	"strconv"
+	"sync"
+
+	"stampede-shooter/internal/script"
+)
+
+// ConsistencyStore holds the test-wide reference values of "equal"
+// consistency checks
+type ConsistencyStore struct {
+	mu     sync.Mutex
+	values map[string]string
+}
+
+// NewConsistencyStore creates an empty consistency store
+func NewConsistencyStore() *ConsistencyStore {
+	return &ConsistencyStore{values: make(map[string]string)}
+}
+
+// reference returns the first value observed for name, recording value if
+// it is the first
+func (s *ConsistencyStore) reference(name, value string) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if ref, ok := s.values[name]; ok {
+		return ref
+	}
+	s.values[name] = value
+	return value
+}
+
+// checkConsistency extracts each checked value from the response body and
+// validates it against the check's invariant
+func (w *Worker) checkConsistency(checks []script.ConsistencyCheck, body []byte) error {
+	for i := range checks {
+		check := &checks[i]
+
+		value, err := check.Extract(body)
+		if err != nil {
+			return fmt.Errorf("%s: %w", check.Name, err)
+		}
+
+		switch check.Invariant {
+		case script.InvariantMonotonic:
+			w.sessionMu.Lock()
+			previous, seen := w.observed[check.Name]
+			if !seen || compareValues(value, previous) >= 0 {
+				w.observed[check.Name] = value
+			}
+			w.sessionMu.Unlock()
+
+			if seen && compareValues(value, previous) < 0 {
+				return fmt.Errorf("%s went backwards from %s to %s", check.Name, previous, value)
+			}
+		case script.InvariantEqual:
+			if ref := w.consistency.reference(check.Name, value); ref != value {
+				return fmt.Errorf("%s is %s, other workers saw %s", check.Name, value, ref)
+			}
+		}
+	}
+	return nil
+}
+
+// compareValues compares two values numerically when both are numbers and
+// lexically otherwise (which also orders RFC 3339 timestamps)
+func compareValues(a, b string) int {
+	x, errA := strconv.ParseFloat(a, 64)
+	y, errB := strconv.ParseFloat(b, 64)
+	if errA != nil || errB != nil {
+		switch {
+		case a < b:
+			return -1
+		case a > b:
+			return 1
+		}
+		return 0
+	}
+
+	switch {
+	case x < y:
+		return -1
+	case x > y:
+		return 1
+	}
+	return 0
+}
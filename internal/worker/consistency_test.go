@@ -0,0 +1,159 @@
+package worker
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"stampede-shooter/internal/config"
+	"stampede-shooter/internal/metrics"
+	"stampede-shooter/internal/script"
+	"stampede-shooter/internal/util"
+)
+
+const consistencyScript = `
+- name: ReadDocument
+  method: GET
+  url: http://localhost/documents/42
+  consistency:
+    - name: doc-version
+      json: $.version
+      invariant: monotonic
+    - name: schema
+      regex: 'schema-version: (\d+)'
+      invariant: equal
+`
+
+// newConsistencyWorkers creates n workers sharing one consistency store and
+// returns them with the script's checks
+func newConsistencyWorkers(t *testing.T, n int) ([]*Worker, []script.ConsistencyCheck) {
+	t.Helper()
+	s := loadTestScript(t, consistencyScript)
+	collector := metrics.NewCollector(metrics.DefaultBufferSize)
+	shared := &Shared{RetryBudget: util.NewRetryBudget(0), Consistency: NewConsistencyStore()}
+
+	workers := make([]*Worker, n)
+	for i := range workers {
+		workers[i] = New(i+1, config.Config{}, s, collector, nil, shared)
+	}
+	return workers, s.Actions[0].Consistency
+}
+
+// document is a response body carrying a version and a schema version
+func document(version, schema string) []byte {
+	return []byte(fmt.Sprintf(`{"version": %s, "note": "schema-version: %s"}`, version, schema))
+}
+
+func TestCheckConsistencyMonotonic(t *testing.T) {
+	workers, checks := newConsistencyWorkers(t, 1)
+	w := workers[0]
+
+	steps := []struct {
+		version string
+		wantErr string // Substring of the violation, empty for a pass
+	}{
+		{"1", ""},
+		{"2", ""},
+		{"2", ""}, // Repeats are not a regression
+		{"10", ""},
+		{"9", "doc-version went backwards from 10 to 9"},
+		// The highest value seen stays the reference after a violation
+		{"9.5", "doc-version went backwards from 10 to 9.5"},
+		{"10.0", ""},
+		{"11", ""},
+	}
+	for i, step := range steps {
+		err := w.checkConsistency(checks, document(step.version, "3"))
+		switch {
+		case step.wantErr == "" && err != nil:
+			t.Errorf("step %d (version %s): %v, want pass", i, step.version, err)
+		case step.wantErr != "" && (err == nil || !strings.Contains(err.Error(), step.wantErr)):
+			t.Errorf("step %d (version %s): %v, want %q", i, step.version, err, step.wantErr)
+		}
+	}
+}
+
+func TestCheckConsistencyMonotonicPerWorker(t *testing.T) {
+	workers, checks := newConsistencyWorkers(t, 2)
+
+	if err := workers[0].checkConsistency(checks, document("5", "3")); err != nil {
+		t.Fatal(err)
+	}
+	// Monotonic reads are per worker: another worker may still lag behind
+	if err := workers[1].checkConsistency(checks, document("4", "3")); err != nil {
+		t.Errorf("worker 2 reading an older version: %v, want pass", err)
+	}
+	if err := workers[1].checkConsistency(checks, document("3", "3")); err == nil {
+		t.Error("worker 2 going backwards passed, want a violation")
+	}
+}
+
+func TestCheckConsistencyEqual(t *testing.T) {
+	workers, checks := newConsistencyWorkers(t, 3)
+
+	// The first value seen in the test becomes the reference for all workers
+	if err := workers[0].checkConsistency(checks, document("1", "3")); err != nil {
+		t.Fatal(err)
+	}
+	if err := workers[1].checkConsistency(checks, document("1", "3")); err != nil {
+		t.Errorf("worker 2 seeing the reference: %v, want pass", err)
+	}
+	err := workers[2].checkConsistency(checks, document("1", "4"))
+	if err == nil || err.Error() != "schema is 4, other workers saw 3" {
+		t.Errorf("worker 3 seeing a different value: %v, want a violation", err)
+	}
+	// A violation does not replace the reference
+	if err := workers[2].checkConsistency(checks, document("1", "3")); err != nil {
+		t.Errorf("worker 3 seeing the reference after a violation: %v, want pass", err)
+	}
+}
+
+func TestCheckConsistencyMissingValue(t *testing.T) {
+	workers, checks := newConsistencyWorkers(t, 1)
+
+	tests := []struct {
+		name    string
+		body    string
+		wantErr string
+	}{
+		{"missing field", `{"note": "schema-version: 3"}`, "doc-version: $.version not found"},
+		{"not JSON", `<html>schema-version: 3</html>`, "doc-version: response is not valid JSON"},
+		{"regex miss", `{"version": 1}`, "schema: regex"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := workers[0].checkConsistency(checks, []byte(tt.body))
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("checkConsistency() = %v, want error containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestCompareValues(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		// Numbers compare numerically, where lexical order would differ
+		{"9", "10", -1},
+		{"10", "9", 1},
+		{"10", "10.0", 0},
+		{"1e3", "999", 1},
+		{"-2", "-10", 1},
+		{"0.5", "0.25", 1},
+		// Anything else falls back to lexical order
+		{"v9", "v10", 1},
+		{"abc", "abd", -1},
+		{"same", "same", 0},
+		{"10", "abc", -1},
+		{"", "0", -1},
+		{"2024-01-02T03:04:05Z", "2024-01-02T03:04:06Z", -1},
+		{"2024-12-31T23:59:59Z", "2025-01-01T00:00:00Z", -1},
+	}
+	for _, tt := range tests {
+		if got := compareValues(tt.a, tt.b); got != tt.want {
+			t.Errorf("compareValues(%q, %q) = %d, want %d", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
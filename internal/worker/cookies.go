@@ -0,0 +1,218 @@
+package worker
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/cookiejar"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// recordingJar is a cookie jar that also remembers the full attributes of
+// every cookie it was given, which cookiejar.Jar does not expose
+type recordingJar struct {
+	*cookiejar.Jar
+
+	mu      sync.Mutex
+	cookies map[string]*http.Cookie // Keyed by domain, path and name
+}
+
+// newRecordingJar creates an empty recording cookie jar
+func newRecordingJar() *recordingJar {
+	jar, _ := cookiejar.New(nil)
+	return &recordingJar{Jar: jar, cookies: make(map[string]*http.Cookie)}
+}
+
+// SetCookies stores cookies in the jar and records their attributes
+func (j *recordingJar) SetCookies(u *url.URL, cookies []*http.Cookie) {
+	j.Jar.SetCookies(u, cookies)
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	now := time.Now()
+	for _, c := range cookies {
+		key := cookieDomain(u, c) + ";" + cookiePath(u, c) + ";" + c.Name
+		if c.MaxAge < 0 || (!c.Expires.IsZero() && c.Expires.Before(now)) {
+			delete(j.cookies, key)
+			continue
+		}
+
+		// Max-Age is relative to now; store the absolute expiry instead
+		if c.MaxAge > 0 {
+			copied := *c
+			copied.Expires = now.Add(time.Duration(c.MaxAge) * time.Second)
+			copied.MaxAge = 0
+			c = &copied
+		}
+		j.cookies[key] = c
+	}
+}
+
+// ParseCookies parses a Cookie header style list, "name=value; name2=value2",
+// into cookies for seeding worker jars
+func ParseCookies(s string) ([]*http.Cookie, error) {
+	var cookies []*http.Cookie
+	for _, pair := range strings.Split(s, ";") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		name, value, ok := strings.Cut(pair, "=")
+		name = strings.TrimSpace(name)
+		if !ok || name == "" || strings.ContainsAny(name, " \t\",\\()<>@:/[]?{}") {
+			return nil, fmt.Errorf("invalid cookie %q: expected name=value", pair)
+		}
+		cookies = append(cookies, &http.Cookie{
+			Name:  name,
+			Value: strings.Trim(strings.TrimSpace(value), `"`),
+			Path:  "/",
+		})
+	}
+	if len(cookies) == 0 {
+		return nil, fmt.Errorf("no cookies in %q", s)
+	}
+	return cookies, nil
+}
+
+// cookieDomain returns the domain a cookie applies to
+func cookieDomain(u *url.URL, c *http.Cookie) string {
+	if c.Domain != "" {
+		return "." + strings.TrimPrefix(c.Domain, ".")
+	}
+	return u.Hostname()
+}
+
+// cookiePath returns the path a cookie applies to, defaulting to the
+// directory of the request path as browsers do
+func cookiePath(u *url.URL, c *http.Cookie) string {
+	if strings.HasPrefix(c.Path, "/") {
+		return c.Path
+	}
+	dir := u.Path
+	if i := strings.LastIndex(dir, "/"); i > 0 {
+		return dir[:i]
+	}
+	return "/"
+}
+
+// jarCookie is a live cookie of a recording jar with where it applies
+type jarCookie struct {
+	domain, path string
+	*http.Cookie
+}
+
+// live returns the jar's unexpired cookies, sorted by domain, path and name
+func (j *recordingJar) live() []jarCookie {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	keys := make([]string, 0, len(j.cookies))
+	for key := range j.cookies {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	cookies := make([]jarCookie, 0, len(keys))
+	now := time.Now()
+	for _, key := range keys {
+		c := j.cookies[key]
+		if !c.Expires.IsZero() && c.Expires.Before(now) {
+			continue
+		}
+		parts := strings.SplitN(key, ";", 3)
+		cookies = append(cookies, jarCookie{domain: parts[0], path: parts[1], Cookie: c})
+	}
+	return cookies
+}
+
+// ExportCookies writes the cookies this worker holds to filename in the
+// Netscape cookies.txt format
+func (w *Worker) ExportCookies(filename string) error {
+	jar, ok := w.client.Jar.(*recordingJar)
+	if !ok {
+		return fmt.Errorf("worker %d does not record cookies", w.id)
+	}
+
+	var lines []string
+	for _, c := range jar.live() {
+		expires := int64(0) // Session cookie
+		if !c.Expires.IsZero() {
+			expires = c.Expires.Unix()
+		}
+
+		domain := c.domain
+		includeSubdomains := strings.HasPrefix(domain, ".")
+		if c.HttpOnly {
+			domain = "#HttpOnly_" + domain
+		}
+
+		lines = append(lines, strings.Join([]string{
+			domain,
+			netscapeBool(includeSubdomains),
+			c.path,
+			netscapeBool(c.Secure),
+			fmt.Sprint(expires),
+			c.Name,
+			c.Value,
+		}, "\t"))
+	}
+
+	file, err := os.Create(filename)
+	if err != nil {
+		return fmt.Errorf("failed to create cookie file: %w", err)
+	}
+	defer file.Close()
+
+	writer := bufio.NewWriter(file)
+	fmt.Fprintf(writer, "# Netscape HTTP Cookie File\n# Exported by stampede-shooter from worker %d\n\n", w.id)
+	for _, line := range lines {
+		fmt.Fprintln(writer, line)
+	}
+	if err := writer.Flush(); err != nil {
+		return fmt.Errorf("failed to write cookie file: %w", err)
+	}
+	return nil
+}
+
+// DumpCookies prints the cookies this worker holds, with their domain, path,
+// expiry and flags, for checking session handling. when says at which point
+// of the run the jar was dumped.
+func (w *Worker) DumpCookies(out io.Writer, when string) {
+	jar, ok := w.client.Jar.(*recordingJar)
+	if !ok {
+		return
+	}
+
+	cookies := jar.live()
+	fmt.Fprintf(out, "Cookies of worker %d %s: %d\n", w.id, when, len(cookies))
+	for _, c := range cookies {
+		attrs := []string{"domain=" + c.domain, "path=" + c.path}
+		if c.Expires.IsZero() {
+			attrs = append(attrs, "session")
+		} else {
+			attrs = append(attrs, "expires="+c.Expires.UTC().Format(time.RFC3339))
+		}
+		if c.HttpOnly {
+			attrs = append(attrs, "HttpOnly")
+		}
+		if c.Secure {
+			attrs = append(attrs, "Secure")
+		}
+		fmt.Fprintf(out, "  %s=%s (%s)\n", c.Name, c.Value, strings.Join(attrs, ", "))
+	}
+}
+
+// netscapeBool formats a boolean the way cookies.txt expects
+func netscapeBool(b bool) string {
+	if b {
+		return "TRUE"
+	}
+	return "FALSE"
+}
@@ -0,0 +1,37 @@
+package worker
+
+import (
+	"context"
+	"net"
+	"time"
+
+	"stampede-shooter/internal/metrics"
+)
+
+// dialFunc is the signature of http.Transport.DialContext
+type dialFunc func(ctx context.Context, network, addr string) (net.Conn, error)
+
+// dialRetryBackoff is the pause before the first dial retry; it doubles per
+// retry
+const dialRetryBackoff = 50 * time.Millisecond
+
+// retryDial wraps dial to retry failed connection attempts up to retries
+// times with a short backoff. Only establishing the connection is retried,
+// so a request is never sent twice.
+func retryDial(dial dialFunc, retries int, collector *metrics.Collector) dialFunc {
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
+		for attempt := 0; ; attempt++ {
+			conn, err := dial(ctx, network, addr)
+			if err == nil || attempt >= retries || ctx.Err() != nil {
+				return conn, err
+			}
+
+			collector.RecordDialRetry()
+			select {
+			case <-ctx.Done():
+				return nil, err
+			case <-time.After(dialRetryBackoff << attempt):
+			}
+		}
+	}
+}
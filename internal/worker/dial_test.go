@@ -0,0 +1,132 @@
+package worker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"syscall"
+	"testing"
+	"time"
+
+	"stampede-shooter/internal/config"
+	"stampede-shooter/internal/metrics"
+)
+
+func TestRetryDialListenerComesUp(t *testing.T) {
+	// Reserve a port, then free it so connections are refused until the
+	// server starts listening on it
+	reserved, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := reserved.Addr().String()
+	reserved.Close()
+
+	// Up after the first retry's 50ms backoff, before the second's 100ms ends
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, "ok")
+	}))
+	started := make(chan error, 1)
+	time.AfterFunc(80*time.Millisecond, func() {
+		listener, err := net.Listen("tcp", addr)
+		if err != nil {
+			started <- err
+			return
+		}
+		server.Listener = listener
+		server.Start()
+		started <- nil
+	})
+	defer func() {
+		if err := <-started; err == nil {
+			server.Close()
+		}
+	}()
+
+	s := loadTestScript(t, `
+- name: Health
+  method: GET
+  url: http://`+addr+`/health
+`)
+	w, collector := newTestWorker(t, config.Config{DialRetries: 3}, s)
+	stats := runActions(t, w, collector)
+
+	requireCounts(t, stats, "Health", 1, 0)
+	if got := collector.DialRetries(); got < 1 || got > 3 {
+		t.Errorf("%d dial retries, want between 1 and 3", got)
+	}
+	if got := stats["Health"].Retries; got != 0 {
+		t.Errorf("%d request retries, want 0: only the connection is retried", got)
+	}
+}
+
+func TestRetryDial(t *testing.T) {
+	refused := &net.OpError{Op: "dial", Net: "tcp", Err: syscall.ECONNREFUSED}
+
+	tests := []struct {
+		name        string
+		failures    int // Dials refused before one succeeds
+		retries     int
+		wantErr     bool
+		wantDials   int
+		wantRetries int64
+	}{
+		{"first dial succeeds", 0, 3, false, 1, 0},
+		{"succeeds on a retry", 2, 3, false, 3, 2},
+		{"retries exhausted", 5, 2, true, 3, 2},
+		{"retries disabled", 1, 0, true, 1, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dials := 0
+			dial := func(ctx context.Context, network, addr string) (net.Conn, error) {
+				dials++
+				if dials <= tt.failures {
+					return nil, refused
+				}
+				client, server := net.Pipe()
+				server.Close()
+				return client, nil
+			}
+
+			collector := metrics.NewCollector(metrics.DefaultBufferSize)
+			conn, err := retryDial(dial, tt.retries, collector)(context.Background(), "tcp", "127.0.0.1:1")
+			if conn != nil {
+				conn.Close()
+			}
+			if gotErr := err != nil; gotErr != tt.wantErr {
+				t.Errorf("dial error %v, want error: %v", err, tt.wantErr)
+			}
+			if tt.wantErr && !errors.Is(err, syscall.ECONNREFUSED) {
+				t.Errorf("dial error %v, want the last dial's error", err)
+			}
+			if dials != tt.wantDials {
+				t.Errorf("%d dials, want %d", dials, tt.wantDials)
+			}
+			if got := collector.DialRetries(); got != tt.wantRetries {
+				t.Errorf("%d dial retries recorded, want %d", got, tt.wantRetries)
+			}
+		})
+	}
+}
+
+func TestRetryDialCancelled(t *testing.T) {
+	dial := func(ctx context.Context, network, addr string) (net.Conn, error) {
+		return nil, &net.OpError{Op: "dial", Net: "tcp", Err: syscall.ECONNREFUSED}
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	// The 50ms backoff is cut short when the context ends
+	start := time.Now()
+	_, err := retryDial(dial, 10, metrics.NewCollector(metrics.DefaultBufferSize))(ctx, "tcp", "127.0.0.1:1")
+	if err == nil {
+		t.Fatal("dial succeeded, want the refused error")
+	}
+	if elapsed := time.Since(start); elapsed > 45*time.Millisecond {
+		t.Errorf("cancelled dial returned after %s", elapsed)
+	}
+}
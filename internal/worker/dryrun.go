@@ -0,0 +1,146 @@
+package worker
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+
+	"stampede-shooter/internal/script"
+	"stampede-shooter/internal/util"
+)
+
+// DryRun expands every action of the script once for this worker's user and
+// writes the resulting requests to out without sending them. Tokens of
+// OAuth and bearer providers aren't fetched and show as <token>. It returns
+// the number of actions that failed to expand or build.
+func (w *Worker) DryRun(ctx context.Context, out io.Writer, loginURL string) int {
+	var creds util.Credentials
+	if w.credentials != nil {
+		creds = w.credentials.GetCredentialsForUser(w.id)
+	}
+	if _, isFile := w.provider.(*util.CredentialsManager); w.provider != nil && !isFile {
+		creds.Token = "<token>"
+	}
+
+	failed := 0
+	if loginURL != "" {
+		req, err := w.newLoginRequest(ctx, loginURL, creds)
+		if err == nil {
+			err = checkURL(req)
+		}
+		failed += printDryRun(out, "login", req, err)
+	}
+
+	for _, step := range w.steps {
+		for _, action := range step {
+			req, err := w.dryRunRequest(ctx, action, creds)
+			failed += printDryRun(out, action.Name, req, err)
+		}
+	}
+	return failed
+}
+
+// dryRunRequest builds the request executeAction would send for action
+func (w *Worker) dryRunRequest(ctx context.Context, action script.Action, creds util.Credentials) (*http.Request, error) {
+	expandedAction, err := w.expandAction(action, creds)
+	if err != nil {
+		return nil, fmt.Errorf("template error: %w", err)
+	}
+	expandedAction.URL = w.resolveURL(expandedAction.URL)
+	if expandedAction.WebSocket != nil {
+		return w.dryRunWebSocket(ctx, expandedAction, creds)
+	}
+	if expandedAction.HasMethodWeights() {
+		expandedAction.Method = w.pickMethod(&expandedAction)
+		if !script.MethodAllowsBody(expandedAction.Method) {
+			expandedAction.Body, expandedAction.JSONBody = "", ""
+			expandedAction.Multipart = nil
+		}
+	}
+
+	req, err := w.newRequest(ctx, expandedAction, w.requestBody(expandedAction))
+	if err != nil {
+		return nil, err
+	}
+	if creds.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+creds.Token)
+	}
+	return req, checkURL(req)
+}
+
+// dryRunWebSocket builds the handshake executeWebSocket would send for an
+// expanded action, with the message exchange listed as its body
+func (w *Worker) dryRunWebSocket(ctx context.Context, action script.Action, creds util.Credentials) (*http.Request, error) {
+	action.URL = webSocketURL(action.URL)
+	req, err := w.newRequest(ctx, action, "")
+	if err != nil {
+		return nil, err
+	}
+	if creds.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+creds.Token)
+	}
+
+	var exchange strings.Builder
+	for _, msg := range action.WebSocket.Messages {
+		if msg.Send != "" {
+			fmt.Fprintf(&exchange, "%s > %s\n", msg.Name, msg.Send)
+		}
+		if msg.Expect != "" {
+			fmt.Fprintf(&exchange, "%s < ...%s...\n", msg.Name, msg.Expect)
+		}
+	}
+	req.Body = io.NopCloser(strings.NewReader(exchange.String()))
+	return req, checkWebSocketURL(req)
+}
+
+// checkURL rejects URLs that parse but can't be sent, such as a missing
+// scheme or host
+func checkURL(req *http.Request) error {
+	if req.URL.Scheme != "http" && req.URL.Scheme != "https" {
+		return fmt.Errorf("URL %q must start with http:// or https://", req.URL)
+	}
+	if req.URL.Host == "" {
+		return fmt.Errorf("URL %q has no host", req.URL)
+	}
+	return nil
+}
+
+// printDryRun writes a request, or the error building it, and returns 1 for
+// an error. Multipart bodies are summarized rather than printed.
+func printDryRun(out io.Writer, name string, req *http.Request, err error) int {
+	if err != nil {
+		if req != nil && req.Body != nil {
+			req.Body.Close()
+		}
+		fmt.Fprintf(out, "=== %s: ERROR %v\n\n", name, err)
+		return 1
+	}
+
+	fmt.Fprintf(out, "=== %s\n%s %s\n", name, req.Method, req.URL)
+	names := make([]string, 0, len(req.Header))
+	for key := range req.Header {
+		names = append(names, key)
+	}
+	sort.Strings(names)
+	for _, key := range names {
+		fmt.Fprintf(out, "%s: %s\n", key, strings.Join(req.Header[key], ", "))
+	}
+
+	if req.Body != nil {
+		body, readErr := io.ReadAll(req.Body)
+		req.Body.Close()
+		switch {
+		case readErr != nil:
+			fmt.Fprintf(out, "\n<body unreadable: %v>\n", readErr)
+		case strings.HasPrefix(req.Header.Get("Content-Type"), "multipart/"):
+			fmt.Fprintf(out, "\n<multipart body, %d bytes>\n", len(body))
+		default:
+			fmt.Fprintf(out, "\n%s\n", strings.TrimRight(string(body), "\n"))
+		}
+	}
+	fmt.Fprintln(out)
+	return 0
+}
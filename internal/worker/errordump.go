@@ -0,0 +1,174 @@
+package worker
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// maxDumpBodyBytes caps the response body stored per dumped failure
+const maxDumpBodyBytes = 4096
+
+// redactedValue replaces secret header values in dumps
+const redactedValue = "[REDACTED]"
+
+// sensitiveHeaders are redacted from dumps unless secrets are requested
+var sensitiveHeaders = map[string]bool{
+	"Authorization":       true,
+	"Proxy-Authorization": true,
+	"Cookie":              true,
+	"Set-Cookie":          true,
+	"X-Csrf-Token":        true,
+	"X-Api-Key":           true,
+}
+
+// DumpedRequest is the fully expanded request as sent to the server
+type DumpedRequest struct {
+	Method  string            `json:"method"`
+	URL     string            `json:"url"`
+	Headers map[string]string `json:"headers"`
+	Body    string            `json:"body,omitempty"`
+}
+
+// DumpedResponse is the (truncated) response that failed an assertion
+type DumpedResponse struct {
+	Status  int               `json:"status"`
+	Headers map[string]string `json:"headers"`
+	Body    string            `json:"body,omitempty"`
+}
+
+// ErrorRecord is a single failed request written to the error dump
+type ErrorRecord struct {
+	Time     time.Time       `json:"time"`
+	WorkerID int             `json:"worker_id"`
+	Action   string          `json:"action"`
+	Error    string          `json:"error"`
+	Request  DumpedRequest   `json:"request"`
+	Response *DumpedResponse `json:"response,omitempty"`
+}
+
+// ErrorDumper writes failed requests and their responses as JSON lines
+type ErrorDumper struct {
+	file    *os.File
+	encoder *json.Encoder
+	max     int
+	count   int
+	secrets bool
+	mu      sync.Mutex
+}
+
+// NewErrorDumper creates a dumper writing up to max records to filename.
+// Secret headers are redacted unless secrets is true.
+func NewErrorDumper(filename string, max int, secrets bool) (*ErrorDumper, error) {
+	file, err := os.Create(filename)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create error dump file: %w", err)
+	}
+
+	return &ErrorDumper{
+		file:    file,
+		encoder: json.NewEncoder(file),
+		max:     max,
+		secrets: secrets,
+	}, nil
+}
+
+// Dump writes a record unless the record limit has been reached
+func (d *ErrorDumper) Dump(record ErrorRecord) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.max > 0 && d.count >= d.max {
+		return
+	}
+	d.count++
+
+	d.encoder.Encode(record)
+}
+
+// Close closes the dump file
+func (d *ErrorDumper) Close() error {
+	return d.file.Close()
+}
+
+// dumpHeaders flattens headers, redacting secrets unless requested
+func (d *ErrorDumper) dumpHeaders(header http.Header) map[string]string {
+	return flattenHeaders(header, d.secrets)
+}
+
+// flattenHeaders joins each header's values, redacting sensitive headers
+// unless secrets is true
+func flattenHeaders(header http.Header, secrets bool) map[string]string {
+	result := make(map[string]string, len(header))
+	for key, values := range header {
+		if !secrets && sensitiveHeaders[http.CanonicalHeaderKey(key)] {
+			result[key] = redactedValue
+			continue
+		}
+		result[key] = strings.Join(values, ", ")
+	}
+	return result
+}
+
+// dumpFailure records the request a worker sent and the response it got
+func (w *Worker) dumpFailure(action string, req *http.Request, bodyContent string, resp *http.Response, respBody []byte, errorMsg string) {
+	d := w.errorDump
+	header := w.sentHeaders(req)
+	if !d.secrets {
+		bodyContent = w.redactPassword(bodyContent)
+	}
+
+	record := ErrorRecord{
+		Time:     time.Now(),
+		WorkerID: w.id,
+		Action:   action,
+		Error:    errorMsg,
+		Request: DumpedRequest{
+			Method:  req.Method,
+			URL:     req.URL.String(),
+			Headers: d.dumpHeaders(header),
+			Body:    bodyContent,
+		},
+	}
+
+	if resp != nil {
+		if len(respBody) > maxDumpBodyBytes {
+			respBody = respBody[:maxDumpBodyBytes]
+		}
+		record.Response = &DumpedResponse{
+			Status:  resp.StatusCode,
+			Headers: d.dumpHeaders(resp.Header),
+			Body:    string(respBody),
+		}
+	}
+
+	d.Dump(record)
+}
+
+// sentHeaders returns the headers of req as sent. Cookies are added by the
+// client from the jar, so they are recovered from it.
+func (w *Worker) sentHeaders(req *http.Request) http.Header {
+	header := req.Header.Clone()
+	if cookies := w.client.Jar.Cookies(req.URL); len(cookies) > 0 {
+		parts := make([]string, len(cookies))
+		for i, c := range cookies {
+			parts[i] = c.Name + "=" + c.Value
+		}
+		header.Set("Cookie", strings.Join(parts, "; "))
+	}
+	return header
+}
+
+// redactPassword replaces the user's password in a request body
+func (w *Worker) redactPassword(body string) string {
+	if w.credentials != nil {
+		if password := w.credentials.GetCredentialsForUser(w.id).Password; password != "" {
+			body = strings.ReplaceAll(body, password, redactedValue)
+		}
+	}
+	return body
+}
@@ -0,0 +1,176 @@
+package worker
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptrace"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// http10Transport sends requests as HTTP/1.0, which http.Transport can't:
+// it always writes HTTP/1.1. Like a legacy client, every request opens a
+// connection of its own that is closed with the response body.
+type http10Transport struct {
+	base *http.Transport
+	dial func(ctx context.Context, network, addr string) (net.Conn, error)
+}
+
+// newHTTP10Transport sends requests over connections dialed with base's
+// dialer and TLS config, so source IPs, Unix sockets, dial retries and TLS
+// session resumption still apply
+func newHTTP10Transport(base *http.Transport) *http10Transport {
+	dial := base.DialContext
+	if dial == nil {
+		dial = (&net.Dialer{Timeout: 30 * time.Second, KeepAlive: 30 * time.Second}).DialContext
+	}
+	return &http10Transport{base: base, dial: dial}
+}
+
+// RoundTrip implements http.RoundTripper
+func (t *http10Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	ctx := req.Context()
+	trace := httptrace.ContextClientTrace(ctx)
+
+	conn, err := t.connect(ctx, req, trace)
+	if err != nil {
+		if req.Body != nil {
+			req.Body.Close()
+		}
+		return nil, err
+	}
+	stop := context.AfterFunc(ctx, func() { conn.Close() })
+	fail := func(err error) (*http.Response, error) {
+		stop()
+		conn.Close()
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		return nil, err
+	}
+
+	if err := writeHTTP10Request(conn, req); err != nil {
+		return fail(err)
+	}
+	if trace != nil && trace.WroteRequest != nil {
+		trace.WroteRequest(httptrace.WroteRequestInfo{})
+	}
+
+	reader := bufio.NewReader(conn)
+	if _, err := reader.Peek(1); err != nil {
+		return fail(err)
+	}
+	if trace != nil && trace.GotFirstResponseByte != nil {
+		trace.GotFirstResponseByte()
+	}
+	resp, err := http.ReadResponse(reader, req)
+	if err != nil {
+		return fail(err)
+	}
+	resp.Body = &connBody{ReadCloser: resp.Body, conn: conn, stop: stop}
+	return resp, nil
+}
+
+// connect dials the request's host, with a TLS handshake for https:// URLs
+func (t *http10Transport) connect(ctx context.Context, req *http.Request, trace *httptrace.ClientTrace) (net.Conn, error) {
+	port := req.URL.Port()
+	if port == "" {
+		port = "80"
+		if req.URL.Scheme == "https" {
+			port = "443"
+		}
+	}
+
+	// The dialer reports DNS and connect events to the trace itself
+	conn, err := t.dial(ctx, "tcp", net.JoinHostPort(req.URL.Hostname(), port))
+	if err != nil {
+		return nil, err
+	}
+
+	if req.URL.Scheme == "https" {
+		cfg := t.base.TLSClientConfig.Clone()
+		if cfg.ServerName == "" {
+			cfg.ServerName = req.URL.Hostname()
+		}
+		if trace != nil && trace.TLSHandshakeStart != nil {
+			trace.TLSHandshakeStart()
+		}
+		tlsConn := tls.Client(conn, cfg)
+		err := tlsConn.HandshakeContext(ctx)
+		if trace != nil && trace.TLSHandshakeDone != nil {
+			trace.TLSHandshakeDone(tlsConn.ConnectionState(), err)
+		}
+		if err != nil {
+			conn.Close()
+			return nil, err
+		}
+		conn = tlsConn
+	}
+
+	if trace != nil && trace.GotConn != nil {
+		trace.GotConn(httptrace.GotConnInfo{Conn: conn})
+	}
+	return conn, nil
+}
+
+// writeHTTP10Request writes req to w with an HTTP/1.0 request line. The body
+// is buffered so it can be sent with a Content-Length, since HTTP/1.0 has no
+// chunked encoding.
+func writeHTTP10Request(w io.Writer, req *http.Request) error {
+	var body []byte
+	if req.Body != nil {
+		var err error
+		body, err = io.ReadAll(req.Body)
+		req.Body.Close()
+		if err != nil {
+			return fmt.Errorf("failed to read request body: %w", err)
+		}
+	}
+
+	host := req.Host
+	if host == "" {
+		host = req.URL.Host
+	}
+	header := req.Header.Clone()
+	if header == nil {
+		header = make(http.Header)
+	}
+	delete(header, "Host") // Sent from req.Host, as http.Transport does
+	if _, ok := header["User-Agent"]; !ok {
+		header.Set("User-Agent", "Go-http-client/1.0")
+	}
+	if len(body) > 0 || req.ContentLength > 0 {
+		header.Set("Content-Length", strconv.Itoa(len(body)))
+	}
+
+	bw := bufio.NewWriter(w)
+	fmt.Fprintf(bw, "%s %s HTTP/1.0\r\nHost: %s\r\n", req.Method, req.URL.RequestURI(), host)
+	header.Write(bw)
+	bw.WriteString("\r\n")
+	bw.Write(body)
+	return bw.Flush()
+}
+
+// connBody closes the connection of an HTTP/1.0 response with its body
+type connBody struct {
+	io.ReadCloser
+	conn net.Conn
+	stop func() bool
+	once sync.Once
+}
+
+// Close implements io.Closer
+func (b *connBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.once.Do(func() {
+		b.stop()
+		b.conn.Close()
+	})
+	return err
+}
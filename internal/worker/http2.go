@@ -0,0 +1,63 @@
+package worker
+
+import (
+	"context"
+	"crypto/tls"
+	"net"
+	"net/http"
+
+	"golang.org/x/net/http2"
+)
+
+// h2cTransport sends http:// requests as HTTP/2 with prior knowledge (h2c)
+// and https:// requests through the regular transport, which negotiates
+// HTTP/2 over TLS
+type h2cTransport struct {
+	h2c  *http2.Transport
+	base *http.Transport
+}
+
+// newH2CTransport wraps base so plain-text requests use h2c. Connections
+// are dialed with base's dialer, so source IPs, Unix sockets and dial
+// retries still apply.
+func newH2CTransport(base *http.Transport) *h2cTransport {
+	dial := base.DialContext
+	if dial == nil {
+		dial = (&net.Dialer{}).DialContext
+	}
+	return &h2cTransport{
+		base: base,
+		h2c: &http2.Transport{
+			AllowHTTP:          true,
+			DisableCompression: base.DisableCompression,
+			DialTLSContext: func(ctx context.Context, network, addr string, _ *tls.Config) (net.Conn, error) {
+				return dial(ctx, network, addr)
+			},
+		},
+	}
+}
+
+// RoundTrip implements http.RoundTripper
+func (t *h2cTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.URL.Scheme == "http" {
+		return t.h2c.RoundTrip(req)
+	}
+	return t.base.RoundTrip(req)
+}
+
+// withInsecureTLS returns a copy of rt with certificate verification set
+// as given. It handles the transports the worker builds: *http.Transport,
+// *h2cTransport and *http10Transport.
+func withInsecureTLS(rt http.RoundTripper, insecure bool) http.RoundTripper {
+	switch t := rt.(type) {
+	case *h2cTransport:
+		return newH2CTransport(withInsecureTLS(t.base, insecure).(*http.Transport))
+	case *http10Transport:
+		return newHTTP10Transport(withInsecureTLS(t.base, insecure).(*http.Transport))
+	case *http.Transport:
+		clone := t.Clone()
+		clone.TLSClientConfig.InsecureSkipVerify = insecure
+		return clone
+	}
+	return rt
+}
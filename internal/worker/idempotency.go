@@ -0,0 +1,109 @@
+package worker
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"math/rand"
+	"sync"
+
+	"stampede-shooter/internal/script"
+)
+
+// idempotencyPoolSize caps the sent requests remembered per action
+const idempotencyPoolSize = 1000
+
+// sentRequest is a request with an idempotency key and the response the
+// backend gave to its first delivery
+type sentRequest struct {
+	key      string
+	body     string
+	status   int
+	bodyHash [sha256.Size]byte
+}
+
+// IdempotencyPool remembers requests sent with an idempotency key, shared
+// across workers so any worker can resend any of them
+type IdempotencyPool struct {
+	mu      sync.Mutex
+	actions map[string][]sentRequest
+}
+
+// NewIdempotencyPool creates an empty idempotency pool
+func NewIdempotencyPool() *IdempotencyPool {
+	return &IdempotencyPool{actions: make(map[string][]sentRequest)}
+}
+
+// add remembers a first delivery, replacing a random entry once the action's
+// pool is full
+func (p *IdempotencyPool) add(action string, sent sentRequest, rng *rand.Rand) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	pool := p.actions[action]
+	if len(pool) < idempotencyPoolSize {
+		p.actions[action] = append(pool, sent)
+		return
+	}
+	pool[rng.Intn(len(pool))] = sent
+}
+
+// pick returns a random remembered request of the action
+func (p *IdempotencyPool) pick(action string, rng *rand.Rand) (sentRequest, bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	pool := p.actions[action]
+	if len(pool) == 0 {
+		return sentRequest{}, false
+	}
+	return pool[rng.Intn(len(pool))], true
+}
+
+// prepareIdempotency decides whether this request resends an earlier one.
+// A resend reuses the earlier key and body; otherwise the request gets a
+// fresh key unless the script already sets the idempotency header.
+func (w *Worker) prepareIdempotency(action *script.Action, bodyContent *string) (original sentRequest, resent bool) {
+	w.rngMu.Lock()
+	defer w.rngMu.Unlock()
+
+	if w.rng.Float64() < action.ResendFraction {
+		if original, ok := w.idempotency.pick(action.Name, w.rng); ok {
+			action.Headers[action.IdempotencyHeader] = original.key
+			*bodyContent = original.body
+			return original, true
+		}
+	}
+
+	if action.Headers[action.IdempotencyHeader] == "" {
+		action.Headers[action.IdempotencyHeader] = fmt.Sprintf("%016x%016x", w.rng.Uint64(), w.rng.Uint64())
+	}
+	return sentRequest{}, false
+}
+
+// verify checks that a resend got the original response back
+func (s sentRequest) verify(status int, body []byte) error {
+	if status != s.status {
+		return fmt.Errorf("resent key %s: got status %d, original was %d", s.key, status, s.status)
+	}
+	if sha256.Sum256(body) != s.bodyHash {
+		return fmt.Errorf("resent key %s: response body differs from the original", s.key)
+	}
+	return nil
+}
+
+// rememberSent adds a first delivery to the pool for later resends. Server
+// errors are skipped, as the backend may not have processed the request.
+func (w *Worker) rememberSent(action script.Action, bodyContent string, status int, body []byte) {
+	if status >= 500 {
+		return
+	}
+
+	w.rngMu.Lock()
+	defer w.rngMu.Unlock()
+	w.idempotency.add(action.Name, sentRequest{
+		key:      action.Headers[action.IdempotencyHeader],
+		body:     bodyContent,
+		status:   status,
+		bodyHash: sha256.Sum256(body),
+	}, w.rng)
+}
@@ -0,0 +1,59 @@
+package worker
+
+import (
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"stampede-shooter/internal/config"
+	"stampede-shooter/internal/metrics"
+	"stampede-shooter/internal/util"
+)
+
+func TestRememberSentOnlyAfterValidation(t *testing.T) {
+	// The second response goes backwards and fails the consistency check
+	versions := []int{5, 3}
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, `{"version": %d}`, versions[requests.Add(1)-1])
+	}))
+	defer server.Close()
+
+	s := loadTestScript(t, `
+- name: CreateDocument
+  method: POST
+  url: `+server.URL+`/documents
+  json_body: '{"title": "draft"}'
+  resend_fraction: 0.000001
+  consistency:
+    - name: doc-version
+      json: $.version
+      invariant: monotonic
+`)
+	collector := metrics.NewCollector(metrics.DefaultBufferSize)
+	collector.SetBlocking(true)
+	shared := &Shared{RetryBudget: util.NewRetryBudget(0), Consistency: NewConsistencyStore(), Idempotency: NewIdempotencyPool()}
+	w := New(1, config.Config{}, s, collector, nil, shared)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	collector.Start()
+	w.executeAction(ctx, s.Actions[0])
+	w.executeAction(ctx, s.Actions[0])
+	collector.Stop()
+	requireCounts(t, collector.GetStats(), "CreateDocument", 1, 1)
+
+	// Only the delivery that passed every check can be resent
+	pool := shared.Idempotency.actions["CreateDocument"]
+	if len(pool) != 1 {
+		t.Fatalf("%d requests remembered, want only the valid one", len(pool))
+	}
+	if pool[0].bodyHash != sha256.Sum256([]byte(`{"version": 5}`)) {
+		t.Error("remembered the response that failed the consistency check")
+	}
+}
@@ -0,0 +1,65 @@
+package worker
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// maxTraceBodyBytes caps the response body printed per traced request
+const maxTraceBodyBytes = 2048
+
+// traceMu keeps the traces of concurrent requests from interleaving
+var traceMu sync.Mutex
+
+// traceRequest prints a request and its response, or the error that ended
+// it, to stderr for --trace. Sensitive headers and the user's password are
+// redacted unless --trace-secrets is set.
+func (w *Worker) traceRequest(action string, req *http.Request, bodyContent string, resp *http.Response, respBody []byte, err error, latency time.Duration) {
+	if !w.traceSecrets {
+		bodyContent = w.redactPassword(bodyContent)
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "--- worker %d %s: %s %s\n", w.id, action, req.Method, req.URL)
+	writeTraceHeaders(&b, w.sentHeaders(req), w.traceSecrets)
+	if bodyContent != "" {
+		fmt.Fprintf(&b, "\n%s\n", strings.TrimRight(bodyContent, "\n"))
+	}
+
+	if err != nil {
+		fmt.Fprintf(&b, "<<< error after %v: %v\n", latency.Round(time.Microsecond), err)
+	} else {
+		fmt.Fprintf(&b, "<<< %s %s in %v\n", resp.Proto, resp.Status, latency.Round(time.Microsecond))
+		writeTraceHeaders(&b, resp.Header, w.traceSecrets)
+		if len(respBody) > 0 {
+			body, more := respBody, ""
+			if len(body) > maxTraceBodyBytes {
+				body, more = body[:maxTraceBodyBytes], fmt.Sprintf("\n... %d more bytes", len(respBody)-maxTraceBodyBytes)
+			}
+			fmt.Fprintf(&b, "\n%s%s\n", strings.TrimRight(string(body), "\n"), more)
+		}
+	}
+
+	traceMu.Lock()
+	defer traceMu.Unlock()
+	fmt.Fprintln(os.Stderr, b.String())
+}
+
+// writeTraceHeaders writes headers sorted by name, redacting sensitive ones
+// unless secrets is true
+func writeTraceHeaders(b *strings.Builder, header http.Header, secrets bool) {
+	flat := flattenHeaders(header, secrets)
+	names := make([]string, 0, len(flat))
+	for name := range flat {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Fprintf(b, "%s: %s\n", name, flat[name])
+	}
+}
@@ -0,0 +1,133 @@
+package worker
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"stampede-shooter/internal/config"
+)
+
+// retryScript is a placeholder script for tests that only exercise retry
+// decisions
+const retryScript = `
+- name: Home
+  method: GET
+  url: http://localhost/
+`
+
+func TestBackoffDoubles(t *testing.T) {
+	cfg := config.Config{RetryBackoff: 100 * time.Millisecond}
+	w, _ := newTestWorker(t, cfg, loadTestScript(t, retryScript))
+
+	for attempt, want := range []time.Duration{100, 200, 400, 800} {
+		if got := w.backoff(attempt); got != want*time.Millisecond {
+			t.Errorf("backoff(%d) = %s, want %s", attempt, got, want*time.Millisecond)
+		}
+	}
+}
+
+func TestBackoffJitter(t *testing.T) {
+	const samples = 1000
+	cfg := config.Config{RetryBackoff: 100 * time.Millisecond, RetryJitter: true, Seed: 42}
+	w, _ := newTestWorker(t, cfg, loadTestScript(t, retryScript))
+
+	for attempt := 0; attempt < 4; attempt++ {
+		ceiling := cfg.RetryBackoff << attempt
+		distinct := make(map[time.Duration]bool)
+		var sum time.Duration
+		var low, high int // Draws in the bottom and top quarter
+		for i := 0; i < samples; i++ {
+			delay := w.backoff(attempt)
+			if delay < 0 || delay > ceiling {
+				t.Fatalf("backoff(%d) = %s, want between 0 and %s", attempt, delay, ceiling)
+			}
+			distinct[delay] = true
+			sum += delay
+			switch {
+			case delay < ceiling/4:
+				low++
+			case delay > ceiling*3/4:
+				high++
+			}
+		}
+
+		// Full jitter is uniform over [0, ceiling]: the mean sits near the
+		// middle and both ends of the range are drawn
+		mean := sum / samples
+		if mean < ceiling*4/10 || mean > ceiling*6/10 {
+			t.Errorf("attempt %d: mean backoff %s, want about %s", attempt, mean, ceiling/2)
+		}
+		if low < samples/8 || high < samples/8 {
+			t.Errorf("attempt %d: %d draws below %s and %d above %s, want delays spread over the range",
+				attempt, low, ceiling/4, high, ceiling*3/4)
+		}
+		if len(distinct) < samples*9/10 {
+			t.Errorf("attempt %d: only %d distinct delays in %d draws", attempt, len(distinct), samples)
+		}
+	}
+}
+
+func TestBackoffJitterSeeded(t *testing.T) {
+	cfg := config.Config{RetryBackoff: 100 * time.Millisecond, RetryJitter: true, Seed: 42}
+	s := loadTestScript(t, retryScript)
+	a, _ := newTestWorker(t, cfg, s)
+	b, _ := newTestWorker(t, cfg, s)
+
+	for attempt := 0; attempt < 10; attempt++ {
+		if da, db := a.backoff(attempt%4), b.backoff(attempt%4); da != db {
+			t.Fatalf("draw %d: %s and %s with the same --seed, want identical delays", attempt, da, db)
+		}
+	}
+}
+
+func TestRetryIfBodyContains(t *testing.T) {
+	tests := []struct {
+		name        string
+		pending     int64 // Responses saying "pending" before the result
+		retries     int   // --retries
+		wantHits    int64
+		wantRetries int64
+	}{
+		{"succeeds after retries", 2, 3, 3, 2},
+		{"no match", 0, 3, 1, 0},
+		{"retries exhausted", 5, 2, 3, 2},
+		{"retries disabled", 2, 0, 1, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var hits atomic.Int64
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				// A 200 the retry pattern has to catch; status codes alone
+				// would not trigger a retry
+				if hits.Add(1) <= tt.pending {
+					fmt.Fprint(w, `{"status": "pending"}`)
+					return
+				}
+				fmt.Fprint(w, `{"status": "done"}`)
+			}))
+			defer server.Close()
+
+			s := loadTestScript(t, `
+- name: Poll
+  method: GET
+  url: `+server.URL+`
+  retry_if_body_contains: '"pending"'
+`)
+			cfg := config.Config{Retries: tt.retries, RetryBackoff: time.Millisecond}
+			w, collector := newTestWorker(t, cfg, s)
+			stats := runActions(t, w, collector)
+
+			requireCounts(t, stats, "Poll", 1, 0)
+			if got := hits.Load(); got != tt.wantHits {
+				t.Errorf("server received %d requests, want %d", got, tt.wantHits)
+			}
+			if got := stats["Poll"].Retries; got != tt.wantRetries {
+				t.Errorf("recorded %d retries, want %d", got, tt.wantRetries)
+			}
+		})
+	}
+}
@@ -0,0 +1,67 @@
+package worker
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// parseServerTime reads the server-reported processing time from the named
+// response header. Server-Timing headers use the "total" metric's dur if
+// present and otherwise the sum of all durations. Other headers may hold a
+// Go duration ("12ms", "0.5s") or a bare number of milliseconds.
+func parseServerTime(header http.Header, name string) (time.Duration, bool) {
+	if http.CanonicalHeaderKey(name) == "Server-Timing" {
+		return serverTimingDuration(header.Values("Server-Timing"))
+	}
+
+	value := strings.TrimSpace(header.Get(name))
+	if value == "" {
+		return 0, false
+	}
+	if d, err := time.ParseDuration(value); err == nil && d > 0 {
+		return d, true
+	}
+	return millisDuration(value)
+}
+
+// serverTimingDuration extracts the processing time from Server-Timing values
+func serverTimingDuration(values []string) (time.Duration, bool) {
+	var sum time.Duration
+	found := false
+
+	for _, value := range values {
+		for _, entry := range strings.Split(value, ",") {
+			params := strings.Split(entry, ";")
+			metricName := strings.TrimSpace(params[0])
+
+			for _, param := range params[1:] {
+				key, val, ok := strings.Cut(strings.TrimSpace(param), "=")
+				if !ok || !strings.EqualFold(key, "dur") {
+					continue
+				}
+				d, ok := millisDuration(strings.Trim(val, `"`))
+				if !ok {
+					continue
+				}
+				if strings.EqualFold(metricName, "total") {
+					return d, true
+				}
+				sum += d
+				found = true
+			}
+		}
+	}
+
+	return sum, found && sum > 0
+}
+
+// millisDuration parses a (possibly fractional) number of milliseconds
+func millisDuration(value string) (time.Duration, bool) {
+	ms, err := strconv.ParseFloat(strings.TrimSuffix(strings.TrimSpace(value), "ms"), 64)
+	if err != nil || ms <= 0 {
+		return 0, false
+	}
+	return time.Duration(ms * float64(time.Millisecond)), true
+}
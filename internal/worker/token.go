@@ -0,0 +1,82 @@
+package worker
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// updateTokenExpiry records when the current session token expires, using the
+// configured expiry source: "jwt" decodes the exp claim of the Authorization
+// token, "header:<Name>" reads a response header holding either seconds until
+// expiry or an absolute HTTP/RFC3339 timestamp.
+func (w *Worker) updateTokenExpiry(resp *http.Response) {
+	var expiry time.Time
+	var ok bool
+
+	switch {
+	case w.tokenExpirySource == "jwt":
+		expiry, ok = jwtExpiry(resp.Header.Get("Authorization"))
+	case strings.HasPrefix(w.tokenExpirySource, "header:"):
+		expiry, ok = headerExpiry(resp.Header.Get(strings.TrimPrefix(w.tokenExpirySource, "header:")))
+	}
+
+	if ok {
+		w.tokenExpiry = expiry
+	}
+}
+
+// tokenNeedsRefresh reports whether the session token is about to expire
+func (w *Worker) tokenNeedsRefresh() bool {
+	if w.tokenExpiry.IsZero() {
+		return false
+	}
+	return time.Now().Add(w.tokenRefreshMargin).After(w.tokenExpiry)
+}
+
+// jwtExpiry extracts the exp claim from a (optionally Bearer-prefixed) JWT
+func jwtExpiry(token string) (time.Time, bool) {
+	token = strings.TrimSpace(strings.TrimPrefix(token, "Bearer "))
+
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return time.Time{}, false
+	}
+
+	payload, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(parts[1], "="))
+	if err != nil {
+		return time.Time{}, false
+	}
+
+	var claims struct {
+		Exp float64 `json:"exp"`
+	}
+	if err := json.Unmarshal(payload, &claims); err != nil || claims.Exp == 0 {
+		return time.Time{}, false
+	}
+
+	return time.Unix(int64(claims.Exp), 0), true
+}
+
+// headerExpiry parses an expiry header as seconds-until-expiry or a timestamp
+func headerExpiry(value string) (time.Time, bool) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return time.Time{}, false
+	}
+
+	if seconds, err := strconv.ParseInt(value, 10, 64); err == nil {
+		return time.Now().Add(time.Duration(seconds) * time.Second), true
+	}
+	if t, err := http.ParseTime(value); err == nil {
+		return t, true
+	}
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, true
+	}
+
+	return time.Time{}, false
+}
@@ -0,0 +1,112 @@
+package worker
+
+import (
+	"context"
+	"crypto/tls"
+	"net/http/httptrace"
+	"sync"
+	"time"
+
+	"stampede-shooter/internal/metrics"
+)
+
+// requestTrace records connection-level events of a single request attempt.
+// Dial callbacks may run on another goroutine, so fields are guarded by mu.
+type requestTrace struct {
+	mu           sync.Mutex
+	tlsHandshake bool // A TLS handshake was performed for this request
+	tlsResumed   bool // The handshake resumed a previous TLS session
+	gotConn      bool // A connection was obtained for the request
+	connReused   bool // The connection was reused from the idle pool
+
+	// Phase boundaries; zero when the phase did not happen
+	dnsStart, dnsDone         time.Time
+	connectStart, connectDone time.Time
+	tlsStart, tlsDone         time.Time
+	wroteRequest, firstByte   time.Time
+}
+
+// withTrace attaches an httptrace.ClientTrace that fills t to ctx
+func (t *requestTrace) withTrace(ctx context.Context) context.Context {
+	return httptrace.WithClientTrace(ctx, &httptrace.ClientTrace{
+		GotConn: func(info httptrace.GotConnInfo) {
+			t.mu.Lock()
+			t.gotConn = true
+			t.connReused = info.Reused
+			t.mu.Unlock()
+		},
+		DNSStart: func(httptrace.DNSStartInfo) {
+			t.mark(&t.dnsStart)
+		},
+		DNSDone: func(httptrace.DNSDoneInfo) {
+			t.mark(&t.dnsDone)
+		},
+		ConnectStart: func(string, string) {
+			t.mu.Lock()
+			// Several addresses may be dialed; time from the first attempt
+			if t.connectStart.IsZero() {
+				t.connectStart = time.Now()
+			}
+			t.mu.Unlock()
+		},
+		ConnectDone: func(_, _ string, err error) {
+			if err == nil {
+				t.mark(&t.connectDone)
+			}
+		},
+		TLSHandshakeStart: func() {
+			t.mark(&t.tlsStart)
+		},
+		TLSHandshakeDone: func(state tls.ConnectionState, err error) {
+			if err != nil {
+				return
+			}
+			t.mu.Lock()
+			t.tlsDone = time.Now()
+			t.tlsHandshake = true
+			t.tlsResumed = state.DidResume
+			t.mu.Unlock()
+		},
+		WroteRequest: func(httptrace.WroteRequestInfo) {
+			t.mark(&t.wroteRequest)
+		},
+		GotFirstResponseByte: func() {
+			t.mark(&t.firstByte)
+		},
+	})
+}
+
+// mark sets field to the current time
+func (t *requestTrace) mark(field *time.Time) {
+	t.mu.Lock()
+	*field = time.Now()
+	t.mu.Unlock()
+}
+
+// apply copies the traced events and phase durations onto a metric whose
+// StartTime marks when the request was sent and EndTime when the response
+// body was fully read
+func (t *requestTrace) apply(metric *metrics.RequestMetric) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	metric.TLSHandshake = t.tlsHandshake
+	metric.TLSResumed = t.tlsResumed
+	metric.NewConn = t.gotConn && !t.connReused
+
+	metric.Phases[metrics.PhaseDNS] = phaseDuration(t.dnsStart, t.dnsDone)
+	metric.Phases[metrics.PhaseConnect] = phaseDuration(t.connectStart, t.connectDone)
+	metric.Phases[metrics.PhaseTLS] = phaseDuration(t.tlsStart, t.tlsDone)
+	metric.Phases[metrics.PhaseServer] = phaseDuration(t.wroteRequest, t.firstByte)
+	metric.Phases[metrics.PhaseBody] = phaseDuration(t.firstByte, metric.EndTime)
+	metric.TTFB = phaseDuration(metric.StartTime, t.firstByte)
+}
+
+// phaseDuration returns the time between start and end, or 0 if the phase
+// did not complete
+func phaseDuration(start, end time.Time) time.Duration {
+	if start.IsZero() || end.IsZero() || end.Before(start) {
+		return 0
+	}
+	return end.Sub(start)
+}
@@ -0,0 +1,221 @@
+package worker
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"golang.org/x/net/websocket"
+
+	"stampede-shooter/internal/metrics"
+	"stampede-shooter/internal/script"
+	"stampede-shooter/internal/util"
+)
+
+// webSocketProto is the protocol WebSocket metrics are reported under
+const webSocketProto = "websocket"
+
+// executeWebSocket opens an expanded WebSocket action's connection and
+// exchanges its messages. The handshake is recorded under the action's name
+// and the round trip of each awaited reply under the message's own name, so
+// connect time and message latency are reported apart.
+func (w *Worker) executeWebSocket(ctx context.Context, action script.Action, creds util.Credentials) {
+	action.URL = webSocketURL(action.URL)
+
+	config, err := w.webSocketConfig(ctx, action, creds)
+	if err != nil {
+		now := time.Now()
+		w.recordMetric(action, metrics.RequestMetric{
+			StartTime: now,
+			EndTime:   now,
+			Error:     err.Error(),
+		})
+		return
+	}
+
+	dialCtx, cancel := requestContext(ctx, action)
+	metric := metrics.RequestMetric{StartTime: time.Now(), Proto: webSocketProto, NewConn: true}
+	w.collector.RequestStarted()
+	conn, err := config.DialContext(dialCtx)
+	if err != nil && dialCtx.Err() != nil && ctx.Err() == nil {
+		err = fmt.Errorf("timeout after %v: %w", requestTimeout(action), err)
+	}
+	cancel()
+	metric.EndTime = time.Now()
+	w.collector.RequestFinished()
+	if err != nil && stopped(ctx) {
+		w.collector.RecordCancelledRequest()
+		return
+	}
+	if err != nil {
+		metric.Error = err.Error()
+		w.recordMetric(action, metric)
+		return
+	}
+	defer conn.Close()
+
+	metric.StatusCode = http.StatusSwitchingProtocols
+	w.recordMetric(action, metric)
+
+	// Unblock a pending read when the test ends
+	stop := context.AfterFunc(ctx, func() { conn.Close() })
+	defer stop()
+
+	for _, msg := range action.WebSocket.Messages {
+		if ctx.Err() != nil {
+			return
+		}
+		if !w.exchangeMessage(ctx, conn, action, msg) {
+			return
+		}
+	}
+}
+
+// exchangeMessage sends a message and waits for its expected reply,
+// recording the round trip. It returns false if the connection can't be used
+// for further messages.
+func (w *Worker) exchangeMessage(ctx context.Context, conn *websocket.Conn, action script.Action, msg script.WebSocketMessage) bool {
+	msgAction := action
+	msgAction.Name = script.MessageMetricName(action.Name, msg)
+	timeout := requestTimeout(action)
+
+	metric := metrics.RequestMetric{StartTime: time.Now(), Proto: webSocketProto}
+	w.collector.RequestStarted()
+	err := sendAndAwait(conn, msg, timeout)
+	metric.EndTime = time.Now()
+	w.collector.RequestFinished()
+
+	// The test ended mid-exchange; a cut-off reply is not a failure
+	if err != nil && ctx.Err() != nil {
+		if stopped(ctx) {
+			w.collector.RecordCancelledRequest()
+		}
+		return false
+	}
+
+	// Fire-and-forget messages only report failing to send
+	if msg.Expect == "" && err == nil {
+		return true
+	}
+
+	if err != nil {
+		metric.Error = err.Error()
+	} else {
+		metric.StatusCode = http.StatusOK
+	}
+	w.recordMetric(msgAction, metric)
+	return err == nil
+}
+
+// sendAndAwait sends the message's text, if any, and reads replies until one
+// contains the expected text, if any, or timeout elapses
+func sendAndAwait(conn *websocket.Conn, msg script.WebSocketMessage, timeout time.Duration) error {
+	deadline := time.Now().Add(timeout)
+	conn.SetDeadline(deadline)
+
+	if msg.Send != "" {
+		if err := websocket.Message.Send(conn, msg.Send); err != nil {
+			return err
+		}
+	}
+	if msg.Expect == "" {
+		return nil
+	}
+
+	for {
+		var reply string
+		if err := websocket.Message.Receive(conn, &reply); err != nil {
+			var netErr net.Error
+			if errors.As(err, &netErr) && netErr.Timeout() {
+				return fmt.Errorf("timeout after %v waiting for a reply containing %q", timeout, msg.Expect)
+			}
+			return err
+		}
+		if strings.Contains(reply, msg.Expect) {
+			return nil
+		}
+	}
+}
+
+// webSocketConfig builds the handshake of an expanded WebSocket action with
+// the same headers, session cookies and token as an HTTP request
+func (w *Worker) webSocketConfig(ctx context.Context, action script.Action, creds util.Credentials) (*websocket.Config, error) {
+	req, err := w.newRequest(ctx, action, "")
+	if err != nil {
+		return nil, err
+	}
+	if err := checkWebSocketURL(req); err != nil {
+		return nil, err
+	}
+	if creds.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+creds.Token)
+	}
+
+	origin := req.Header.Get("Origin")
+	if origin == "" {
+		origin = httpURL(req.URL).Scheme + "://" + req.URL.Host
+	}
+	config, err := websocket.NewConfig(req.URL.String(), origin)
+	if err != nil {
+		return nil, err
+	}
+
+	req.Header.Del("Origin")
+	for key, values := range req.Header {
+		config.Header[key] = values
+	}
+
+	// The cookie jar holds cookies by http(s) URL
+	for _, cookie := range w.client.Jar.Cookies(httpURL(req.URL)) {
+		config.Header.Add("Cookie", cookie.String())
+	}
+
+	insecure := w.insecureTLS
+	if action.InsecureTLS != nil {
+		insecure = *action.InsecureTLS
+	}
+	config.TlsConfig = &tls.Config{InsecureSkipVerify: insecure}
+	config.Dialer = w.wsDialer
+	return config, nil
+}
+
+// webSocketURL returns raw with an http(s) scheme replaced by ws(s), so
+// relative URLs resolved against --base-url can be opened as WebSockets
+func webSocketURL(raw string) string {
+	switch {
+	case strings.HasPrefix(raw, "http://"):
+		return "ws://" + strings.TrimPrefix(raw, "http://")
+	case strings.HasPrefix(raw, "https://"):
+		return "wss://" + strings.TrimPrefix(raw, "https://")
+	}
+	return raw
+}
+
+// httpURL returns a copy of a ws(s) URL with the matching http(s) scheme
+func httpURL(u *url.URL) *url.URL {
+	converted := *u
+	switch u.Scheme {
+	case "ws":
+		converted.Scheme = "http"
+	case "wss":
+		converted.Scheme = "https"
+	}
+	return &converted
+}
+
+// checkWebSocketURL rejects WebSocket URLs that parse but can't be opened
+func checkWebSocketURL(req *http.Request) error {
+	if req.URL.Scheme != "ws" && req.URL.Scheme != "wss" {
+		return fmt.Errorf("URL %q must start with ws://, wss://, http:// or https://", req.URL)
+	}
+	if req.URL.Host == "" {
+		return fmt.Errorf("URL %q has no host", req.URL)
+	}
+	return nil
+}
@@ -4,13 +4,20 @@ import (
 	"bytes"
 	"context"
 	"crypto/tls"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
+	"log/slog"
+	"math/rand"
+	"net"
 	"net/http"
-	"net/http/cookiejar"
 	"net/url"
+	"os"
 	"regexp"
+	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"stampede-shooter/internal/config"
@@ -24,33 +31,155 @@ type Worker struct {
 	id             int
 	client         *http.Client
 	rateLimiter    *util.RateLimiter
+	globalLimit    *util.RateLimiter          // Caps the combined rate of all workers, nil without --global-rps
+	insecureTLS    bool                       // --insecure-tls, the default client's setting
+	clients        map[clientKey]*http.Client // Clients for actions overriding TLS
+	clientsMu      sync.Mutex                 // Guards clients
 	script         *script.Script
+	steps          [][]script.Action    // Script actions grouped for parallel execution
+	picker         *script.ActionPicker // Picks one action per iteration in weighted mode, nil for sequential
 	collector      *metrics.Collector
 	loginHeader    string
+	loginBody      string // Login body template, expanded with the user's credentials
+	loginStagger   time.Duration
+	idOffset       int                      // User IDs of this run start at idOffset+1
+	timeScale      float64                  // Multiplier applied to all action delays
 	sessionHeaders map[string]string        // Persistent headers across requests
 	csrfToken      string                   // Current CSRF token for Rails apps
+	vars           map[string]string        // Values captured from responses, for {{var name}}
+	strictCapture  bool                     // A missed capture fails the request
+	sessionMu      sync.Mutex               // Guards session state shared by parallel actions
 	credentials    *util.CredentialsManager // Credentials manager for authentication
+	provider       util.CredentialProvider  // Supplies each request's credentials, nil without any
+	retries        int                      // Maximum retries per request
+	retryBackoff   time.Duration            // Base backoff between retries
+	retryBudget    *util.RetryBudget        // Test-wide retry budget
+	requestLimit   *util.RequestLimit       // Test-wide request cap, nil without --max-requests
+	retryJitter    bool                     // Use full jitter for retry backoff
+	rng            *rand.Rand               // Per-worker random source
+	rngMu          sync.Mutex               // Guards rng across parallel actions
+	errorDump      *ErrorDumper             // Optional dump of failed requests
+	serverTimeHdr  string                   // Response header with server processing time
+	observed       map[string]string        // Last value of each monotonic consistency check
+	consistency    *ConsistencyStore        // Test-wide values of equal consistency checks
+	idempotency    *IdempotencyPool         // Requests available for idempotency resends
+	proxies        *util.ProxyPool          // Optional proxy pool
+	arrivals       <-chan time.Time         // Open-arrival schedule; nil in closed mode
+	baseURL        *url.URL                 // --base-url, nil without one
+	logFailures    bool                     // Log each failed request as a structured record
+	trace          bool                     // Print every request and response (--trace)
+	traceSecrets   bool                     // Don't redact secrets from traces
+	wsDialer       *net.Dialer              // Dials WebSocket connections, bound to the worker's source IP
+	dumpCookies    bool                     // Print the jar once logged in (--dump-cookies)
+
+	// Session token expiry tracking for proactive re-login
+	loginURL           string
+	loginLimit         *util.RateLimiter
+	tokenExpirySource  string
+	tokenRefreshMargin time.Duration
+	tokenExpiry        time.Time
+}
+
+// Shared holds state shared by all workers in a test run
+type Shared struct {
+	RetryBudget  *util.RetryBudget
+	SourceIPs    []net.IP             // Local addresses assigned round-robin by worker ID
+	RateLimiter  *util.RateLimiter    // Replaces per-worker rate limiting when set
+	ErrorDump    *ErrorDumper         // Records failed requests when set
+	LoginLimit   *util.RateLimiter    // Caps login requests across workers when set
+	GlobalLimit  *util.RateLimiter    // Caps the combined request rate of all workers when set
+	Consistency  *ConsistencyStore    // Reference values for cross-worker consistency checks
+	Idempotency  *IdempotencyPool     // Sent requests shared for idempotency resends
+	Proxies      *util.ProxyPool      // Proxies assigned round-robin by worker ID when set
+	Proxy        *url.URL             // Single proxy for all workers when set
+	BaseURL      *url.URL             // Relative action and login URLs are resolved against it when set
+	Arrivals     <-chan time.Time     // Scheduled iteration starts in open-arrival mode
+	Picker       *script.ActionPicker // Picks one action per iteration in weighted mode when set
+	RequestLimit *util.RequestLimit   // Caps the requests of all workers when set
+	Cookies      []*http.Cookie       // Seeded into every worker's jar for CookieURL
+	CookieURL    *url.URL             // The target host the seeded cookies are set for
+
+	// Supplies user credentials, e.g. OAuth tokens, instead of the
+	// credentials file when set
+	Credentials util.CredentialProvider
 }
 
 // New creates a new worker
-func New(id int, cfg config.Config, script *script.Script, collector *metrics.Collector, credentials *util.CredentialsManager) *Worker {
+func New(id int, cfg config.Config, script *script.Script, collector *metrics.Collector, credentials *util.CredentialsManager, shared *Shared) *Worker {
 	// Configure HTTP client with cookie jar for session persistence
-	jar, _ := cookiejar.New(nil)
+	jar := newRecordingJar()
+	if len(shared.Cookies) > 0 {
+		jar.SetCookies(shared.CookieURL, shared.Cookies)
+	}
 
 	transport := &http.Transport{
-		MaxIdleConns:        100,
-		MaxIdleConnsPerHost: 10,
-		IdleConnTimeout:     30 * time.Second,
+		MaxIdleConns:        cfg.MaxIdleConns,
+		MaxIdleConnsPerHost: cfg.MaxIdlePerHost,
+		MaxConnsPerHost:     cfg.MaxConnsPerHost,
+		IdleConnTimeout:     cfg.ConnIdleTimeout,
 		DisableCompression:  true,
+		DisableKeepAlives:   cfg.DisableKeepAlive,
+	}
+
+	// Bind outgoing connections to this worker's source IP
+	wsDialer := &net.Dialer{Timeout: 30 * time.Second}
+	if len(shared.SourceIPs) > 0 {
+		dialer := &net.Dialer{
+			Timeout:   30 * time.Second,
+			KeepAlive: 30 * time.Second,
+			LocalAddr: &net.TCPAddr{IP: shared.SourceIPs[(id-1)%len(shared.SourceIPs)]},
+		}
+		transport.DialContext = dialer.DialContext
+		wsDialer = dialer
+	}
+
+	// Dial a local Unix socket regardless of the URL's host
+	if cfg.UnixSocket != "" {
+		dialer := &net.Dialer{Timeout: 30 * time.Second}
+		transport.DialContext = func(ctx context.Context, _, _ string) (net.Conn, error) {
+			return dialer.DialContext(ctx, "unix", cfg.UnixSocket)
+		}
+	}
+
+	// Retry failed connection attempts before failing the request
+	if cfg.DialRetries > 0 {
+		dial := transport.DialContext
+		if dial == nil {
+			dial = (&net.Dialer{Timeout: 30 * time.Second, KeepAlive: 30 * time.Second}).DialContext
+		}
+		transport.DialContext = retryDial(dial, cfg.DialRetries, collector)
+	}
+
+	// Route this worker through its proxy from the pool, the --proxy, or
+	// the proxy from the environment like Go's default transport
+	switch {
+	case shared.Proxies != nil:
+		transport.Proxy = shared.Proxies.ProxyFunc(id)
+	case shared.Proxy != nil:
+		transport.Proxy = http.ProxyURL(shared.Proxy)
+	default:
+		transport.Proxy = http.ProxyFromEnvironment
+	}
+
+	// Cache TLS sessions so resumption is exercised across connections
+	transport.TLSClientConfig = &tls.Config{InsecureSkipVerify: cfg.InsecureTLS}
+	if cfg.TLSSessionCache > 0 {
+		transport.TLSClientConfig.ClientSessionCache = tls.NewLRUClientSessionCache(cfg.TLSSessionCache)
 	}
 
-	if cfg.InsecureTLS {
-		transport.TLSClientConfig = &tls.Config{InsecureSkipVerify: true}
+	// A custom TLS config disables HTTP/2 unless it is asked for explicitly
+	transport.ForceAttemptHTTP2 = cfg.HTTP2 || cfg.ForceHTTP2
+	var roundTripper http.RoundTripper = transport
+	switch {
+	case cfg.ForceHTTP2:
+		roundTripper = newH2CTransport(transport)
+	case cfg.HTTP10:
+		roundTripper = newHTTP10Transport(transport)
 	}
 
+	// Requests are bounded per request by requestContext, not by the client
 	client := &http.Client{
-		Transport: transport,
-		Timeout:   30 * time.Second,
+		Transport: roundTripper,
 		Jar:       jar, // Enable cookie persistence
 		CheckRedirect: func(req *http.Request, via []*http.Request) error {
 			// Allow up to 10 redirects (default behavior)
@@ -61,29 +190,113 @@ func New(id int, cfg config.Config, script *script.Script, collector *metrics.Co
 		},
 	}
 
+	// The arrival schedule alone paces workers in open-arrival mode
+	rateLimiter := shared.RateLimiter
+	if rateLimiter == nil && shared.Arrivals == nil && cfg.RPS > 0 {
+		rateLimiter = util.NewRateLimiter(cfg.RPS)
+	}
+
+	var provider util.CredentialProvider = shared.Credentials
+	if provider == nil && credentials != nil {
+		provider = credentials
+	}
+
+	// Each worker draws from its own source; a fixed --seed makes every
+	// worker's sequence of random values reproducible
+	seed := time.Now().UnixNano()
+	if cfg.Seed != 0 {
+		seed = cfg.Seed
+	}
+
 	return &Worker{
 		id:             id,
 		client:         client,
-		rateLimiter:    util.NewRateLimiter(cfg.RPS),
+		rateLimiter:    rateLimiter,
+		globalLimit:    shared.GlobalLimit,
+		insecureTLS:    cfg.InsecureTLS,
+		clients:        make(map[clientKey]*http.Client),
 		script:         script,
+		steps:          script.Steps(),
+		picker:         shared.Picker,
 		collector:      collector,
 		loginHeader:    cfg.LoginHeader,
+		loginBody:      cfg.LoginBody,
+		loginStagger:   cfg.LoginStagger,
+		idOffset:       cfg.UserIDOffset,
+		timeScale:      cfg.TimeScale,
 		sessionHeaders: make(map[string]string),
+		vars:           make(map[string]string),
 		credentials:    credentials,
+		provider:       provider,
+		retries:        cfg.Retries,
+		retryBackoff:   cfg.RetryBackoff,
+		retryBudget:    shared.RetryBudget,
+		requestLimit:   shared.RequestLimit,
+		retryJitter:    cfg.RetryJitter,
+		rng:            rand.New(rand.NewSource(seed + int64(id))),
+		errorDump:      shared.ErrorDump,
+		serverTimeHdr:  cfg.ServerTimeHeader,
+		strictCapture:  cfg.FailOnCaptureMiss,
+		observed:       make(map[string]string),
+		consistency:    shared.Consistency,
+		idempotency:    shared.Idempotency,
+		proxies:        shared.Proxies,
+		arrivals:       shared.Arrivals,
+		baseURL:        shared.BaseURL,
+		logFailures:    cfg.LogFormat == util.LogFormatJSON,
+		trace:          cfg.Trace,
+		traceSecrets:   cfg.TraceSecrets,
+		wsDialer:       wsDialer,
+		dumpCookies:    cfg.DumpCookies && id == cfg.UserIDOffset+1,
+
+		loginLimit:         shared.LoginLimit,
+		tokenExpirySource:  cfg.TokenExpirySource,
+		tokenRefreshMargin: cfg.TokenRefreshMargin,
 	}
 }
 
-// Run executes the worker's test script
+// ErrStopped is the cause a worker's context is cancelled with when the
+// worker is stopped before the end of the test, e.g. by a stage lowering the
+// user count. Requests it cuts off are counted as cancelled, not as errors.
+var ErrStopped = errors.New("worker stopped")
+
+// stopped reports whether ctx was cancelled by stopping the worker
+func stopped(ctx context.Context) bool {
+	return errors.Is(context.Cause(ctx), ErrStopped)
+}
+
+// Run executes the worker's test script until ctx is done
 func (w *Worker) Run(ctx context.Context, loginURL string) error {
+	w.loginURL = loginURL
+
 	// Optional login step
 	if loginURL != "" {
+		// Spread logins out so the auth endpoint doesn't see a synchronized spike
+		if w.loginStagger > 0 {
+			select {
+			case <-ctx.Done():
+				return nil
+			case <-time.After(time.Duration(w.id-1-w.idOffset) * w.loginStagger):
+			}
+		}
+
 		if err := w.login(ctx, loginURL); err != nil {
 			return fmt.Errorf("login failed: %w", err)
 		}
+		if w.dumpCookies {
+			w.DumpCookies(os.Stderr, "after login")
+		}
+	} else if w.dumpCookies {
+		w.DumpCookies(os.Stderr, "at start")
 	}
 
-	// Execute script actions in a loop until context is cancelled
-	for {
+	if w.arrivals != nil {
+		return w.runArrivals(ctx)
+	}
+
+	// Execute script actions in a loop until context is cancelled or the
+	// request cap is used up
+	for w.requestsLeft() {
 		select {
 		case <-ctx.Done():
 			return nil
@@ -94,24 +307,60 @@ func (w *Worker) Run(ctx context.Context, loginURL string) error {
 			}
 		}
 	}
+	return nil
 }
 
-// login performs the optional login request
+// runArrivals executes one script iteration per scheduled arrival, recording
+// how long each arrival queued for this worker
+func (w *Worker) runArrivals(ctx context.Context) error {
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case scheduled := <-w.arrivals:
+			w.collector.RecordQueueTime(time.Since(scheduled))
+			w.executeScript(ctx)
+			if !w.requestsLeft() {
+				return nil
+			}
+		}
+	}
+}
+
+// requestsLeft reports whether the --max-requests cap, if any, leaves
+// requests to send
+func (w *Worker) requestsLeft() bool {
+	return w.requestLimit == nil || !w.requestLimit.Exhausted()
+}
+
+// login performs the optional login request, authenticating as the user's
+// assigned credentials when a login body is configured
 func (w *Worker) login(ctx context.Context, loginURL string) error {
-	req, err := http.NewRequestWithContext(ctx, "POST", loginURL, nil)
+	var creds util.Credentials
+	if w.loginBody != "" {
+		var err error
+		if creds, err = w.userCredentials(ctx); err != nil {
+			return err
+		}
+	}
+
+	req, err := w.newLoginRequest(ctx, loginURL, creds)
 	if err != nil {
 		return err
 	}
 
-	// Add login header if provided
-	if w.loginHeader != "" {
-		parts := strings.SplitN(w.loginHeader, ":", 2)
-		if len(parts) == 2 {
-			req.Header.Set(strings.TrimSpace(parts[0]), strings.TrimSpace(parts[1]))
+	// Bound auth traffic independently of the main load
+	if w.loginLimit != nil {
+		if err := w.loginLimit.WaitContext(ctx); err != nil {
+			return err
 		}
 	}
 
-	resp, err := w.client.Do(req)
+	reqCtx, cancel := context.WithTimeout(ctx, defaultRequestTimeout)
+	defer cancel()
+
+	w.collector.RecordLogin()
+	resp, err := w.client.Do(req.WithContext(reqCtx))
 	if err != nil {
 		return err
 	}
@@ -127,21 +376,110 @@ func (w *Worker) login(ctx context.Context, loginURL string) error {
 	return nil
 }
 
-// executeScript runs through all actions in the script once
+// newLoginRequest builds the login request, with the login body expanded
+// for creds
+func (w *Worker) newLoginRequest(ctx context.Context, loginURL string, creds util.Credentials) (*http.Request, error) {
+	body, contentType := w.loginRequestBody(creds)
+	req, err := http.NewRequestWithContext(ctx, "POST", w.resolveURL(loginURL), body)
+	if err != nil {
+		return nil, err
+	}
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+
+	// Add login header if provided
+	if w.loginHeader != "" {
+		parts := strings.SplitN(w.loginHeader, ":", 2)
+		if len(parts) == 2 {
+			req.Header.Set(strings.TrimSpace(parts[0]), strings.TrimSpace(parts[1]))
+		}
+	}
+	return req, nil
+}
+
+// loginRequestBody expands the login body template with the user's
+// credentials. Bodies starting with { are sent as JSON, others as a form.
+func (w *Worker) loginRequestBody(creds util.Credentials) (io.Reader, string) {
+	if w.loginBody == "" {
+		return nil, ""
+	}
+
+	// Escape the credentials so passwords with & or " don't break the body
+	contentType, escape := "application/x-www-form-urlencoded", url.QueryEscape
+	if strings.HasPrefix(strings.TrimSpace(w.loginBody), "{") {
+		contentType, escape = "application/json", jsonEscape
+	}
+	creds.Username, creds.Password = escape(creds.Username), escape(creds.Password)
+	if creds.Extra != nil {
+		extra := make(map[string]string, len(creds.Extra))
+		for name, value := range creds.Extra {
+			extra[name] = escape(value)
+		}
+		creds.Extra = extra
+	}
+
+	body := strings.ReplaceAll(w.loginBody, "{{userId}}", strconv.Itoa(w.id))
+	body = w.replaceCredentialPlaceholders(body, creds)
+	return strings.NewReader(body), contentType
+}
+
+// jsonEscape escapes s for use inside a JSON string literal
+func jsonEscape(s string) string {
+	quoted, _ := json.Marshal(s)
+	return string(quoted[1 : len(quoted)-1])
+}
+
+// refreshToken proactively re-authenticates before the session token expires
+func (w *Worker) refreshToken(ctx context.Context) {
+	// Forget the old expiry so a failed refresh isn't retried before every action
+	w.tokenExpiry = time.Time{}
+
+	if err := w.login(ctx, w.loginURL); err != nil {
+		slog.Warn("proactive token refresh failed", "worker_id", w.id, "error", err)
+		return
+	}
+	w.collector.RecordTokenRefresh()
+}
+
+// executeScript runs through all actions in the script once, or through a
+// single randomly picked action in weighted mode
 func (w *Worker) executeScript(ctx context.Context) error {
-	for _, action := range w.script.Actions {
+	steps := w.steps
+	if w.picker != nil {
+		steps = [][]script.Action{{w.pickAction()}}
+	}
+
+	for _, step := range steps {
+		if !w.requestsLeft() {
+			return nil
+		}
+
 		select {
 		case <-ctx.Done():
 			return nil
 		default:
-			// Rate limit requests
-			w.rateLimiter.Wait()
+			// Re-login before the session token expires
+			if w.loginURL != "" && w.tokenNeedsRefresh() {
+				w.refreshToken(ctx)
+			}
 
-			// Execute action
-			w.executeAction(ctx, action)
+			var delay time.Duration
+			if len(step) == 1 {
+				// Rate limit requests
+				if w.waitForRate(ctx) != nil {
+					return nil
+				}
+
+				// Execute action
+				w.executeAction(ctx, step[0])
+				delay = w.delayAfter(step[0])
+			} else {
+				delay = w.executeParallel(ctx, step)
+			}
 
 			// Apply delay after action (except for the last action)
-			if delay := action.GetDelay(); delay > 0 {
+			if delay > 0 {
 				select {
 				case <-ctx.Done():
 					return nil
@@ -154,48 +492,445 @@ func (w *Worker) executeScript(ctx context.Context) error {
 	return nil
 }
 
+// executeParallel runs a parallel group concurrently and waits for all of its
+// actions to finish. Each request still passes the rate limiter before it is
+// started. It returns the longest delay configured on the group's actions.
+func (w *Worker) executeParallel(ctx context.Context, group []script.Action) time.Duration {
+	var (
+		wg    sync.WaitGroup
+		delay time.Duration
+	)
+
+	for _, action := range group {
+		if w.waitForRate(ctx) != nil {
+			break
+		}
+
+		wg.Add(1)
+		go func(action script.Action) {
+			defer wg.Done()
+			w.executeAction(ctx, action)
+		}(action)
+
+		if d := w.delayAfter(action); d > delay {
+			delay = d
+		}
+	}
+
+	wg.Wait()
+	return delay
+}
+
+// waitForRate blocks until the rate limiter, and the global limit if any,
+// allow the next request. It returns an error if ctx ends first.
+func (w *Worker) waitForRate(ctx context.Context) error {
+	if w.rateLimiter != nil {
+		if err := w.rateLimiter.WaitContext(ctx); err != nil {
+			return err
+		}
+	}
+	if w.globalLimit != nil {
+		return w.globalLimit.WaitContext(ctx)
+	}
+	return nil
+}
+
 // executeAction performs a single HTTP action
 func (w *Worker) executeAction(ctx context.Context, action script.Action) {
-	// Expand templates with user-specific data
-	expandedAction := action.ExpandTemplates(w.id)
+	if w.requestLimit != nil && !w.requestLimit.Take() {
+		return // --max-requests is used up
+	}
 
-	// Replace credential placeholders if credentials manager is available
-	if w.credentials != nil {
-		creds := w.credentials.GetCredentialsForUser(w.id)
+	creds, err := w.userCredentials(ctx)
+	if err != nil {
+		if ctx.Err() != nil {
+			return // The test ended while fetching a token; nothing was sent
+		}
+		now := time.Now()
+		w.recordMetric(action, metrics.RequestMetric{
+			StartTime: now,
+			EndTime:   now,
+			Error:     fmt.Sprintf("auth error: %v", err),
+		})
+		return
+	}
+
+	expandedAction, err := w.expandAction(action, creds)
+	if err != nil {
+		now := time.Now()
+		w.recordMetric(action, metrics.RequestMetric{
+			StartTime: now,
+			EndTime:   now,
+			Error:     fmt.Sprintf("template error: %v", err),
+		})
+		return
+	}
+	expandedAction.URL = w.resolveURL(expandedAction.URL)
+
+	if expandedAction.WebSocket != nil {
+		w.executeWebSocket(ctx, expandedAction, creds)
+		return
+	}
+
+	if expandedAction.HasMethodWeights() {
+		expandedAction.Method = w.pickMethod(&expandedAction)
+		if !script.MethodAllowsBody(expandedAction.Method) {
+			expandedAction.Body, expandedAction.JSONBody = "", ""
+			expandedAction.Multipart = nil
+		}
+	}
+
+	bodyContent := w.requestBody(expandedAction)
+
+	var (
+		original sentRequest
+		resent   bool
+	)
+	if expandedAction.ResendFraction > 0 {
+		original, resent = w.prepareIdempotency(&expandedAction, &bodyContent)
+	}
+
+	var (
+		req       *http.Request
+		resp      *http.Response
+		bodyBytes []byte
+		metric    = metrics.RequestMetric{Resent: resent}
+		reauthed  bool // A rejected token was replaced and the request resent
+	)
+
+attempts:
+	for attempt := 0; ; attempt++ {
+		req, err = w.newRequest(ctx, expandedAction, bodyContent)
+		if err != nil {
+			now := time.Now()
+			w.recordMetric(expandedAction, metrics.RequestMetric{
+				StartTime: now,
+				EndTime:   now,
+				Retries:   metric.Retries,
+				Error:     err.Error(),
+			})
+			return
+		}
+		if creds.Token != "" {
+			req.Header.Set("Authorization", "Bearer "+creds.Token)
+		}
+
+		reqCtx, cancelReq := requestContext(ctx, expandedAction)
+		if w.proxies != nil {
+			reqCtx = util.WithProxyChoice(reqCtx)
+		}
+		trace := &requestTrace{}
+		req = req.WithContext(trace.withTrace(reqCtx))
+
+		// Execute request
+		metric.StartTime = time.Now()
+		w.retryBudget.RecordRequest()
+		w.collector.RequestStarted()
+		resp, err = w.clientFor(expandedAction).Do(req)
+		if err == nil {
+			// Read response body (Go automatically handles decompression when Accept-Encoding is not set)
+			bodyBytes, _ = io.ReadAll(resp.Body)
+			resp.Body.Close()
+		} else if reqCtx.Err() != nil && ctx.Err() == nil {
+			// Tell the action's own timeout apart from the end of the test
+			err = fmt.Errorf("timeout after %v: %w", requestTimeout(expandedAction), err)
+		}
+		cancelReq()
+		metric.EndTime = time.Now()
+		w.collector.RequestFinished()
+		if w.proxies != nil && ctx.Err() == nil {
+			w.proxies.RecordResult(req.Context(), err)
+		}
+		trace.apply(&metric)
+		if w.trace {
+			w.traceRequest(expandedAction.Name, req, bodyContent, resp, bodyBytes, err, metric.EndTime.Sub(metric.StartTime))
+		}
+
+		// A 401 means the token was revoked or expired early; fetch a new one
+		// and resend once
+		if err == nil && resp.StatusCode == http.StatusUnauthorized && creds.Token != "" && !reauthed {
+			reauthed = true
+			w.provider.Invalidate(w.id, creds.Token)
+			if fresh, authErr := w.provider.Credentials(ctx, w.id); authErr == nil && fresh.Token != creds.Token {
+				creds = fresh
+				metric.Retries++
+				continue
+			}
+		}
+
+		if !w.shouldRetry(ctx, expandedAction, resp, bodyBytes, err, attempt) {
+			break
+		}
+
+		// Back off exponentially before the next attempt
+		select {
+		case <-ctx.Done():
+			break attempts
+		case <-time.After(w.backoff(attempt)):
+		}
+		metric.Retries++
+	}
+
+	if err != nil {
+		// Stopping the worker cut the request off; it says nothing about the target
+		if stopped(ctx) {
+			w.collector.RecordCancelledRequest()
+			return
+		}
+
+		metric.Error = err.Error()
+		if w.errorDump != nil {
+			w.dumpFailure(expandedAction.Name, req, bodyContent, nil, nil, metric.Error)
+		}
+		w.recordMetric(expandedAction, metric)
+		return
+	}
+
+	metric.StatusCode = resp.StatusCode
+	metric.BytesRead = int64(len(bodyBytes))
+	metric.Proto = resp.Proto
+
+	// Connection: close and HTTP/1.0 responses prevent connection reuse
+	metric.ConnClosed = resp.Close || !resp.ProtoAtLeast(1, 1)
+
+	// Record server-reported processing time; missing or unparseable headers are ignored
+	if w.serverTimeHdr != "" {
+		metric.ServerTime, _ = parseServerTime(resp.Header, w.serverTimeHdr)
+	}
+
+	// Extract CSRF token from HTML response if this is a login page
+	if strings.Contains(expandedAction.URL, "sign_in") || strings.Contains(expandedAction.URL, "login") {
+		w.extractCSRFTokenFromHTML(string(bodyBytes))
+	}
+
+	// Extract and store any new session headers
+	w.extractSessionHeaders(resp)
+
+	// Unwrap the payload (XSSI prefixes, JSONP, base64) for validation and
+	// extraction; the raw body is still what gets dumped and compared
+	payload, err := expandedAction.TransformBody(bodyBytes)
+	if err != nil {
+		metric.Error = err.Error()
+	}
+
+	// Capture named values for later actions; misses are soft errors unless
+	// --fail-on-capture-miss is set
+	metric.CaptureMisses = w.capture(expandedAction, payload)
+
+	// Check expected status
+	if err := expandedAction.CheckStatus(resp.StatusCode); err != nil {
+		metric.Error = err.Error()
+	}
+
+	// A 200 with the wrong content type is often an HTML error or login page
+	if metric.Error == "" {
+		if err := expandedAction.CheckContentType(resp.Header.Get("Content-Type")); err != nil {
+			metric.Error = err.Error()
+		}
+	}
+
+	// Check the body only once status and content type are as expected
+	if metric.Error == "" {
+		if err := expandedAction.CheckBody(payload); err != nil {
+			metric.Error = err.Error()
+		} else if err := expandedAction.CheckJSON(payload); err != nil {
+			metric.Error = err.Error()
+		}
+	}
+
+	if metric.Error == "" && w.strictCapture && len(metric.CaptureMisses) > 0 {
+		metric.Error = fmt.Sprintf("capture missed: %s", strings.Join(metric.CaptureMisses, ", "))
+	}
+
+	// Resends must get the original response back
+	if resent {
+		if err := original.verify(resp.StatusCode, bodyBytes); err != nil {
+			metric.DedupFailed = true
+			if metric.Error == "" {
+				metric.Error = fmt.Sprintf("idempotency violation: %v", err)
+			}
+		}
+	}
+
+	// Validate response against the JSON schema
+	if metric.Error == "" {
+		if err := expandedAction.ValidateSchema(payload); err != nil {
+			metric.Error = fmt.Sprintf("schema violation: %v", err)
+		}
+	}
+
+	// Validate captured values against their consistency invariants
+	if metric.Error == "" && len(expandedAction.Consistency) > 0 {
+		if err := w.checkConsistency(expandedAction.Consistency, payload); err != nil {
+			metric.Error = fmt.Sprintf("consistency violation: %v", err)
+		}
+	}
+
+	// Only first deliveries that passed every check become candidates for
+	// resending, so a resend is never compared against a bad original
+	if !resent && expandedAction.ResendFraction > 0 && metric.Error == "" {
+		w.rememberSent(expandedAction, bodyContent, resp.StatusCode, bodyBytes)
+	}
+
+	if metric.Error != "" && w.errorDump != nil {
+		w.dumpFailure(expandedAction.Name, req, bodyContent, resp, bodyBytes, metric.Error)
+	}
+
+	w.recordMetric(expandedAction, metric)
+}
+
+// userCredentials returns the worker's credentials from its provider, or
+// empty credentials without one
+func (w *Worker) userCredentials(ctx context.Context) (util.Credentials, error) {
+	if w.provider == nil {
+		return util.Credentials{}, nil
+	}
+	return w.provider.Credentials(ctx, w.id)
+}
+
+// expandAction expands the action's templates with user-specific data
+func (w *Worker) expandAction(action script.Action, creds util.Credentials) (script.Action, error) {
+	if action.HasGoTemplates() {
+		data := w.templateContext(creds)
+		w.rngMu.Lock()
+		defer w.rngMu.Unlock()
+		return action.ExecuteTemplates(data, w.rng)
+	}
+
+	vars := w.varsSnapshot()
+	w.rngMu.Lock()
+	expandedAction, err := action.ExpandTemplates(w.id, vars, w.rng)
+	w.rngMu.Unlock()
+	if err != nil {
+		return script.Action{}, err
+	}
+
+	// Replace credential placeholders if credentials are available
+	if w.provider != nil {
+		expandedAction.URL = w.replaceCredentialPlaceholders(expandedAction.URL, creds)
 		expandedAction.Body = w.replaceCredentialPlaceholders(expandedAction.Body, creds)
 		expandedAction.JSONBody = w.replaceCredentialPlaceholders(expandedAction.JSONBody, creds)
+		expandedAction.ExpectBodyContains = w.replaceCredentialPlaceholders(expandedAction.ExpectBodyContains, creds)
+		for key, value := range expandedAction.Headers {
+			expandedAction.Headers[key] = w.replaceCredentialPlaceholders(value, creds)
+		}
+		if expandedAction.Multipart != nil {
+			expandedAction.Multipart = expandedAction.Multipart.Map(func(s string) string {
+				return w.replaceCredentialPlaceholders(s, creds)
+			})
+		}
+	}
+	return expandedAction, nil
+}
+
+// templateContext returns the data this worker's Go templates execute with
+func (w *Worker) templateContext(creds util.Credentials) script.TemplateContext {
+	data := script.TemplateContext{UserID: w.id, Vars: w.varsSnapshot()}
+	data.Username, data.Password, data.Email = creds.Username, creds.Password, creds.Field("email")
+	data.Cred = creds.Fields()
+	if w.credentials != nil {
+		data.CredentialAt = func(index int) map[string]string {
+			return w.credentials.GetCredentialsAt(index).Fields()
+		}
 	}
+	return data
+}
+
+// varsSnapshot returns a copy of the worker's captured variables
+func (w *Worker) varsSnapshot() map[string]string {
+	w.sessionMu.Lock()
+	defer w.sessionMu.Unlock()
+
+	vars := make(map[string]string, len(w.vars))
+	for name, value := range w.vars {
+		vars[name] = value
+	}
+	return vars
+}
 
-	startTime := time.Now()
+// capture stores the values the action captures from a response body and
+// returns the names of captures that did not match. A missed capture keeps
+// its previous value.
+func (w *Worker) capture(action script.Action, body []byte) []string {
+	values, misses := action.RunCaptures(body)
+
+	w.sessionMu.Lock()
+	for name, value := range values {
+		w.vars[name] = value
+	}
+	w.sessionMu.Unlock()
+
+	return misses
+}
 
-	// Create request
-	var body io.Reader
-	if expandedAction.JSONBody != "" {
-		body = bytes.NewBufferString(expandedAction.JSONBody)
-	} else if expandedAction.Body != "" {
-		// Replace CSRF token placeholder in body if present
-		bodyContent := expandedAction.Body
-		if w.csrfToken != "" {
-			// URL-encode the CSRF token for form data
-			encodedToken := url.QueryEscape(w.csrfToken)
-			bodyContent = strings.ReplaceAll(bodyContent, "CSRF_TOKEN_PLACEHOLDER", encodedToken)
+// requestBody returns the request body for an expanded action
+func (w *Worker) requestBody(action script.Action) string {
+	if action.JSONBody != "" {
+		return action.JSONBody
+	}
+
+	// Replace CSRF token placeholder in body if present
+	bodyContent := action.Body
+	w.sessionMu.Lock()
+	defer w.sessionMu.Unlock()
+	if bodyContent != "" && w.csrfToken != "" {
+		// URL-encode the CSRF token for form data
+		encodedToken := url.QueryEscape(w.csrfToken)
+		bodyContent = strings.ReplaceAll(bodyContent, "CSRF_TOKEN_PLACEHOLDER", encodedToken)
+	}
+	return bodyContent
+}
+
+// resolveURL resolves a relative URL against --base-url. Absolute URLs, and
+// all URLs without a base URL, are returned unchanged.
+func (w *Worker) resolveURL(raw string) string {
+	if w.baseURL == nil {
+		return raw
+	}
+	ref, err := url.Parse(raw)
+	if err != nil || ref.IsAbs() {
+		return raw // Invalid URLs fail when the request is built
+	}
+	return w.baseURL.ResolveReference(ref).String()
+}
+
+// newRequest builds the HTTP request for an expanded action
+func (w *Worker) newRequest(ctx context.Context, action script.Action, bodyContent string) (*http.Request, error) {
+	var (
+		body          io.Reader
+		multipartType string
+		multipartLen  int64
+	)
+	if action.Multipart != nil {
+		// Multipart bodies stream their files, so each attempt opens a new one
+		stream, contentType, length, err := action.Multipart.Open()
+		if err != nil {
+			return nil, err
 		}
-		body = bytes.NewBufferString(bodyContent)
+		body, multipartType, multipartLen = stream, contentType, length
+	} else if bodyContent != "" {
+		body = strings.NewReader(bodyContent)
 	}
 
-	req, err := http.NewRequestWithContext(ctx, expandedAction.Method, expandedAction.URL, body)
+	req, err := http.NewRequestWithContext(ctx, action.Method, action.URL, body)
 	if err != nil {
-		w.recordMetric(expandedAction, startTime, time.Now(), 0, 0, err.Error())
-		return
+		if closer, ok := body.(io.Closer); ok {
+			closer.Close()
+		}
+		return nil, err
 	}
 
-	// Set content type for JSON requests
-	if expandedAction.JSONBody != "" {
+	// Set content type for JSON and multipart requests
+	if action.JSONBody != "" {
 		req.Header.Set("Content-Type", "application/json")
 	}
+	if multipartType != "" {
+		req.Header.Set("Content-Type", multipartType)
+		req.ContentLength = multipartLen
+	}
 
 	// Set custom headers from script
-	for key, value := range expandedAction.Headers {
+	for key, value := range action.Headers {
 		// Skip Accept-Encoding to let Go handle decompression automatically
 		if key == "Accept-Encoding" {
 			continue
@@ -204,6 +939,7 @@ func (w *Worker) executeAction(ctx context.Context, action script.Action) {
 	}
 
 	// Add persistent session headers
+	w.sessionMu.Lock()
 	for key, value := range w.sessionHeaders {
 		req.Header.Set(key, value)
 	}
@@ -212,6 +948,7 @@ func (w *Worker) executeAction(ctx context.Context, action script.Action) {
 	if w.csrfToken != "" {
 		req.Header.Set("X-CSRF-Token", w.csrfToken)
 	}
+	w.sessionMu.Unlock()
 
 	// Add login header if provided
 	if w.loginHeader != "" {
@@ -221,35 +958,62 @@ func (w *Worker) executeAction(ctx context.Context, action script.Action) {
 		}
 	}
 
-	// Execute request
-	resp, err := w.client.Do(req)
-	endTime := time.Now()
+	return req, nil
+}
 
-	if err != nil {
-		w.recordMetric(expandedAction, startTime, endTime, 0, 0, err.Error())
-		return
+// shouldRetry decides whether a failed attempt is retried. Connection errors,
+// 5xx responses and responses whose body contains the action's
+// retry_if_body_contains pattern are retryable while retries and the retry
+// budget remain.
+func (w *Worker) shouldRetry(ctx context.Context, action script.Action, resp *http.Response, body []byte, err error, attempt int) bool {
+	if attempt >= w.retries || ctx.Err() != nil {
+		return false
 	}
-	defer resp.Body.Close()
-
-	// Read response body (Go automatically handles decompression when Accept-Encoding is not set)
-	bodyBytes, _ := io.ReadAll(resp.Body)
-	bytesRead := int64(len(bodyBytes))
 
-	// Extract CSRF token from HTML response if this is a login page
-	if strings.Contains(expandedAction.URL, "sign_in") || strings.Contains(expandedAction.URL, "login") {
-		w.extractCSRFTokenFromHTML(string(bodyBytes))
+	retryable := err != nil || resp.StatusCode >= 500
+	if !retryable && action.RetryIfBodyContains != "" {
+		retryable = bytes.Contains(body, []byte(action.RetryIfBodyContains))
+	}
+	if !retryable {
+		return false
 	}
 
-	// Extract and store any new session headers
-	w.extractSessionHeaders(resp)
+	return w.retryBudget.Allow()
+}
 
-	// Check expected status
-	errorMsg := ""
-	if expandedAction.ExpectStatus > 0 && resp.StatusCode != expandedAction.ExpectStatus {
-		errorMsg = fmt.Sprintf("expected status %d, got %d", expandedAction.ExpectStatus, resp.StatusCode)
+// backoff returns the delay before retry attempt+1: the base backoff doubled
+// per attempt, or a random duration up to that with full jitter so that
+// requests failing together don't retry in synchronized waves
+func (w *Worker) backoff(attempt int) time.Duration {
+	delay := w.retryBackoff << attempt
+	if !w.retryJitter || delay <= 0 {
+		return delay
 	}
 
-	w.recordMetric(expandedAction, startTime, endTime, resp.StatusCode, bytesRead, errorMsg)
+	w.rngMu.Lock()
+	defer w.rngMu.Unlock()
+	return time.Duration(w.rng.Int63n(int64(delay) + 1))
+}
+
+// delayAfter draws the think time after an action
+func (w *Worker) delayAfter(action script.Action) time.Duration {
+	w.rngMu.Lock()
+	defer w.rngMu.Unlock()
+	return action.GetDelay(w.timeScale, w.rng)
+}
+
+// pickAction draws the action of a weighted-mode iteration
+func (w *Worker) pickAction() script.Action {
+	w.rngMu.Lock()
+	defer w.rngMu.Unlock()
+	return w.picker.Pick(w.rng)
+}
+
+// pickMethod draws the method of a request from the action's method_weights
+func (w *Worker) pickMethod(action *script.Action) string {
+	w.rngMu.Lock()
+	defer w.rngMu.Unlock()
+	return action.PickMethod(w.rng)
 }
 
 // replaceCredentialPlaceholders replaces credential placeholders in request bodies
@@ -263,13 +1027,40 @@ func (w *Worker) replaceCredentialPlaceholders(content string, creds util.Creden
 	content = strings.ReplaceAll(content, "{{password}}", creds.Password)
 
 	// Also support email format for Rails apps
-	content = strings.ReplaceAll(content, "{{email}}", creds.Username)
+	content = strings.ReplaceAll(content, "{{email}}", creds.Field("email"))
+
+	// {{credential.username}} or {{cred.apikey}} is a field of the worker's
+	// own assigned credential
+	content = credentialFieldPattern.ReplaceAllStringFunc(content, func(match string) string {
+		field := credentialFieldPattern.FindStringSubmatch(match)[1]
+		return creds.Field(strings.ToLower(field))
+	})
+
+	// {{credential 3 username}} is the credential on line 3 (0-based) of the file
+	content = credentialIndexPattern.ReplaceAllStringFunc(content, func(match string) string {
+		if w.credentials == nil {
+			return ""
+		}
+		parts := credentialIndexPattern.FindStringSubmatch(match)
+		index, _ := strconv.Atoi(parts[1])
+		return w.credentials.GetCredentialsAt(index).Field(strings.ToLower(parts[2]))
+	})
 
 	return content
 }
 
+// Credential template patterns: {{credential.<field>}} (or {{cred.<field>}})
+// and {{credential <index> <field>}}
+var (
+	credentialFieldPattern = regexp.MustCompile(`\{\{(?:credential|cred)\.([\w-]+)\}\}`)
+	credentialIndexPattern = regexp.MustCompile(`\{\{credential\s+(-?\d+)\s+([\w-]+)\}\}`)
+)
+
 // extractCSRFTokenFromHTML extracts CSRF token from HTML response
 func (w *Worker) extractCSRFTokenFromHTML(htmlContent string) {
+	w.sessionMu.Lock()
+	defer w.sessionMu.Unlock()
+
 	// Method 1: Extract from meta tag
 	metaPattern := regexp.MustCompile(`<meta name="csrf-token" content="([^"]+)"`)
 	if matches := metaPattern.FindStringSubmatch(htmlContent); len(matches) > 1 {
@@ -294,6 +1085,9 @@ func (w *Worker) extractCSRFTokenFromHTML(htmlContent string) {
 
 // extractSessionHeaders extracts important headers from response for future requests
 func (w *Worker) extractSessionHeaders(resp *http.Response) {
+	w.sessionMu.Lock()
+	defer w.sessionMu.Unlock()
+
 	// Extract CSRF token from response headers
 	if csrfToken := resp.Header.Get("X-CSRF-Token"); csrfToken != "" {
 		w.sessionHeaders["X-CSRF-Token"] = csrfToken
@@ -305,20 +1099,30 @@ func (w *Worker) extractSessionHeaders(resp *http.Response) {
 		w.sessionHeaders["Authorization"] = authHeader
 	}
 
+	// Track when the session token expires
+	if w.tokenExpirySource != "" {
+		w.updateTokenExpiry(resp)
+	}
+
 	// Note: Cookies are automatically handled by the cookie jar
 }
 
-// recordMetric sends a metric to the collector
-func (w *Worker) recordMetric(action script.Action, start, end time.Time, statusCode int, bytesRead int64, errorMsg string) {
-	metric := metrics.RequestMetric{
-		Name:       action.Name,
-		Method:     action.Method,
-		URL:        action.URL,
-		StartTime:  start,
-		EndTime:    end,
-		StatusCode: statusCode,
-		BytesRead:  bytesRead,
-		Error:      errorMsg,
+// recordMetric sends a metric for an action to the collector
+func (w *Worker) recordMetric(action script.Action, metric metrics.RequestMetric) {
+	metric.Name = action.Name
+	metric.Method = action.Method
+	metric.URL = action.URL
+
+	if w.logFailures && metric.Error != "" {
+		slog.Warn("request failed",
+			"worker_id", w.id,
+			"action", metric.Name,
+			"method", metric.Method,
+			"url", metric.URL,
+			"status", metric.StatusCode,
+			"latency_ms", float64(metric.EndTime.Sub(metric.StartTime).Microseconds())/1000,
+			"retries", metric.Retries,
+			"error", metric.Error)
 	}
 
 	w.collector.Record(metric)
@@ -0,0 +1,209 @@
+package worker
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"stampede-shooter/internal/config"
+	"stampede-shooter/internal/metrics"
+	"stampede-shooter/internal/script"
+	"stampede-shooter/internal/util"
+)
+
+// loadTestScript writes a YAML script to a temporary file and loads it, so
+// actions are validated and compiled as in a real run
+func loadTestScript(t *testing.T, yaml string) *script.Script {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "script.yml")
+	if err := os.WriteFile(path, []byte(yaml), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	s, err := script.LoadScript(path)
+	if err != nil {
+		t.Fatalf("LoadScript() error: %v", err)
+	}
+	return s
+}
+
+// newTestWorker creates worker 1 running s with cfg and a collector to
+// record into
+func newTestWorker(t *testing.T, cfg config.Config, s *script.Script) (*Worker, *metrics.Collector) {
+	t.Helper()
+	collector := metrics.NewCollector(metrics.DefaultBufferSize)
+	collector.SetBlocking(true)
+	shared := &Shared{RetryBudget: util.NewRetryBudget(0)}
+	return New(1, cfg, s, collector, nil, shared), collector
+}
+
+// runActions executes each of the script's actions once and returns the
+// recorded stats by action name
+func runActions(t *testing.T, w *Worker, collector *metrics.Collector) map[string]*metrics.ActionStats {
+	t.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	collector.Start()
+	for _, action := range w.script.Actions {
+		w.executeAction(ctx, action)
+	}
+	collector.Stop()
+	return collector.GetStats()
+}
+
+// requireCounts fails the test unless the action recorded ok successes and
+// errs failures
+func requireCounts(t *testing.T, stats map[string]*metrics.ActionStats, name string, ok, errs int64) {
+	t.Helper()
+	stat, found := stats[name]
+	if !found {
+		t.Fatalf("no stats recorded for %q", name)
+	}
+	if stat.TotalOK != ok || stat.TotalErrors != errs {
+		t.Fatalf("%s: %d ok, %d errors (%v), want %d ok, %d errors",
+			name, stat.TotalOK, stat.TotalErrors, stat.ErrorCategories, ok, errs)
+	}
+}
+
+func TestUnixSocket(t *testing.T) {
+	socket := filepath.Join(t.TempDir(), "app.sock")
+	listener, err := net.Listen("unix", socket)
+	if err != nil {
+		t.Skipf("Unix sockets unavailable: %v", err)
+	}
+
+	var gotHost string
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotHost = r.Host
+		fmt.Fprint(w, "ok")
+	}))
+	server.Listener = listener
+	server.Start()
+	defer server.Close()
+
+	s := loadTestScript(t, `
+- name: Health
+  method: GET
+  url: http://app.local/health
+`)
+	w, collector := newTestWorker(t, config.Config{UnixSocket: socket}, s)
+	stats := runActions(t, w, collector)
+
+	requireCounts(t, stats, "Health", 1, 0)
+	if gotHost != "app.local" {
+		t.Errorf("Host header %q, want the URL's host app.local", gotHost)
+	}
+}
+
+func TestTemplateCredentialAt(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "creds.csv")
+	if err := os.WriteFile(path, []byte("username,password,apikey\nalice,a1,k1\nbob,b2,k2\ncarol,c3,k3\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	credentials, err := util.LoadCredentials(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s := loadTestScript(t, `
+- name: Transfer
+  method: POST
+  url: http://localhost/transfer
+  body: '{{.Username}} {{.Cred.apikey}} {{.Credential 0 "username"}} {{.Credential 2 "apikey"}} {{.Credential 4 "Username"}} {{.Credential -1 "password"}}'
+`)
+	if err := s.CompileTemplates(); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		userID int
+		want   string
+	}{
+		// The user's own credential is selected by ID; .Credential picks
+		// any line by 0-based index, wrapping in both directions
+		{1, "alice k1 alice k3 bob c3"},
+		{2, "bob k2 alice k3 bob c3"},
+		{4, "alice k1 alice k3 bob c3"},
+	}
+	for _, tt := range tests {
+		collector := metrics.NewCollector(metrics.DefaultBufferSize)
+		w := New(tt.userID, config.Config{}, s, collector, credentials, &Shared{RetryBudget: util.NewRetryBudget(0)})
+		creds, err := w.userCredentials(context.Background())
+		if err != nil {
+			t.Fatal(err)
+		}
+		expanded, err := w.expandAction(s.Actions[0], creds)
+		if err != nil {
+			t.Fatalf("user %d: expandAction() error: %v", tt.userID, err)
+		}
+		if expanded.Body != tt.want {
+			t.Errorf("user %d: body %q, want %q", tt.userID, expanded.Body, tt.want)
+		}
+	}
+}
+
+func TestTemplateCredentialUnknownField(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "creds.txt")
+	if err := os.WriteFile(path, []byte("alice,a1\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	credentials, err := util.LoadCredentials(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s := loadTestScript(t, `
+- name: Transfer
+  method: POST
+  url: http://localhost/transfer
+  body: '{{.Credential 0 "apikey"}}'
+`)
+	if err := s.CompileTemplates(); err != nil {
+		t.Fatal(err)
+	}
+	w := New(1, config.Config{}, s, metrics.NewCollector(metrics.DefaultBufferSize), credentials, &Shared{RetryBudget: util.NewRetryBudget(0)})
+	_, err = w.expandAction(s.Actions[0], credentials.GetCredentialsForUser(1))
+	if err == nil || !strings.Contains(err.Error(), `unknown credential field "apikey"`) {
+		t.Errorf("expandAction() = %v, want an unknown field error", err)
+	}
+}
+
+func TestExpectContentType(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/login" {
+			// A 200 sign-in page instead of the API response
+			w.Header().Set("Content-Type", "text/html; charset=utf-8")
+			fmt.Fprint(w, "<html>Please sign in</html>")
+			return
+		}
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		fmt.Fprint(w, `{"id": 1}`)
+	}))
+	defer server.Close()
+
+	s := loadTestScript(t, `
+- name: Match
+  method: GET
+  url: `+server.URL+`/users/1
+  expect_content_type: application/json
+- name: Mismatch
+  method: GET
+  url: `+server.URL+`/login
+  expect_content_type: application/json
+`)
+	w, collector := newTestWorker(t, config.Config{}, s)
+	stats := runActions(t, w, collector)
+
+	requireCounts(t, stats, "Match", 1, 0)
+	requireCounts(t, stats, "Mismatch", 0, 1)
+	if stats["Mismatch"].ErrorCategories[metrics.ErrorContentType] != 1 {
+		t.Errorf("Mismatch failed with %v, want a content type error", stats["Mismatch"].ErrorCategories)
+	}
+}
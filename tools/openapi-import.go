@@ -0,0 +1,619 @@
+//go:build ignore
+
+// openapi-import generates a baseline stampede script from an OpenAPI 3 spec.
+//
+// Usage: go run tools/openapi-import.go [flags] <spec.yaml|spec.json>
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Spec is the subset of an OpenAPI 3 document the importer uses
+type Spec struct {
+	OpenAPI    string                          `yaml:"openapi"`
+	Servers    []Server                        `yaml:"servers"`
+	Paths      map[string]map[string]yaml.Node `yaml:"paths"`
+	Components Components                      `yaml:"components"`
+	Security   []map[string][]string           `yaml:"security"`
+}
+
+type Server struct {
+	URL string `yaml:"url"`
+}
+
+type Components struct {
+	Schemas       map[string]*Schema      `yaml:"schemas"`
+	Parameters    map[string]*Parameter   `yaml:"parameters"`
+	RequestBodies map[string]*RequestBody `yaml:"requestBodies"`
+}
+
+type Operation struct {
+	OperationID string                 `yaml:"operationId"`
+	Summary     string                 `yaml:"summary"`
+	Tags        []string               `yaml:"tags"`
+	Parameters  []*Parameter           `yaml:"parameters"`
+	RequestBody *RequestBody           `yaml:"requestBody"`
+	Responses   map[string]interface{} `yaml:"responses"`
+	Security    []map[string][]string  `yaml:"security"`
+	Deprecated  bool                   `yaml:"deprecated"`
+}
+
+type Parameter struct {
+	Ref      string      `yaml:"$ref"`
+	Name     string      `yaml:"name"`
+	In       string      `yaml:"in"`
+	Required bool        `yaml:"required"`
+	Schema   *Schema     `yaml:"schema"`
+	Example  interface{} `yaml:"example"`
+}
+
+type RequestBody struct {
+	Ref      string               `yaml:"$ref"`
+	Required bool                 `yaml:"required"`
+	Content  map[string]MediaType `yaml:"content"`
+}
+
+type MediaType struct {
+	Schema   *Schema                `yaml:"schema"`
+	Example  interface{}            `yaml:"example"`
+	Examples map[string]ExampleItem `yaml:"examples"`
+}
+
+type ExampleItem struct {
+	Value interface{} `yaml:"value"`
+}
+
+type Schema struct {
+	Ref        string             `yaml:"$ref"`
+	Type       string             `yaml:"type"`
+	Format     string             `yaml:"format"`
+	Example    interface{}        `yaml:"example"`
+	Default    interface{}        `yaml:"default"`
+	Enum       []interface{}      `yaml:"enum"`
+	Properties map[string]*Schema `yaml:"properties"`
+	Required   []string           `yaml:"required"`
+	Items      *Schema            `yaml:"items"`
+	AllOf      []*Schema          `yaml:"allOf"`
+	OneOf      []*Schema          `yaml:"oneOf"`
+	AnyOf      []*Schema          `yaml:"anyOf"`
+}
+
+// methods are the operation keys of a path item, in output order
+var methods = []string{"get", "head", "options", "post", "put", "patch", "delete"}
+
+// maxRefChain bounds following a $ref that points at another $ref
+const maxRefChain = 6
+
+// importer generates actions from a parsed spec
+type importer struct {
+	spec          *Spec
+	baseURL       string
+	allProperties bool // Generate optional body properties too, not only required ones
+}
+
+// sampleState collects what body generation had to make up or leave out
+type sampleState struct {
+	synthesized bool     // Values were generated from the schema rather than examples
+	recursive   []string // Required properties left out because their schema recurses
+	refs        map[string]bool
+}
+
+// action is a generated script action with notes on data it still needs
+type action struct {
+	name         string
+	summary      string
+	method       string
+	url          string
+	headers      map[string]string
+	jsonBody     string
+	expectStatus []int
+	notes        []string
+}
+
+func main() {
+	tag := flag.String("tag", "", "Only import operations with this tag")
+	prefix := flag.String("path-prefix", "", "Only import paths starting with this prefix")
+	baseURL := flag.String("base-url", "", "Base URL for requests (default: the spec's first server)")
+	output := flag.String("o", "", "Output file (default: stdout)")
+	skipDeprecated := flag.Bool("skip-deprecated", false, "Skip deprecated operations")
+	allProperties := flag.Bool("all-properties", false, "Fill optional request body properties too, not only required ones")
+	flag.Usage = func() {
+		fmt.Fprintln(os.Stderr, "Usage: go run tools/openapi-import.go [flags] <spec.yaml|spec.json>")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	if flag.NArg() != 1 {
+		flag.Usage()
+		os.Exit(1)
+	}
+
+	data, err := os.ReadFile(flag.Arg(0))
+	if err != nil {
+		log.Fatalf("Failed to read spec: %v", err)
+	}
+
+	// JSON is valid YAML, so one parser handles both formats
+	var spec Spec
+	if err := yaml.Unmarshal(data, &spec); err != nil {
+		log.Fatalf("Failed to parse spec: %v", err)
+	}
+	if !strings.HasPrefix(spec.OpenAPI, "3.") {
+		log.Fatalf("Unsupported spec version %q: only OpenAPI 3 is supported", spec.OpenAPI)
+	}
+
+	imp := &importer{spec: &spec, baseURL: strings.TrimSuffix(*baseURL, "/"), allProperties: *allProperties}
+	if imp.baseURL == "" && len(spec.Servers) > 0 {
+		imp.baseURL = strings.TrimSuffix(spec.Servers[0].URL, "/")
+	}
+	if imp.baseURL == "" {
+		imp.baseURL = "http://localhost:8080"
+		log.Printf("Warning: spec has no servers, using %s (override with --base-url)", imp.baseURL)
+	}
+
+	paths := make([]string, 0, len(spec.Paths))
+	for path := range spec.Paths {
+		if strings.HasPrefix(path, *prefix) {
+			paths = append(paths, path)
+		}
+	}
+	sort.Strings(paths)
+
+	var actions []action
+	for _, path := range paths {
+		item := spec.Paths[path]
+
+		// Parameters declared on the path item apply to all its operations
+		var shared []*Parameter
+		if node, ok := item["parameters"]; ok {
+			if err := node.Decode(&shared); err != nil {
+				log.Fatalf("%s: invalid parameters: %v", path, err)
+			}
+		}
+
+		for _, method := range methods {
+			node, ok := item[method]
+			if !ok {
+				continue
+			}
+			var op Operation
+			if err := node.Decode(&op); err != nil {
+				log.Fatalf("%s %s: invalid operation: %v", strings.ToUpper(method), path, err)
+			}
+			if *tag != "" && !contains(op.Tags, *tag) {
+				continue
+			}
+			if *skipDeprecated && op.Deprecated {
+				continue
+			}
+			actions = append(actions, imp.action(path, method, op, shared))
+		}
+	}
+
+	if len(actions) == 0 {
+		log.Fatalf("No operations matched")
+	}
+
+	out := os.Stdout
+	if *output != "" {
+		file, err := os.Create(*output)
+		if err != nil {
+			log.Fatalf("Failed to create output file: %v", err)
+		}
+		defer file.Close()
+		out = file
+	}
+
+	fmt.Fprintf(out, "# Generated from %s by tools/openapi-import.go\n", flag.Arg(0))
+	fmt.Fprintf(out, "# Review the NOTE comments: those operations need real data before they will succeed.\n")
+	for _, a := range actions {
+		writeAction(out, a)
+	}
+
+	if *output != "" {
+		log.Printf("Wrote %d actions to %s", len(actions), *output)
+	}
+}
+
+// action builds the script action for one operation
+func (imp *importer) action(path, method string, op Operation, shared []*Parameter) action {
+	a := action{
+		name:    op.OperationID,
+		summary: op.Summary,
+		method:  strings.ToUpper(method),
+		headers: make(map[string]string),
+	}
+	if a.name == "" {
+		a.name = a.method + " " + path
+	}
+	if op.Deprecated {
+		a.notes = append(a.notes, "operation is deprecated")
+	}
+
+	// Operation parameters override path-level ones with the same name and location
+	params := make(map[string]*Parameter)
+	var order []string
+	for _, p := range append(append([]*Parameter{}, shared...), op.Parameters...) {
+		p = imp.resolveParameter(p)
+		if p == nil {
+			continue
+		}
+		key := p.In + ":" + p.Name
+		if _, seen := params[key]; !seen {
+			order = append(order, key)
+		}
+		params[key] = p
+	}
+
+	url := path
+	var query []string
+	for _, key := range order {
+		p := params[key]
+		switch p.In {
+		case "path":
+			url = strings.ReplaceAll(url, "{"+p.Name+"}", imp.paramValue(p, &a))
+		case "query":
+			if p.Required {
+				query = append(query, p.Name+"="+imp.paramValue(p, &a))
+			}
+		case "header":
+			if p.Required {
+				a.headers[p.Name] = imp.paramValue(p, &a)
+			}
+		}
+	}
+	a.url = imp.baseURL + url
+	if len(query) > 0 {
+		a.url += "?" + strings.Join(query, "&")
+	}
+
+	if body := imp.resolveRequestBody(op.RequestBody); body != nil {
+		imp.requestBody(body, &a)
+	}
+
+	if security := op.Security; security != nil || imp.spec.Security != nil {
+		if security == nil {
+			security = imp.spec.Security
+		}
+		var schemes []string
+		for _, requirement := range security {
+			for name := range requirement {
+				schemes = append(schemes, name)
+			}
+		}
+		sort.Strings(schemes)
+		if len(schemes) > 0 {
+			a.notes = append(a.notes, "requires authentication ("+strings.Join(schemes, ", ")+"): use --login-url, --login-hdr or headers")
+		}
+	}
+
+	a.expectStatus = successStatuses(op.Responses)
+	return a
+}
+
+// requestBody fills the action's JSON body from the spec's examples or schema
+func (imp *importer) requestBody(body *RequestBody, a *action) {
+	media, ok := body.Content["application/json"]
+	if !ok {
+		var types []string
+		for contentType := range body.Content {
+			types = append(types, contentType)
+		}
+		sort.Strings(types)
+		a.notes = append(a.notes, "request body ("+strings.Join(types, ", ")+") must be written by hand")
+		return
+	}
+
+	value := media.Example
+	if value == nil {
+		names := make([]string, 0, len(media.Examples))
+		for name := range media.Examples {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		if len(names) > 0 {
+			value = media.Examples[names[0]].Value
+		}
+	}
+	if value == nil && media.Schema != nil {
+		state := &sampleState{refs: make(map[string]bool)}
+		value, _ = imp.sample(media.Schema, state)
+		if state.synthesized {
+			a.notes = append(a.notes, "request body has placeholder values generated from the schema")
+		}
+		if len(state.recursive) > 0 {
+			sort.Strings(state.recursive)
+			a.notes = append(a.notes, "request body leaves out recursive required properties: "+strings.Join(state.recursive, ", "))
+		}
+	}
+	if value == nil {
+		a.notes = append(a.notes, "request body must be written by hand")
+		return
+	}
+
+	encoded, err := json.Marshal(normalize(value))
+	if err != nil {
+		a.notes = append(a.notes, "request body example could not be encoded: "+err.Error())
+		return
+	}
+	a.jsonBody = string(encoded)
+}
+
+// paramValue returns an example value for a parameter, or a template
+// placeholder noted for review when the spec has none
+func (imp *importer) paramValue(p *Parameter, a *action) string {
+	if p.Example != nil {
+		return fmt.Sprint(p.Example)
+	}
+
+	schema := imp.resolveSchema(p.Schema)
+	if schema != nil {
+		switch {
+		case schema.Example != nil:
+			return fmt.Sprint(schema.Example)
+		case schema.Default != nil:
+			return fmt.Sprint(schema.Default)
+		case len(schema.Enum) > 0:
+			return fmt.Sprint(schema.Enum[0])
+		case schema.Type == "integer" || schema.Type == "number":
+			a.notes = append(a.notes, fmt.Sprintf("%s parameter %q uses a random placeholder", p.In, p.Name))
+			return "{{randInt 1 1000}}"
+		}
+	}
+
+	a.notes = append(a.notes, fmt.Sprintf("%s parameter %q needs real data", p.In, p.Name))
+	return p.Name + "-{{userId}}"
+}
+
+// sample builds an example value for a schema, preferring its examples.
+// Only required object properties are filled unless allProperties is set.
+// A schema that refers back to a $ref already being expanded is recursive:
+// ok is false and the caller leaves the value out, so recursion stops at
+// the first repeat.
+func (imp *importer) sample(schema *Schema, state *sampleState) (value interface{}, ok bool) {
+	for i := 0; schema != nil && schema.Ref != ""; i++ {
+		ref := schema.Ref
+		name, local := refName(ref, "schemas")
+		if state.refs[ref] || !local || i == maxRefChain {
+			return nil, false
+		}
+		state.refs[ref] = true
+		defer delete(state.refs, ref)
+		schema = imp.spec.Components.Schemas[name]
+	}
+	if schema == nil {
+		return nil, false
+	}
+
+	switch {
+	case schema.Example != nil:
+		return schema.Example, true
+	case schema.Default != nil:
+		return schema.Default, true
+	case len(schema.Enum) > 0:
+		return schema.Enum[0], true
+	case len(schema.AllOf) > 0:
+		merged := make(map[string]interface{})
+		for _, part := range schema.AllOf {
+			value, _ := imp.sample(part, state)
+			if obj, ok := value.(map[string]interface{}); ok {
+				for k, v := range obj {
+					merged[k] = v
+				}
+			}
+		}
+		return merged, true
+	case len(schema.OneOf) > 0:
+		return imp.sampleFirst(schema.OneOf, state)
+	case len(schema.AnyOf) > 0:
+		return imp.sampleFirst(schema.AnyOf, state)
+	}
+
+	state.synthesized = true
+	switch schema.Type {
+	case "object", "":
+		obj := make(map[string]interface{})
+		for name, prop := range schema.Properties {
+			required := contains(schema.Required, name)
+			if !required && !imp.allProperties {
+				continue
+			}
+			value, ok := imp.sample(prop, state)
+			if !ok {
+				if required {
+					state.recursive = append(state.recursive, name)
+				}
+				continue
+			}
+			obj[name] = value
+		}
+		return obj, true
+	case "array":
+		// An empty array ends recursion through a list of children
+		if item, ok := imp.sample(schema.Items, state); ok {
+			return []interface{}{item}, true
+		}
+		return []interface{}{}, true
+	case "integer":
+		return 1, true
+	case "number":
+		return 1.5, true
+	case "boolean":
+		return true, true
+	default:
+		switch schema.Format {
+		case "date-time":
+			return "2024-01-01T00:00:00Z", true
+		case "date":
+			return "2024-01-01", true
+		case "email":
+			return "user{{userId}}@example.com", true
+		case "uuid":
+			return "00000000-0000-0000-0000-000000000000", true
+		}
+		return "string", true
+	}
+}
+
+// sampleFirst samples the first of a oneOf or anyOf's schemas that isn't
+// recursive
+func (imp *importer) sampleFirst(schemas []*Schema, state *sampleState) (interface{}, bool) {
+	for _, schema := range schemas {
+		if value, ok := imp.sample(schema, state); ok {
+			return value, true
+		}
+	}
+	return nil, false
+}
+
+// refName returns the component name of a local $ref of the given kind
+func refName(ref, kind string) (string, bool) {
+	prefix := "#/components/" + kind + "/"
+	if !strings.HasPrefix(ref, prefix) {
+		return "", false
+	}
+	return strings.TrimPrefix(ref, prefix), true
+}
+
+// resolveSchema follows a schema's local $ref chain
+func (imp *importer) resolveSchema(schema *Schema) *Schema {
+	for i := 0; schema != nil && schema.Ref != "" && i < maxRefChain; i++ {
+		name, ok := refName(schema.Ref, "schemas")
+		if !ok {
+			return nil
+		}
+		schema = imp.spec.Components.Schemas[name]
+	}
+	return schema
+}
+
+// resolveParameter follows a parameter's local $ref
+func (imp *importer) resolveParameter(p *Parameter) *Parameter {
+	if p == nil || p.Ref == "" {
+		return p
+	}
+	name, ok := refName(p.Ref, "parameters")
+	if !ok {
+		return nil
+	}
+	return imp.spec.Components.Parameters[name]
+}
+
+// resolveRequestBody follows a request body's local $ref
+func (imp *importer) resolveRequestBody(body *RequestBody) *RequestBody {
+	if body == nil || body.Ref == "" {
+		return body
+	}
+	name, ok := refName(body.Ref, "requestBodies")
+	if !ok {
+		return nil
+	}
+	return imp.spec.Components.RequestBodies[name]
+}
+
+// successStatuses returns the documented 2xx statuses in order
+func successStatuses(responses map[string]interface{}) []int {
+	var statuses []int
+	for code := range responses {
+		status, err := strconv.Atoi(code)
+		if err == nil && status >= 200 && status < 300 {
+			statuses = append(statuses, status)
+		}
+	}
+	sort.Ints(statuses)
+	return statuses
+}
+
+// normalize converts YAML-decoded maps into JSON-encodable ones
+func normalize(v interface{}) interface{} {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		for k, item := range v {
+			v[k] = normalize(item)
+		}
+		return v
+	case map[interface{}]interface{}:
+		converted := make(map[string]interface{}, len(v))
+		for k, item := range v {
+			converted[fmt.Sprint(k)] = normalize(item)
+		}
+		return converted
+	case []interface{}:
+		for i, item := range v {
+			v[i] = normalize(item)
+		}
+		return v
+	}
+	return v
+}
+
+// plainScalar matches strings that can be written to YAML unquoted
+var plainScalar = regexp.MustCompile(`^[A-Za-z0-9/_.-][A-Za-z0-9 /_.,-]*$`)
+
+// yamlString quotes a string for YAML when it needs it
+func yamlString(s string) string {
+	if plainScalar.MatchString(s) && !strings.HasSuffix(s, " ") {
+		return s
+	}
+	return "'" + strings.ReplaceAll(s, "'", "''") + "'"
+}
+
+// writeAction writes one action as YAML, with its notes as comments
+func writeAction(out *os.File, a action) {
+	fmt.Fprintln(out)
+	if a.summary != "" {
+		fmt.Fprintf(out, "# %s\n", a.summary)
+	}
+	for _, note := range a.notes {
+		fmt.Fprintf(out, "# NOTE: %s\n", note)
+	}
+	fmt.Fprintf(out, "- name: %s\n", yamlString(a.name))
+	fmt.Fprintf(out, "  method: %s\n", a.method)
+	fmt.Fprintf(out, "  url: %s\n", yamlString(a.url))
+	if len(a.headers) > 0 {
+		names := make([]string, 0, len(a.headers))
+		for name := range a.headers {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		fmt.Fprintln(out, "  headers:")
+		for _, name := range names {
+			fmt.Fprintf(out, "    %s: %s\n", yamlString(name), yamlString(a.headers[name]))
+		}
+	}
+	if a.jsonBody != "" {
+		fmt.Fprintf(out, "  json_body: %s\n", yamlString(a.jsonBody))
+	}
+	switch len(a.expectStatus) {
+	case 0:
+	case 1:
+		fmt.Fprintf(out, "  expect_status: %d\n", a.expectStatus[0])
+	default:
+		codes := make([]string, len(a.expectStatus))
+		for i, status := range a.expectStatus {
+			codes[i] = strconv.Itoa(status)
+		}
+		fmt.Fprintf(out, "  expect_status_in: [%s]\n", strings.Join(codes, ", "))
+	}
+}
+
+// contains reports whether list contains s
+func contains(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}